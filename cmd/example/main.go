@@ -1,8 +1,9 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"log"
+	"time"
 
 	"github.com/vfa-khuongdv/lazy"
 	"github.com/vfa-khuongdv/lazy/pkg/backup"
@@ -103,15 +104,19 @@ func main() {
 
 	// Authenticate with Google Drive (first time only)
 	if tokenInfo, _ := manager.GetTokenInfo(); !tokenInfo.HasToken {
+		// Serve the redirect URL so the code is exchanged as soon as the browser comes back
+		callbackServer, err := manager.StartAuthCallbackServer("")
+		if err != nil {
+			log.Fatalf("Failed to start callback server: %v", err)
+		}
+
 		authURL := manager.GetAuthURL()
 		log.Printf("Visit: %s", authURL)
 
-		// Get authorization code from user
-		var authCode string
-		fmt.Print("Enter authorization code: ")
-		fmt.Scanln(&authCode)
-
-		if err := manager.SetAuthCode(authCode); err != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		err = callbackServer.Wait(ctx)
+		cancel()
+		if err != nil {
 			log.Fatalf("Authentication failed: %v", err)
 		}
 	}
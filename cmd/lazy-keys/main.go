@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/vfa-khuongdv/lazy/internal/cli"
+	"github.com/vfa-khuongdv/lazy/internal/database"
+	"github.com/vfa-khuongdv/lazy/internal/secrets"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage:
+  lazy-keys generate          Print a new random master key
+  lazy-keys rotate [flags]    Re-encrypt stored secrets with the primary master key
+
+Master keys are read from -key-file, LAZY_MASTER_KEY_FILE or LAZY_MASTER_KEY.
+The first key is the primary key; keep the previous key listed after it until rotate succeeds.
+Rotate refuses to run while schema migrations are pending; apply them with lazy-migrate first.
+
+Rotate flags:`)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "generate":
+		key, err := secrets.GenerateKey()
+		if err != nil {
+			log.Fatalf("Failed to generate key: %v", err)
+		}
+		fmt.Println(key)
+
+	case "rotate":
+		os.Exit(rotate(os.Args[2:]))
+
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+// rotate re-encrypts the stored secrets and returns the exit code, so the store is closed on every path
+func rotate(args []string) int {
+	flags := flag.NewFlagSet("rotate", flag.ExitOnError)
+	flags.Usage = func() {
+		usage()
+		flags.PrintDefaults()
+	}
+
+	keyFile := flags.String("key-file", "", "file holding the master keys")
+	storeFlags := cli.RegisterStoreFlags(flags)
+	flags.Parse(args)
+
+	keyring, err := secrets.LoadKeyring(*keyFile)
+	if err != nil {
+		log.Printf("Failed to load master keys: %v", err)
+		return 1
+	}
+	if keyring == nil {
+		log.Print("No master key configured")
+		return 1
+	}
+	database.SetKeyring(keyring)
+
+	store, err := storeFlags.Config()
+	if err != nil {
+		log.Printf("Invalid metadata store: %v", err)
+		return 1
+	}
+
+	// Schema changes are applied with lazy-migrate, never as a side effect of rotating keys
+	dbService, err := database.OpenService(store)
+	if err != nil {
+		log.Printf("Failed to open metadata store: %v", err)
+		return 1
+	}
+	defer dbService.Close()
+
+	if err := dbService.CheckSchemaVersion(); err != nil {
+		log.Print(err)
+		return 1
+	}
+	pending, err := dbService.PendingMigrations()
+	if err != nil {
+		log.Printf("Failed to list pending migrations: %v", err)
+		return 1
+	}
+	if len(pending) > 0 {
+		log.Printf("%d pending schema migration(s), run lazy-migrate before rotating keys", len(pending))
+		return 1
+	}
+
+	count, err := dbService.ReencryptSecrets()
+	if err != nil {
+		log.Printf("Failed to re-encrypt secrets: %v", err)
+		return 1
+	}
+
+	log.Printf("Re-encrypted %d rows with master key '%s'", count, keyring.PrimaryKeyID())
+	return 0
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/vfa-khuongdv/lazy/internal/cli"
+	"github.com/vfa-khuongdv/lazy/internal/database"
+	"github.com/vfa-khuongdv/lazy/internal/secrets"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage:
+  lazy-migrate status [flags]       List migrations and whether they are applied
+  lazy-migrate up [-dry-run] [flags] Apply pending migrations
+
+Flags:`)
+}
+
+func main() {
+	if len(os.Args) < 2 || (os.Args[1] != "status" && os.Args[1] != "up") {
+		usage()
+		os.Exit(2)
+	}
+	command := os.Args[1]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	flags.Usage = func() {
+		usage()
+		flags.PrintDefaults()
+	}
+	dryRun := flags.Bool("dry-run", false, "list pending migrations without applying them")
+	storeFlags := cli.RegisterStoreFlags(flags)
+	flags.Parse(os.Args[2:])
+
+	// Migrations may need to read encrypted columns
+	keyring, err := secrets.LoadKeyring("")
+	if err != nil {
+		log.Fatalf("Failed to load master keys: %v", err)
+	}
+	database.SetKeyring(keyring)
+
+	store, err := storeFlags.Config()
+	if err != nil {
+		log.Fatalf("Invalid metadata store: %v", err)
+	}
+
+	dbService, err := database.OpenService(store)
+	if err != nil {
+		log.Fatalf("Failed to open metadata store: %v", err)
+	}
+	defer dbService.Close()
+
+	switch {
+	case command == "status":
+		statuses, err := dbService.GetMigrationStatus()
+		if err != nil {
+			log.Fatalf("Failed to get migration status: %v", err)
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-40s %s\n", status.Version, status.Name, applied)
+		}
+		if err := dbService.CheckSchemaVersion(); err != nil {
+			log.Fatal(err)
+		}
+
+	case *dryRun:
+		pending, err := dbService.PendingMigrations()
+		if err != nil {
+			log.Fatalf("Failed to list pending migrations: %v", err)
+		}
+		if len(pending) == 0 {
+			fmt.Println("Schema is up to date")
+		}
+		for _, migration := range pending {
+			fmt.Printf("%4d  %s\n", migration.Version, migration.Name)
+		}
+
+	default:
+		applied, err := dbService.Migrate()
+		if err != nil {
+			log.Fatalf("Failed to migrate: %v", err)
+		}
+		fmt.Printf("Applied %d migration(s), schema is at version %d\n", len(applied), database.LatestSchemaVersion())
+	}
+}
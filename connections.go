@@ -0,0 +1,366 @@
+package lazy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vfa-khuongdv/lazy/internal/auth"
+	"github.com/vfa-khuongdv/lazy/internal/database"
+	"github.com/vfa-khuongdv/lazy/internal/scheduler"
+	"github.com/vfa-khuongdv/lazy/pkg/gdrive"
+	"golang.org/x/oauth2"
+)
+
+// DefaultDriveConnection is the connection formed by Config.OAuthConfig or Config.DriveCredentials
+const DefaultDriveConnection = database.DefaultConnection
+
+// DriveConnection is a named Google Drive account that backup configs can upload to
+type DriveConnection struct {
+	Name string
+	// OAuth client of the connection; the top-level OAuthConfig is used when both fields are nil,
+	// so one OAuth client can authorize several Google accounts
+	OAuthConfig *oauth2.Config
+	// Service account or Application Default Credentials used instead of OAuthConfig
+	DriveCredentials *DriveCredentials
+	// Shared drive and root folder the backups of this connection are stored in
+	DriveFolder DriveFolderOptions
+}
+
+// DriveFolderOptions places backup folders in a shared drive and/or below a root folder ID or path
+type DriveFolderOptions = gdrive.FolderOptions
+
+// DriveCredentials authenticates Google Drive without the interactive OAuth flow, e.g. on headless servers
+type DriveCredentials struct {
+	// Service account JSON key, inline or as a file; Application Default Credentials are used when both are empty
+	ServiceAccountKey     []byte
+	ServiceAccountKeyFile string
+	// User to impersonate through domain-wide delegation (optional)
+	Subject string
+}
+
+// DeviceAuth is a pending device authorization started by StartDeviceAuth
+type DeviceAuth = auth.DeviceAuth
+
+// ErrOAuthNotConfigured is returned by the interactive OAuth methods when DriveCredentials are used
+var ErrOAuthNotConfigured = auth.ErrOAuthNotConfigured
+
+// driveAuthenticator is implemented by auth.Service and auth.CredentialsService
+type driveAuthenticator interface {
+	gdrive.AuthService
+	GetTokenInfo() (*auth.TokenInfo, error)
+	ValidateToken() error
+}
+
+// driveConnection holds the services of one Drive connection
+type driveConnection struct {
+	name         string
+	authService  *auth.Service // nil when the connection uses DriveCredentials
+	driveAuth    driveAuthenticator
+	driveService *gdrive.Service
+}
+
+// newDriveConnections builds the default connection and every connection in config.DriveConnections
+func newDriveConnections(config *Config, dbService *database.Service) (map[string]*driveConnection, error) {
+	connections := make(map[string]*driveConnection, len(config.DriveConnections)+1)
+
+	defaultConnection, err := newDriveConnection(DefaultDriveConnection, config.OAuthConfig, config.DriveCredentials, config.DriveFolder, dbService)
+	if err != nil {
+		return nil, err
+	}
+	connections[DefaultDriveConnection] = defaultConnection
+
+	for _, dc := range config.DriveConnections {
+		if dc.Name == "" {
+			return nil, fmt.Errorf("drive connection name is required")
+		}
+		if _, exists := connections[dc.Name]; exists {
+			return nil, fmt.Errorf("duplicate drive connection '%s'", dc.Name)
+		}
+		if dc.OAuthConfig != nil && dc.DriveCredentials != nil {
+			return nil, fmt.Errorf("drive connection '%s': OAuthConfig and DriveCredentials are mutually exclusive", dc.Name)
+		}
+
+		oauthConfig := dc.OAuthConfig
+		if oauthConfig == nil && dc.DriveCredentials == nil {
+			oauthConfig = config.OAuthConfig
+		}
+
+		connection, err := newDriveConnection(dc.Name, oauthConfig, dc.DriveCredentials, dc.DriveFolder, dbService)
+		if err != nil {
+			return nil, err
+		}
+		connections[dc.Name] = connection
+	}
+
+	return connections, nil
+}
+
+// newDriveConnection authenticates one connection with OAuth tokens stored under its name, or with service credentials,
+// and stores its backups in the given folder
+func newDriveConnection(name string, oauthConfig *oauth2.Config, credentials *DriveCredentials, folder DriveFolderOptions, dbService *database.Service) (*driveConnection, error) {
+	connection := &driveConnection{name: name}
+
+	if folder.PathTemplate != "" {
+		if err := gdrive.ValidatePathTemplate(folder.PathTemplate); err != nil {
+			return nil, fmt.Errorf("drive connection '%s': %w", name, err)
+		}
+	}
+
+	if credentials != nil {
+		credentialsService, err := newCredentialsService(credentials, folder.Scope())
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Drive credentials of connection '%s': %w", name, err)
+		}
+		connection.driveAuth = credentialsService
+	} else {
+		if oauthConfig == nil {
+			return nil, fmt.Errorf("drive connection '%s' needs OAuthConfig or DriveCredentials", name)
+		}
+		if oauthConfig.ClientID == "" || oauthConfig.ClientSecret == "" || oauthConfig.RedirectURL == "" {
+			return nil, fmt.Errorf("drive connection '%s': OAuth configuration must include ClientID, ClientSecret, and RedirectURL", name)
+		}
+		connection.authService = auth.NewService(oauthConfig.ClientID, oauthConfig.ClientSecret, oauthConfig.RedirectURL, dbService.TokenStore(name), folder.Scope())
+		connection.driveAuth = connection.authService
+	}
+
+	connection.driveService = gdrive.NewServiceWithFolder(connection.driveAuth, folder)
+	return connection, nil
+}
+
+// newCredentialsService picks the service account key or Application Default Credentials
+func newCredentialsService(credentials *DriveCredentials, scope string) (*auth.CredentialsService, error) {
+	switch {
+	case len(credentials.ServiceAccountKey) > 0:
+		return auth.NewServiceAccountService(credentials.ServiceAccountKey, credentials.Subject, scope)
+	case credentials.ServiceAccountKeyFile != "":
+		return auth.NewServiceAccountServiceFromFile(credentials.ServiceAccountKeyFile, credentials.Subject, scope)
+	default:
+		return auth.NewDefaultCredentialsService(credentials.Subject, scope)
+	}
+}
+
+// DriveConnections returns the names of the configured Drive connections
+func (lm *LazyManager) DriveConnections() []string {
+	names := make([]string, 0, len(lm.connections))
+	for name := range lm.connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ConnectionAuth returns the authorization methods of a named Drive connection
+func (lm *LazyManager) ConnectionAuth(name string) (*ConnectionAuth, error) {
+	connection, ok := lm.connections[name]
+	if !ok {
+		return nil, fmt.Errorf("drive connection '%s' is not configured", name)
+	}
+	return &ConnectionAuth{manager: lm, connection: connection}, nil
+}
+
+// defaultConnectionAuth returns the authorization methods of the default connection
+func (lm *LazyManager) defaultConnectionAuth() *ConnectionAuth {
+	return &ConnectionAuth{manager: lm, connection: lm.connections[DefaultDriveConnection]}
+}
+
+// hasDriveConnection reports whether backups can target the named connection
+func (lm *LazyManager) hasDriveConnection(name string) bool {
+	_, ok := lm.connections[name]
+	return ok
+}
+
+// authURLNotificationTTL keeps authorization links sent in notifications usable for a day.
+// The links complete through the callback server, and only while the process that issued them runs.
+const authURLNotificationTTL = 24 * time.Hour
+
+// checkDriveRoots fails when an authorized connection cannot see its configured root folder, so a folder
+// the drive.file scope does not reach is reported at startup instead of by every backup.
+// Connections that are not authorized yet are skipped; other lookup failures are only logged.
+func (lm *LazyManager) checkDriveRoots() error {
+	for _, name := range lm.DriveConnections() {
+		connection := lm.connections[name]
+		if info, err := connection.driveAuth.GetTokenInfo(); err != nil || !info.HasToken {
+			continue
+		}
+
+		err := connection.driveService.CheckRootAccess()
+		if errors.Is(err, gdrive.ErrRootNotAccessible) {
+			return fmt.Errorf("drive connection '%s': %w", name, err)
+		}
+		if err != nil {
+			log.Printf("Failed to check the root folder of drive connection '%s': %v", name, err)
+		}
+	}
+	return nil
+}
+
+// tokenChecks returns a health check for every Drive connection
+func (lm *LazyManager) tokenChecks() []scheduler.TokenCheck {
+	var checks []scheduler.TokenCheck
+	for _, name := range lm.DriveConnections() {
+		connection := lm.connections[name]
+		check := scheduler.TokenCheck{
+			Connection: name,
+			Validate:   connection.driveAuth.ValidateToken,
+		}
+		if connection.authService != nil {
+			authService := connection.authService
+			check.AuthURL = func() string { return authService.GetAuthURLValidFor(authURLNotificationTTL) }
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// GetTokenHealth returns the last token check of every Drive connection, nil when Config.TokenHealthCheck is not set
+func (lm *LazyManager) GetTokenHealth() []TokenHealth {
+	if lm.tokenChecker == nil {
+		return nil
+	}
+	return lm.tokenChecker.GetHealth()
+}
+
+// GetCapacityReports returns the last storage forecast of every Drive connection, nil when Config.StorageCheck is not set
+func (lm *LazyManager) GetCapacityReports() []CapacityReport {
+	if lm.capacityReporter == nil {
+		return nil
+	}
+	return lm.capacityReporter.GetReports()
+}
+
+// watchAuthorizations resumes connections on new authorizations and keeps
+// connections revoked before a restart paused until they are authorized again
+func (lm *LazyManager) watchAuthorizations() {
+	for _, name := range lm.DriveConnections() {
+		connection := lm.connections[name]
+		if connection.authService == nil {
+			continue
+		}
+		connection.authService.OnAuthorized(func() { lm.connectionAuthorized(name) })
+
+		if _, err := lm.dbService.GetTokenConfigByConnection(name); err == nil {
+			continue
+		}
+		latest, err := lm.dbService.GetLatestAuditEntry(name, database.AuditAuthRevoked, database.AuditAuthAuthorized)
+		if err == nil && latest.Action == database.AuditAuthRevoked {
+			lm.schedulerService.PauseConnection(name)
+		}
+	}
+}
+
+// connectionAuthorized records a new authorization and resumes the connection's backup jobs
+func (lm *LazyManager) connectionAuthorized(name string) {
+	detail := ""
+	if lm.schedulerService.IsConnectionPaused(name) {
+		if err := lm.schedulerService.ResumeConnection(name); err != nil {
+			log.Printf("Failed to resume drive connection '%s': %v", name, err)
+		}
+		detail = "resumed backup jobs"
+	}
+
+	if err := lm.dbService.SaveAuditEntry(&database.AuditEntry{Action: database.AuditAuthAuthorized, Target: name, Detail: detail}); err != nil {
+		log.Printf("Failed to record authorization of drive connection '%s': %v", name, err)
+	}
+}
+
+// ConnectionAuth authorizes a single Drive connection, see the LazyManager methods of the same names
+type ConnectionAuth struct {
+	manager    *LazyManager
+	connection *driveConnection
+}
+
+// Name returns the connection name
+func (ca *ConnectionAuth) Name() string {
+	return ca.connection.name
+}
+
+// GetAuthURL returns the OAuth2 authorization URL, or an empty string when DriveCredentials are used
+func (ca *ConnectionAuth) GetAuthURL() string {
+	if ca.connection.authService == nil {
+		return ""
+	}
+	return ca.connection.authService.GetAuthURL()
+}
+
+// SetAuthCode exchanges the authorization code for tokens
+func (ca *ConnectionAuth) SetAuthCode(authCode string) error {
+	if ca.connection.authService == nil {
+		return ErrOAuthNotConfigured
+	}
+	return ca.connection.authService.ExchangeToken(authCode)
+}
+
+// StartAuthCallbackServer serves the OAuth redirect URL until the authorization completes
+func (ca *ConnectionAuth) StartAuthCallbackServer(addr string) (*AuthCallbackServer, error) {
+	if ca.connection.authService == nil {
+		return nil, ErrOAuthNotConfigured
+	}
+	return ca.connection.authService.StartCallbackServer(addr)
+}
+
+// AuthCallbackHandler returns a handler for the OAuth redirect URL to mount on an existing HTTP server
+func (ca *ConnectionAuth) AuthCallbackHandler(done func(error)) http.Handler {
+	if ca.connection.authService == nil {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, ErrOAuthNotConfigured.Error(), http.StatusNotFound)
+		})
+	}
+	return ca.connection.authService.CallbackHandler(done)
+}
+
+// StartDeviceAuth starts a device authorization and logs the verification URL and user code
+func (ca *ConnectionAuth) StartDeviceAuth(ctx context.Context) (*DeviceAuth, error) {
+	if ca.connection.authService == nil {
+		return nil, ErrOAuthNotConfigured
+	}
+
+	deviceAuth, err := ca.connection.authService.StartDeviceAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("To authorize Google Drive connection '%s', visit %s and enter code %s", ca.connection.name, deviceAuth.VerificationURL, deviceAuth.UserCode)
+	return deviceAuth, nil
+}
+
+// GetTokenInfo returns information about the current token
+func (ca *ConnectionAuth) GetTokenInfo() (*auth.TokenInfo, error) {
+	return ca.connection.driveAuth.GetTokenInfo()
+}
+
+// ValidateToken validates the current token by making a test API call
+func (ca *ConnectionAuth) ValidateToken() error {
+	return ca.connection.driveAuth.ValidateToken()
+}
+
+// RevokeAuth revokes the authorization at Google, deletes the stored token and pauses the backup jobs
+// of the connection until it is authorized again. The revocation is recorded in the audit log.
+func (ca *ConnectionAuth) RevokeAuth(ctx context.Context) error {
+	if ca.connection.authService == nil {
+		return ErrOAuthNotConfigured
+	}
+
+	name := ca.connection.name
+	if err := ca.connection.authService.RevokeToken(ctx); err != nil {
+		return fmt.Errorf("failed to revoke drive connection '%s': %w", name, err)
+	}
+
+	paused := ca.manager.schedulerService.PauseConnection(name)
+	detail := "no backup jobs paused"
+	if len(paused) > 0 {
+		detail = "paused backup jobs: " + strings.Join(paused, ", ")
+	}
+
+	if err := ca.manager.dbService.SaveAuditEntry(&database.AuditEntry{Action: database.AuditAuthRevoked, Target: name, Detail: detail}); err != nil {
+		return fmt.Errorf("drive connection '%s' was revoked but the audit entry could not be saved: %w", name, err)
+	}
+
+	log.Printf("Revoked Google Drive authorization of connection '%s', %s", name, detail)
+	return nil
+}
@@ -0,0 +1,209 @@
+package lazy
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vfa-khuongdv/lazy/internal/database"
+	"github.com/vfa-khuongdv/lazy/internal/scheduler"
+	"golang.org/x/oauth2"
+)
+
+func newTestDatabaseService(t *testing.T) *database.Service {
+	dbService, err := database.NewService(&database.ServiceSQLiteConfig{Path: filepath.Join(t.TempDir(), "lazy.db")})
+	assert.NoError(t, err)
+	t.Cleanup(func() { dbService.Close() })
+	return dbService
+}
+
+// newTestServiceAccountKey returns a service account JSON key; it is only parsed, never used to fetch tokens
+func newTestServiceAccountKey(t *testing.T) []byte {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	key, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})),
+		"client_email": "backup@lazy-test.iam.gserviceaccount.com",
+		"token_uri":    "https://oauth2.googleapis.com/token",
+	})
+	assert.NoError(t, err)
+	return key
+}
+
+func TestNewDriveConnections(t *testing.T) {
+	dbService := newTestDatabaseService(t)
+	oauthConfig := &oauth2.Config{ClientID: "id", ClientSecret: "secret", RedirectURL: "http://localhost:8081/callback"}
+
+	connections, err := newDriveConnections(&Config{
+		OAuthConfig: oauthConfig,
+		DriveConnections: []DriveConnection{
+			{Name: "client-a"},
+			{Name: "client-b", OAuthConfig: &oauth2.Config{ClientID: "b-id", ClientSecret: "b-secret", RedirectURL: "http://localhost:8082/callback"}},
+			{Name: "client-c", DriveFolder: DriveFolderOptions{RootFolderID: "folder-1", FullDriveAccess: true}},
+		},
+	}, dbService)
+	assert.NoError(t, err)
+	assert.Len(t, connections, 4)
+
+	// Connections without their own client reuse the default OAuth client but not its token
+	assert.NotNil(t, connections["client-a"].authService)
+	assert.NotSame(t, connections[DefaultDriveConnection].authService, connections["client-a"].authService)
+	assert.Contains(t, connections["client-b"].authService.GetAuthURL(), "client_id=b-id")
+
+	// Only connections that opt in ask for the full drive scope
+	assert.Contains(t, connections["client-a"].authService.GetAuthURL(), "scope=https%3A%2F%2Fwww.googleapis.com%2Fauth%2Fdrive.file&")
+	assert.Contains(t, connections["client-c"].authService.GetAuthURL(), "scope=https%3A%2F%2Fwww.googleapis.com%2Fauth%2Fdrive&")
+
+	manager := &LazyManager{connections: connections}
+	assert.Equal(t, []string{"client-a", "client-b", "client-c", DefaultDriveConnection}, manager.DriveConnections())
+
+	_, err = manager.ConnectionAuth("client-d")
+	assert.ErrorContains(t, err, "drive connection 'client-d' is not configured")
+}
+
+func TestNewDriveConnections_Invalid(t *testing.T) {
+	dbService := newTestDatabaseService(t)
+	oauthConfig := &oauth2.Config{ClientID: "id", ClientSecret: "secret", RedirectURL: "http://localhost:8081/callback"}
+	serviceAccountKey := newTestServiceAccountKey(t)
+
+	testCases := []struct {
+		name        string
+		config      *Config
+		expectedErr string
+	}{
+		{
+			name:        "missing name",
+			config:      &Config{OAuthConfig: oauthConfig, DriveConnections: []DriveConnection{{}}},
+			expectedErr: "drive connection name is required",
+		},
+		{
+			name:        "duplicate name",
+			config:      &Config{OAuthConfig: oauthConfig, DriveConnections: []DriveConnection{{Name: "a"}, {Name: "a"}}},
+			expectedErr: "duplicate drive connection 'a'",
+		},
+		{
+			name:        "default name reused",
+			config:      &Config{OAuthConfig: oauthConfig, DriveConnections: []DriveConnection{{Name: DefaultDriveConnection}}},
+			expectedErr: "duplicate drive connection 'default'",
+		},
+		{
+			name:        "no client to inherit",
+			config:      &Config{DriveCredentials: &DriveCredentials{ServiceAccountKey: serviceAccountKey}, DriveConnections: []DriveConnection{{Name: "a"}}},
+			expectedErr: "drive connection 'a' needs OAuthConfig or DriveCredentials",
+		},
+		{
+			name:        "incomplete client",
+			config:      &Config{OAuthConfig: oauthConfig, DriveConnections: []DriveConnection{{Name: "a", OAuthConfig: &oauth2.Config{ClientID: "id"}}}},
+			expectedErr: "drive connection 'a': OAuth configuration must include",
+		},
+		{
+			name:        "invalid path template",
+			config:      &Config{OAuthConfig: oauthConfig, DriveFolder: DriveFolderOptions{PathTemplate: "{config}/{quarter}"}},
+			expectedErr: "drive connection 'default': unknown placeholder {quarter}",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newDriveConnections(tc.config, dbService)
+			assert.ErrorContains(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestConnectionAuth_ServiceCredentials(t *testing.T) {
+	ca := &ConnectionAuth{connection: &driveConnection{name: "robot"}}
+
+	assert.Empty(t, ca.GetAuthURL())
+	assert.ErrorIs(t, ca.SetAuthCode("code"), ErrOAuthNotConfigured)
+	_, err := ca.StartAuthCallbackServer("")
+	assert.ErrorIs(t, err, ErrOAuthNotConfigured)
+	assert.ErrorIs(t, ca.RevokeAuth(context.Background()), ErrOAuthNotConfigured)
+}
+
+// newTestManager builds a manager with a single OAuth connection without starting it
+func newTestManager(t *testing.T, dbService *database.Service) *LazyManager {
+	config := &Config{OAuthConfig: &oauth2.Config{ClientID: "id", ClientSecret: "secret", RedirectURL: "http://localhost:8080/callback"}}
+	connections, err := newDriveConnections(config, dbService)
+	assert.NoError(t, err)
+
+	manager := &LazyManager{
+		dbService:        dbService,
+		connections:      connections,
+		schedulerService: scheduler.NewService(dbService, connections[DefaultDriveConnection].driveService),
+		config:           config,
+	}
+	manager.watchAuthorizations()
+	return manager
+}
+
+func TestRevokedConnection_StaysPausedUntilAuthorized(t *testing.T) {
+	dbService := newTestDatabaseService(t)
+	assert.NoError(t, dbService.SaveBackupConfig(&database.BackupConfig{
+		Name: "orders", BackupMode: "full", DatabaseURL: "mysql://localhost/orders", DatabaseType: "mysql",
+		CronSchedule: "0 0 2 * * *", Enabled: true, Connection: DefaultDriveConnection,
+	}))
+	assert.NoError(t, dbService.SaveAuditEntry(&database.AuditEntry{Action: database.AuditAuthRevoked, Target: DefaultDriveConnection}))
+
+	// A connection revoked before the restart is paused again, so its jobs are not scheduled
+	manager := newTestManager(t, dbService)
+	assert.True(t, manager.schedulerService.IsConnectionPaused(DefaultDriveConnection))
+	config, err := dbService.GetBackupConfigByName("orders")
+	assert.NoError(t, err)
+	assert.NoError(t, manager.schedulerService.AddBackupJob(config))
+	assert.Empty(t, manager.schedulerService.GetScheduledJobs())
+	assert.ErrorContains(t, manager.ExecuteBackupNow("orders"), "is paused")
+
+	// A new authorization resumes the jobs and is audited
+	manager.connectionAuthorized(DefaultDriveConnection)
+	assert.False(t, manager.schedulerService.IsConnectionPaused(DefaultDriveConnection))
+	jobs := manager.schedulerService.GetScheduledJobs()
+	assert.Len(t, jobs, 1)
+	assert.Equal(t, "orders", jobs[0].Name)
+
+	entries, err := manager.GetAuditLog(DefaultDriveConnection, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, database.AuditAuthAuthorized, entries[0].Action)
+	assert.Equal(t, "resumed backup jobs", entries[0].Detail)
+
+	// Pausing unschedules the connection's jobs
+	assert.Equal(t, []string{"orders"}, manager.schedulerService.PauseConnection(DefaultDriveConnection))
+	assert.Empty(t, manager.schedulerService.GetScheduledJobs())
+}
+
+func TestNeverAuthorizedConnection_IsNotPaused(t *testing.T) {
+	manager := newTestManager(t, newTestDatabaseService(t))
+	assert.False(t, manager.schedulerService.IsConnectionPaused(DefaultDriveConnection))
+}
+
+func TestBackupDriveService(t *testing.T) {
+	dbService := newTestDatabaseService(t)
+	connections, err := newDriveConnections(&Config{
+		OAuthConfig:      &oauth2.Config{ClientID: "id", ClientSecret: "secret", RedirectURL: "http://localhost:8081/callback"},
+		DriveConnections: []DriveConnection{{Name: "client-a"}},
+	}, dbService)
+	assert.NoError(t, err)
+	manager := &LazyManager{dbService: dbService, connections: connections}
+
+	assert.NoError(t, dbService.SaveBackupConfig(&database.BackupConfig{
+		Name: "orders", BackupMode: "full", DatabaseURL: "mysql://localhost/orders", DatabaseType: "mysql",
+		CronSchedule: "0 0 2 * * *", Enabled: true, Connection: "client-a",
+	}))
+
+	driveService, err := manager.backupDriveService("orders")
+	assert.NoError(t, err)
+	assert.Same(t, connections["client-a"].driveService, driveService)
+
+	// Backups of deleted configs are looked up on the default connection
+	driveService, err = manager.backupDriveService("deleted")
+	assert.NoError(t, err)
+	assert.Same(t, connections[DefaultDriveConnection].driveService, driveService)
+}
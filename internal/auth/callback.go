@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// CallbackHandler serves the OAuth redirect URL. It verifies the state, exchanges the code and
+// reports the outcome to done. Requests with an unknown state are rejected without calling done.
+func (s *Service) CallbackHandler(done func(error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		state := query.Get("state")
+		if providerError := query.Get("error"); providerError != "" {
+			// Only a state we issued may end the flow, so a stray request cannot cancel it
+			s.mu.Lock()
+			_, ok := s.takeAuthRequest(state)
+			s.mu.Unlock()
+			if !ok {
+				writeCallbackPage(w, http.StatusBadRequest, "Authorization failed", ErrInvalidState.Error())
+				return
+			}
+
+			err := fmt.Errorf("authorization denied: %s", providerError)
+			writeCallbackPage(w, http.StatusBadRequest, "Authorization failed", err.Error())
+			done(err)
+			return
+		}
+
+		code := query.Get("code")
+		if code == "" {
+			writeCallbackPage(w, http.StatusBadRequest, "Authorization failed", "missing authorization code")
+			return
+		}
+
+		err := s.ExchangeTokenWithState(state, code)
+		if errors.Is(err, ErrInvalidState) {
+			writeCallbackPage(w, http.StatusBadRequest, "Authorization failed", err.Error())
+			return
+		}
+		if err != nil {
+			writeCallbackPage(w, http.StatusInternalServerError, "Authorization failed", err.Error())
+			done(err)
+			return
+		}
+
+		writeCallbackPage(w, http.StatusOK, "Authorization complete", "Google Drive is connected. You can close this window.")
+		done(nil)
+	})
+}
+
+// writeCallbackPage renders a minimal page for the browser that followed the redirect
+func writeCallbackPage(w http.ResponseWriter, status int, title, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<!DOCTYPE html><html><head><title>%s</title></head><body><h1>%s</h1><p>%s</p></body></html>",
+		html.EscapeString(title), html.EscapeString(title), html.EscapeString(message))
+}
+
+// CallbackServer is an embedded HTTP server for the OAuth redirect URL that stops after the first completed authorization
+type CallbackServer struct {
+	server   *http.Server
+	listener net.Listener
+	result   chan error
+	once     sync.Once
+}
+
+// StartCallbackServer listens on addr and serves the path of the configured redirect URL.
+// An empty addr listens on the host and port of the redirect URL.
+func (s *Service) StartCallbackServer(addr string) (*CallbackServer, error) {
+	redirectURL, err := url.Parse(s.config.RedirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect URL: %w", err)
+	}
+	if addr == "" {
+		addr = redirectURL.Host
+	}
+	path := redirectURL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	cs := &CallbackServer{
+		listener: listener,
+		result:   make(chan error, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, s.CallbackHandler(cs.finish))
+	cs.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := cs.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			cs.finish(fmt.Errorf("callback server failed: %w", err))
+		}
+	}()
+
+	log.Printf("Waiting for OAuth callback on http://%s%s", listener.Addr(), path)
+	return cs, nil
+}
+
+// Addr returns the address the server is listening on
+func (cs *CallbackServer) Addr() string {
+	return cs.listener.Addr().String()
+}
+
+// Wait blocks until the authorization completes or ctx is done, then stops the server
+func (cs *CallbackServer) Wait(ctx context.Context) error {
+	select {
+	case err := <-cs.result:
+		return err
+	case <-ctx.Done():
+		cs.Shutdown(context.Background())
+		return ctx.Err()
+	}
+}
+
+// Shutdown stops the server without waiting for an authorization
+func (cs *CallbackServer) Shutdown(ctx context.Context) error {
+	return cs.server.Shutdown(ctx)
+}
+
+// finish records the first outcome and stops the server once the response has been written
+func (cs *CallbackServer) finish(err error) {
+	cs.once.Do(func() {
+		cs.result <- err
+		go cs.server.Shutdown(context.Background())
+	})
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/oauth2"
+)
+
+// newTokenServer stands in for Google's token endpoint and checks the PKCE verifier against challenge
+func newTokenServer(t *testing.T, challenge *string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != "good-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != *challenge {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "access-token",
+			"refresh_token": "refresh-token",
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newCallbackTestService(t *testing.T, mockDB *MockDatabaseService, redirectURL string) (*Service, *string) {
+	challenge := new(string)
+	tokenServer := newTokenServer(t, challenge)
+
+	service := NewService("client", "secret", redirectURL, mockDB)
+	service.config.Endpoint = oauth2.Endpoint{
+		AuthURL:  "https://accounts.example.com/auth",
+		TokenURL: tokenServer.URL,
+	}
+	return service, challenge
+}
+
+// issueAuthURL returns the state of a fresh auth URL and records its PKCE challenge
+func issueAuthURL(t *testing.T, service *Service, challenge *string) string {
+	authURL, err := url.Parse(service.GetAuthURL())
+	assert.NoError(t, err)
+
+	query := authURL.Query()
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	*challenge = query.Get("code_challenge")
+	return query.Get("state")
+}
+
+func TestGetAuthURL_RandomState(t *testing.T) {
+	service := NewService("client", "secret", "http://localhost:8081/callback", &MockDatabaseService{})
+
+	first, _ := url.Parse(service.GetAuthURL())
+	second, _ := url.Parse(service.GetAuthURL())
+
+	assert.NotEmpty(t, first.Query().Get("state"))
+	assert.NotEqual(t, first.Query().Get("state"), second.Query().Get("state"))
+	assert.NotEqual(t, first.Query().Get("code_challenge"), second.Query().Get("code_challenge"))
+}
+
+func TestExchangeTokenWithState(t *testing.T) {
+	mockDB := &MockDatabaseService{}
+	mockDB.On("SaveTokenConfig", mock.AnythingOfType("*database.TokenConfig")).Return(nil).Once()
+	service, challenge := newCallbackTestService(t, mockDB, "http://localhost:8081/callback")
+
+	state := issueAuthURL(t, service, challenge)
+
+	assert.ErrorIs(t, service.ExchangeTokenWithState("forged", "good-code"), ErrInvalidState)
+	assert.NoError(t, service.ExchangeTokenWithState(state, "good-code"))
+
+	// A state can only be used once
+	assert.ErrorIs(t, service.ExchangeTokenWithState(state, "good-code"), ErrInvalidState)
+	mockDB.AssertNumberOfCalls(t, "SaveTokenConfig", 1)
+}
+
+func TestExchangeTokenWithState_Expired(t *testing.T) {
+	service, challenge := newCallbackTestService(t, &MockDatabaseService{}, "http://localhost:8081/callback")
+
+	state := issueAuthURL(t, service, challenge)
+	service.pending[state] = authRequest{verifier: service.pending[state].verifier, expiresAt: time.Now().Add(-time.Minute)}
+
+	assert.ErrorIs(t, service.ExchangeTokenWithState(state, "good-code"), ErrInvalidState)
+}
+
+func TestGetAuthURLValidFor(t *testing.T) {
+	service := NewService("client", "secret", "http://localhost:8081/callback", &MockDatabaseService{})
+
+	authURL, _ := url.Parse(service.GetAuthURLValidFor(24 * time.Hour))
+	request := service.pending[authURL.Query().Get("state")]
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), request.expiresAt, time.Minute)
+}
+
+func TestExchangeToken_UsesLatestVerifier(t *testing.T) {
+	mockDB := &MockDatabaseService{}
+	mockDB.On("SaveTokenConfig", mock.AnythingOfType("*database.TokenConfig")).Return(nil)
+	service, challenge := newCallbackTestService(t, mockDB, "http://localhost:8081/callback")
+
+	// The copy-paste flow has no state, so the most recent URL's verifier is used
+	issueAuthURL(t, service, challenge)
+	assert.NoError(t, service.ExchangeToken("good-code"))
+	mockDB.AssertExpectations(t)
+}
+
+func TestExchangeToken_IgnoresNotificationURLs(t *testing.T) {
+	mockDB := &MockDatabaseService{}
+	mockDB.On("SaveTokenConfig", mock.AnythingOfType("*database.TokenConfig")).Return(nil)
+	service, challenge := newCallbackTestService(t, mockDB, "http://localhost:8081/callback")
+
+	// A health check alert issued while the operator is pasting a code does not take over its verifier
+	issueAuthURL(t, service, challenge)
+	notificationURL, _ := url.Parse(service.GetAuthURLValidFor(24 * time.Hour))
+	assert.NoError(t, service.ExchangeToken("good-code"))
+
+	// The notification link still completes through the callback
+	assert.Contains(t, service.pending, notificationURL.Query().Get("state"))
+	mockDB.AssertExpectations(t)
+}
+
+func TestCallbackHandler(t *testing.T) {
+	mockDB := &MockDatabaseService{}
+	mockDB.On("SaveTokenConfig", mock.AnythingOfType("*database.TokenConfig")).Return(nil)
+	service, challenge := newCallbackTestService(t, mockDB, "http://localhost:8081/callback")
+
+	var results []error
+	handler := service.CallbackHandler(func(err error) { results = append(results, err) })
+
+	serve := func(query string) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/callback?"+query, nil))
+		return recorder.Code
+	}
+
+	state := issueAuthURL(t, service, challenge)
+
+	// Unknown state and missing code do not end the flow
+	assert.Equal(t, http.StatusBadRequest, serve("state=forged&code=good-code"))
+	assert.Equal(t, http.StatusBadRequest, serve("state=forged&error=access_denied"))
+	assert.Equal(t, http.StatusBadRequest, serve("state="+state))
+	assert.Empty(t, results)
+
+	assert.Equal(t, http.StatusOK, serve("state="+state+"&code=good-code"))
+	assert.Equal(t, []error{nil}, results)
+
+	// The provider reporting a denial ends the flow with an error
+	state = issueAuthURL(t, service, challenge)
+	assert.Equal(t, http.StatusBadRequest, serve("state="+state+"&error=access_denied"))
+	assert.Len(t, results, 2)
+	assert.ErrorContains(t, results[1], "access_denied")
+}
+
+func TestCallbackServer(t *testing.T) {
+	mockDB := &MockDatabaseService{}
+	mockDB.On("SaveTokenConfig", mock.AnythingOfType("*database.TokenConfig")).Return(nil)
+	service, challenge := newCallbackTestService(t, mockDB, "http://localhost:8081/auth/google/callback")
+
+	server, err := service.StartCallbackServer("127.0.0.1:0")
+	assert.NoError(t, err)
+
+	state := issueAuthURL(t, service, challenge)
+	response, err := http.Get("http://" + server.Addr() + "/auth/google/callback?state=" + state + "&code=good-code")
+	assert.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, server.Wait(ctx))
+
+	// The server stops itself after a successful authorization
+	assert.Eventually(t, func() bool {
+		_, err := http.Get("http://" + server.Addr() + "/auth/google/callback")
+		return err != nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestCallbackServer_WaitCancelled(t *testing.T) {
+	service := NewService("client", "secret", "http://localhost:8081/callback", &MockDatabaseService{})
+
+	server, err := service.StartCallbackServer("127.0.0.1:0")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, server.Wait(ctx), context.Canceled)
+}
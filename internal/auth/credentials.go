@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/drive/v3"
+)
+
+// ErrOAuthNotConfigured is returned by interactive OAuth operations when Drive uses service credentials
+var ErrOAuthNotConfigured = errors.New("interactive OAuth is not configured, Google Drive uses service credentials")
+
+// CredentialsService authenticates Google Drive with a service account key or Application Default Credentials.
+// It needs no interactive consent and keeps no token in the database.
+type CredentialsService struct {
+	tokenSource oauth2.TokenSource
+	// config only carries the scopes; tokens are minted by tokenSource, never refreshed through config
+	config *oauth2.Config
+}
+
+// NewServiceAccountService authenticates with a service account JSON key.
+// A non-empty subject impersonates that user through domain-wide delegation.
+// The drive.file scope is requested unless other scopes are given.
+func NewServiceAccountService(credentialsJSON []byte, subject string, scopes ...string) (*CredentialsService, error) {
+	scopes = driveScopes(scopes)
+	jwtConfig, err := google.JWTConfigFromJSON(credentialsJSON, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service account key: %w", err)
+	}
+	jwtConfig.Subject = subject
+
+	return newCredentialsService(jwtConfig.TokenSource(context.Background()), scopes), nil
+}
+
+// NewServiceAccountServiceFromFile authenticates with a service account JSON key file
+func NewServiceAccountServiceFromFile(path string, subject string, scopes ...string) (*CredentialsService, error) {
+	credentialsJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account key: %w", err)
+	}
+	return NewServiceAccountService(credentialsJSON, subject, scopes...)
+}
+
+// NewDefaultCredentialsService authenticates with Application Default Credentials, e.g. GOOGLE_APPLICATION_CREDENTIALS
+// or the metadata server on Google Cloud. Impersonating a subject requires the credentials to be a service account key.
+func NewDefaultCredentialsService(subject string, scopes ...string) (*CredentialsService, error) {
+	scopes = driveScopes(scopes)
+	credentials, err := google.FindDefaultCredentials(context.Background(), scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find application default credentials: %w", err)
+	}
+
+	if subject == "" {
+		return newCredentialsService(credentials.TokenSource, scopes), nil
+	}
+
+	var key struct {
+		Type string `json:"type"`
+	}
+	if len(credentials.JSON) == 0 || json.Unmarshal(credentials.JSON, &key) != nil || key.Type != "service_account" {
+		return nil, fmt.Errorf("domain-wide delegation requires application default credentials from a service account key")
+	}
+	return NewServiceAccountService(credentials.JSON, subject, scopes...)
+}
+
+func newCredentialsService(tokenSource oauth2.TokenSource, scopes []string) *CredentialsService {
+	return &CredentialsService{
+		// Renew tokens a few minutes early, like Service.GetValidToken
+		tokenSource: oauth2.ReuseTokenSourceWithExpiry(nil, tokenSource, 5*time.Minute),
+		config:      &oauth2.Config{Scopes: scopes},
+	}
+}
+
+// driveScopes defaults to the drive.file scope, which only reaches files created by this app
+func driveScopes(scopes []string) []string {
+	if len(scopes) == 0 {
+		return []string{drive.DriveFileScope}
+	}
+	return scopes
+}
+
+// GetClient returns a current token; the config has no endpoint, so callers fetch a new token per operation
+func (s *CredentialsService) GetClient() (*oauth2.Config, *oauth2.Token, error) {
+	token, err := s.tokenSource.Token()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get service credentials token: %w", err)
+	}
+	return s.config, token, nil
+}
+
+// RefreshToken returns a current token from the credentials; the given token is ignored
+func (s *CredentialsService) RefreshToken(token *oauth2.Token) (*oauth2.Token, error) {
+	newToken, err := s.tokenSource.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+	return newToken, nil
+}
+
+// GetTokenInfo returns information about the current token
+func (s *CredentialsService) GetTokenInfo() (*TokenInfo, error) {
+	token, err := s.tokenSource.Token()
+	if err != nil {
+		return &TokenInfo{HasToken: false}, nil
+	}
+
+	return &TokenInfo{
+		HasToken: true,
+		Expiry:   token.Expiry,
+		Valid:    token.Valid(),
+	}, nil
+}
+
+// ValidateToken validates the credentials by making a test API call
+func (s *CredentialsService) ValidateToken() error {
+	_, token, err := s.GetClient()
+	if err != nil {
+		return err
+	}
+
+	return validateDriveAccess(s.config, token)
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/drive/v3"
+)
+
+// newServiceAccountKey returns a service account JSON key whose token_uri points at a local token endpoint.
+// The endpoint records the subject of the last JWT assertion and counts the tokens it issued.
+func newServiceAccountKey(t *testing.T) ([]byte, *atomic.Value, *atomic.Int32) {
+	subject := &atomic.Value{}
+	issued := &atomic.Int32{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.PostForm.Get("grant_type"))
+
+		parts := strings.Split(r.PostForm.Get("assertion"), ".")
+		assert.Len(t, parts, 3)
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		assert.NoError(t, err)
+
+		var claims struct {
+			Scope string `json:"scope"`
+			Sub   string `json:"sub"`
+		}
+		assert.NoError(t, json.Unmarshal(payload, &claims))
+		assert.Equal(t, drive.DriveFileScope, claims.Scope)
+		subject.Store(claims.Sub)
+		issued.Add(1)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "service-account-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+
+	key, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "lazy-test",
+		"private_key_id": "key-1",
+		"private_key":    string(keyPEM),
+		"client_email":   "backup@lazy-test.iam.gserviceaccount.com",
+		"client_id":      "1234567890",
+		"token_uri":      server.URL,
+	})
+	assert.NoError(t, err)
+	return key, subject, issued
+}
+
+func TestServiceAccountService(t *testing.T) {
+	key, subject, issued := newServiceAccountKey(t)
+
+	service, err := NewServiceAccountService(key, "")
+	assert.NoError(t, err)
+
+	_, token, err := service.GetClient()
+	assert.NoError(t, err)
+	assert.Equal(t, "service-account-token", token.AccessToken)
+	assert.Equal(t, "", subject.Load())
+
+	// Tokens are reused until they are about to expire
+	refreshed, err := service.RefreshToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, token.AccessToken, refreshed.AccessToken)
+	assert.Equal(t, int32(1), issued.Load())
+
+	info, err := service.GetTokenInfo()
+	assert.NoError(t, err)
+	assert.True(t, info.HasToken)
+	assert.True(t, info.Valid)
+}
+
+func TestServiceAccountService_DomainWideDelegation(t *testing.T) {
+	key, subject, _ := newServiceAccountKey(t)
+
+	service, err := NewServiceAccountService(key, "backups@example.com")
+	assert.NoError(t, err)
+
+	_, _, err = service.GetClient()
+	assert.NoError(t, err)
+	assert.Equal(t, "backups@example.com", subject.Load())
+}
+
+func TestServiceAccountService_InvalidKey(t *testing.T) {
+	_, err := NewServiceAccountService([]byte(`{"type":"authorized_user"}`), "")
+	assert.ErrorContains(t, err, "invalid service account key")
+
+	_, err = NewServiceAccountServiceFromFile(filepath.Join(t.TempDir(), "missing.json"), "")
+	assert.ErrorContains(t, err, "failed to read service account key")
+}
+
+func TestDefaultCredentialsService(t *testing.T) {
+	key, subject, _ := newServiceAccountKey(t)
+	file := filepath.Join(t.TempDir(), "credentials.json")
+	assert.NoError(t, os.WriteFile(file, key, 0600))
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", file)
+
+	service, err := NewDefaultCredentialsService("backups@example.com")
+	assert.NoError(t, err)
+
+	_, token, err := service.GetClient()
+	assert.NoError(t, err)
+	assert.Equal(t, "service-account-token", token.AccessToken)
+	assert.Equal(t, "backups@example.com", subject.Load())
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// DeviceAuth is a pending device authorization (RFC 8628). Show VerificationURL and UserCode
+// to the operator, who approves access from any browser, then call Wait.
+type DeviceAuth struct {
+	VerificationURL string    `json:"verification_url"`
+	UserCode        string    `json:"user_code"`
+	ExpiresAt       time.Time `json:"expires_at"`
+
+	service  *Service
+	response *oauth2.DeviceAuthResponse
+}
+
+// StartDeviceAuth requests a device and user code. Google only issues them to OAuth clients
+// of type "TVs and Limited Input devices".
+func (s *Service) StartDeviceAuth(ctx context.Context) (*DeviceAuth, error) {
+	response, err := s.config.DeviceAuth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start device authorization: %w", err)
+	}
+
+	verificationURL := response.VerificationURIComplete
+	if verificationURL == "" {
+		verificationURL = response.VerificationURI
+	}
+
+	return &DeviceAuth{
+		VerificationURL: verificationURL,
+		UserCode:        response.UserCode,
+		ExpiresAt:       response.Expiry,
+		service:         s,
+		response:        response,
+	}, nil
+}
+
+// Wait polls the token endpoint until the operator approves, denies or the code expires, then saves the token
+func (d *DeviceAuth) Wait(ctx context.Context) error {
+	token, err := d.service.config.DeviceAccessToken(ctx, d.response)
+	if err != nil {
+		return fmt.Errorf("device authorization failed: %w", err)
+	}
+
+	return d.service.saveToken(token)
+}
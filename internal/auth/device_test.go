@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/oauth2"
+
+	"github.com/vfa-khuongdv/lazy/internal/database"
+)
+
+// newDeviceTestService points the service at a stand-in device and token endpoint.
+// The token endpoint answers authorization_pending once, then finalError or a token when it is empty.
+func newDeviceTestService(t *testing.T, mockDB *MockDatabaseService, finalError string) (*Service, *atomic.Int32) {
+	polls := &atomic.Int32{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device/code", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "client", r.PostForm.Get("client_id"))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_url": "https://www.google.com/device",
+			"expires_in":       1800,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "urn:ietf:params:oauth:grant-type:device_code", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "device-code", r.PostForm.Get("device_code"))
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case polls.Add(1) == 1:
+			w.WriteHeader(http.StatusPreconditionRequired)
+			json.NewEncoder(w).Encode(map[string]string{"error": "authorization_pending"})
+		case finalError != "":
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": finalError})
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token":  "device-access-token",
+				"refresh_token": "device-refresh-token",
+				"token_type":    "Bearer",
+				"expires_in":    3600,
+			})
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	service := NewService("client", "secret", "http://localhost:8081/callback", mockDB)
+	service.config.Endpoint = oauth2.Endpoint{
+		DeviceAuthURL: server.URL + "/device/code",
+		TokenURL:      server.URL + "/token",
+	}
+	return service, polls
+}
+
+func TestDeviceAuth(t *testing.T) {
+	mockDB := &MockDatabaseService{}
+	mockDB.On("SaveTokenConfig", mock.MatchedBy(func(config *database.TokenConfig) bool {
+		return config.AccessToken == "device-access-token" && config.RefreshToken == "device-refresh-token"
+	})).Return(nil).Once()
+	service, polls := newDeviceTestService(t, mockDB, "")
+
+	deviceAuth, err := service.StartDeviceAuth(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "https://www.google.com/device", deviceAuth.VerificationURL)
+	assert.Equal(t, "ABCD-EFGH", deviceAuth.UserCode)
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), deviceAuth.ExpiresAt, time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	assert.NoError(t, deviceAuth.Wait(ctx))
+	assert.Equal(t, int32(2), polls.Load())
+	mockDB.AssertExpectations(t)
+}
+
+func TestDeviceAuth_Denied(t *testing.T) {
+	mockDB := &MockDatabaseService{}
+	service, _ := newDeviceTestService(t, mockDB, "access_denied")
+
+	deviceAuth, err := service.StartDeviceAuth(context.Background())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = deviceAuth.Wait(ctx)
+	assert.ErrorContains(t, err, "device authorization failed")
+	assert.ErrorContains(t, err, "access_denied")
+	mockDB.AssertNotCalled(t, "SaveTokenConfig")
+}
+
+func TestDeviceAuth_Cancelled(t *testing.T) {
+	service, _ := newDeviceTestService(t, &MockDatabaseService{}, "")
+
+	deviceAuth, err := service.StartDeviceAuth(context.Background())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, deviceAuth.Wait(ctx), context.Canceled)
+}
+
+func TestStartDeviceAuth_EndpointError(t *testing.T) {
+	service := NewService("client", "secret", "http://localhost:8081/callback", &MockDatabaseService{})
+	service.config.Endpoint = oauth2.Endpoint{}
+
+	_, err := service.StartDeviceAuth(context.Background())
+	assert.ErrorContains(t, err, "failed to start device authorization")
+}
@@ -2,7 +2,10 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/vfa-khuongdv/lazy/internal/database"
@@ -16,12 +19,33 @@ import (
 type DatabaseService interface {
 	SaveTokenConfig(config *database.TokenConfig) error
 	GetTokenConfig() (*database.TokenConfig, error)
+	DeleteTokenConfig() error
 }
 
+// authRequestTTL is how long an authorization URL from GetAuthURL can be completed after it was issued
+const authRequestTTL = 10 * time.Minute
+
+// ErrInvalidState is returned when a callback carries a state that was not issued or has expired
+var ErrInvalidState = errors.New("invalid or expired OAuth state")
+
 // Service handles OAuth2 authentication for Google Drive API
 type Service struct {
 	config    *oauth2.Config
 	dbService DatabaseService
+
+	revokeURL    string
+	onAuthorized func()
+
+	mu          sync.Mutex
+	pending     map[string]authRequest
+	latestState string
+	token       *oauth2.Token // in-memory token of TokenSource
+}
+
+// authRequest is an issued authorization URL waiting for its callback
+type authRequest struct {
+	verifier  string
+	expiresAt time.Time
 }
 
 // TokenInfo represents token information for display
@@ -31,35 +55,119 @@ type TokenInfo struct {
 	Valid    bool      `json:"valid"`
 }
 
-// NewService creates a new auth service
-func NewService(clientID, clientSecret string, redirectURL string, dbService DatabaseService) *Service {
+// NewService creates a new auth service requesting the given scopes, drive.file by default
+func NewService(clientID, clientSecret string, redirectURL string, dbService DatabaseService, scopes ...string) *Service {
 	config := &oauth2.Config{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
 		RedirectURL:  redirectURL,
-		Scopes:       []string{drive.DriveFileScope},
+		Scopes:       driveScopes(scopes),
 		Endpoint:     google.Endpoint,
 	}
 
 	return &Service{
 		config:    config,
 		dbService: dbService,
+		revokeURL: googleRevokeURL,
+		pending:   make(map[string]authRequest),
 	}
 }
 
-// GetAuthURL returns the authorization URL for OAuth2 flow
+// GetAuthURL returns the authorization URL for OAuth2 flow.
+// Every URL carries its own random state and PKCE challenge, valid for 10 minutes.
 func (s *Service) GetAuthURL() string {
-	return s.config.AuthCodeURL("state-token", oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+	return s.issueAuthURL(authRequestTTL, true)
+}
+
+// GetAuthURLValidFor returns an authorization URL that can be completed within ttl,
+// for links that are not opened right away such as notifications.
+// It leaves the URL that ExchangeToken completes alone, so its code must come back through the
+// callback with its state; the pending state lives in memory and is lost on restart.
+func (s *Service) GetAuthURLValidFor(ttl time.Duration) string {
+	return s.issueAuthURL(ttl, false)
+}
+
+// issueAuthURL registers a new state and PKCE verifier; latest makes it the URL ExchangeToken completes
+func (s *Service) issueAuthURL(ttl time.Duration, latest bool) string {
+	state := rand.Text()
+	verifier := oauth2.GenerateVerifier()
+
+	s.mu.Lock()
+	s.pruneAuthRequests()
+	s.pending[state] = authRequest{verifier: verifier, expiresAt: time.Now().Add(ttl)}
+	if latest {
+		s.latestState = state
+	}
+	s.mu.Unlock()
+
+	return s.config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce, oauth2.S256ChallengeOption(verifier))
 }
 
-// ExchangeToken exchanges authorization code for tokens and saves them
+// ExchangeToken exchanges authorization code for tokens and saves them.
+// The code is expected to come from the most recent GetAuthURL; use ExchangeTokenWithState when the state is known.
 func (s *Service) ExchangeToken(authCode string) error {
-	token, err := s.config.Exchange(context.Background(), authCode)
+	s.mu.Lock()
+	request, _ := s.takeAuthRequest(s.latestState)
+	s.mu.Unlock()
+
+	return s.exchange(authCode, request.verifier)
+}
+
+// ExchangeTokenWithState verifies the state returned to the redirect URL and exchanges the code
+func (s *Service) ExchangeTokenWithState(state, authCode string) error {
+	s.mu.Lock()
+	request, ok := s.takeAuthRequest(state)
+	s.mu.Unlock()
+
+	if !ok {
+		return ErrInvalidState
+	}
+	return s.exchange(authCode, request.verifier)
+}
+
+// takeAuthRequest removes and returns an unexpired auth request; callers hold s.mu
+func (s *Service) takeAuthRequest(state string) (authRequest, bool) {
+	request, ok := s.pending[state]
+	if !ok || state == "" {
+		return authRequest{}, false
+	}
+
+	delete(s.pending, state)
+	if state == s.latestState {
+		s.latestState = ""
+	}
+	if time.Now().After(request.expiresAt) {
+		return authRequest{}, false
+	}
+	return request, true
+}
+
+// pruneAuthRequests drops expired auth requests; callers hold s.mu
+func (s *Service) pruneAuthRequests() {
+	for state, request := range s.pending {
+		if time.Now().After(request.expiresAt) {
+			delete(s.pending, state)
+		}
+	}
+}
+
+// exchange trades the code for tokens, proving possession of the PKCE verifier when there is one
+func (s *Service) exchange(authCode, verifier string) error {
+	var opts []oauth2.AuthCodeOption
+	if verifier != "" {
+		opts = append(opts, oauth2.VerifierOption(verifier))
+	}
+
+	token, err := s.config.Exchange(context.Background(), authCode, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to exchange token: %w", err)
 	}
 
-	// Save token to database
+	return s.saveToken(token)
+}
+
+// saveToken stores a newly granted token
+func (s *Service) saveToken(token *oauth2.Token) error {
 	tokenConfig := &database.TokenConfig{
 		ClientID:     s.config.ClientID,
 		ClientSecret: s.config.ClientSecret,
@@ -72,10 +180,19 @@ func (s *Service) ExchangeToken(authCode string) error {
 	if err := s.dbService.SaveTokenConfig(tokenConfig); err != nil {
 		return fmt.Errorf("failed to save token config: %w", err)
 	}
+	s.forgetToken()
 
+	if s.onAuthorized != nil {
+		s.onAuthorized()
+	}
 	return nil
 }
 
+// OnAuthorized registers a hook that runs after a new authorization is saved, not after refreshes
+func (s *Service) OnAuthorized(hook func()) {
+	s.onAuthorized = hook
+}
+
 // GetValidToken returns a valid token, refreshing if necessary
 func (s *Service) GetValidToken() (*oauth2.Token, error) {
 	// Get stored token
@@ -150,8 +267,13 @@ func (s *Service) ValidateToken() error {
 		return err
 	}
 
+	return validateDriveAccess(s.config, token)
+}
+
+// validateDriveAccess makes a test API call with the token
+func validateDriveAccess(config *oauth2.Config, token *oauth2.Token) error {
 	ctx := context.Background()
-	client := s.config.Client(ctx, token)
+	client := config.Client(ctx, token)
 
 	// Create Drive service and test with a simple API call
 	driveService, err := drive.NewService(ctx, option.WithHTTPClient(client))
@@ -189,6 +311,7 @@ func (s *Service) RefreshToken(token *oauth2.Token) (*oauth2.Token, error) {
 	if err := s.dbService.SaveTokenConfig(tokenConfig); err != nil {
 		return nil, fmt.Errorf("failed to save refreshed token: %w", err)
 	}
+	s.forgetToken()
 
 	return newToken, nil
 }
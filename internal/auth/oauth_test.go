@@ -25,6 +25,11 @@ func (m *MockDatabaseService) SaveTokenConfig(config *database.TokenConfig) erro
 	return args.Error(0)
 }
 
+func (m *MockDatabaseService) DeleteTokenConfig() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
 func (m *MockDatabaseService) GetTokenConfig() (*database.TokenConfig, error) {
 	args := m.Called()
 	if args.Get(0) == nil {
@@ -108,7 +113,9 @@ func (suite *AuthServiceTestSuite) TestGetAuthURL() {
 	suite.Contains(authURL, "redirect_uri=http%3A%2F%2Flocalhost%3A8080%2Fcallback")
 	// URL encoding: scope becomes encoded
 	suite.Contains(authURL, "scope=https%3A%2F%2Fwww.googleapis.com%2Fauth%2Fdrive.file")
-	suite.Contains(authURL, "state=state-token")
+	suite.Contains(authURL, "state=")
+	suite.NotContains(authURL, "state=state-token")
+	suite.Contains(authURL, "code_challenge_method=S256")
 	suite.Contains(authURL, "access_type=offline")
 	// Note: OAuth2 library now uses "prompt=consent" instead of "approval_prompt=force"
 	suite.Contains(authURL, "prompt=consent")
@@ -505,7 +512,8 @@ func TestGetAuthURL_Variations(t *testing.T) {
 
 			assert.Contains(t, authURL, "https://accounts.google.com/o/oauth2/auth")
 			assert.Contains(t, authURL, "client_id="+tc.clientID)
-			assert.Contains(t, authURL, "state=state-token")
+			assert.Contains(t, authURL, "state=")
+			assert.Contains(t, authURL, "code_challenge=")
 			assert.Contains(t, authURL, "access_type=offline")
 		})
 	}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// googleRevokeURL is Google's OAuth2 token revocation endpoint
+const googleRevokeURL = "https://oauth2.googleapis.com/revoke"
+
+// RevokeToken revokes the stored token at Google and deletes it. A token Google no longer
+// recognizes counts as revoked, so a stale record can always be removed.
+func (s *Service) RevokeToken(ctx context.Context) error {
+	tokenConfig, err := s.dbService.GetTokenConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get stored token: %w", err)
+	}
+
+	// Revoking the refresh token also revokes the access tokens issued from it
+	token := tokenConfig.RefreshToken
+	if token == "" {
+		token = tokenConfig.AccessToken
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.revokeURL, strings.NewReader(url.Values{"token": {token}}.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create revoke request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		var revokeError struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &revokeError) != nil || revokeError.Error != "invalid_token" {
+			return fmt.Errorf("failed to revoke token: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+		log.Println("Token was already revoked or expired at Google")
+	}
+
+	if err := s.dbService.DeleteTokenConfig(); err != nil {
+		return fmt.Errorf("failed to delete token config: %w", err)
+	}
+	s.forgetToken()
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/oauth2"
+
+	"github.com/vfa-khuongdv/lazy/internal/database"
+)
+
+// newRevokeTestService points the service at a stand-in revocation endpoint answering with status and body
+func newRevokeTestService(t *testing.T, mockDB *MockDatabaseService, status int, body string) (*Service, *string) {
+	revoked := new(string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.NoError(t, r.ParseForm())
+		*revoked = r.PostForm.Get("token")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	service := NewService("client", "secret", "http://localhost:8081/callback", mockDB)
+	service.revokeURL = server.URL
+	return service, revoked
+}
+
+func TestRevokeToken(t *testing.T) {
+	mockDB := &MockDatabaseService{}
+	mockDB.On("GetTokenConfig").Return(&database.TokenConfig{AccessToken: "access", RefreshToken: "refresh"}, nil)
+	mockDB.On("DeleteTokenConfig").Return(nil).Once()
+	service, revoked := newRevokeTestService(t, mockDB, http.StatusOK, `{}`)
+
+	assert.NoError(t, service.RevokeToken(context.Background()))
+	assert.Equal(t, "refresh", *revoked)
+	mockDB.AssertExpectations(t)
+}
+
+func TestRevokeToken_AlreadyRevoked(t *testing.T) {
+	mockDB := &MockDatabaseService{}
+	mockDB.On("GetTokenConfig").Return(&database.TokenConfig{AccessToken: "access"}, nil)
+	mockDB.On("DeleteTokenConfig").Return(nil).Once()
+	service, revoked := newRevokeTestService(t, mockDB, http.StatusBadRequest, `{"error":"invalid_token","error_description":"Token expired or revoked"}`)
+
+	assert.NoError(t, service.RevokeToken(context.Background()))
+	assert.Equal(t, "access", *revoked, "the access token is revoked when there is no refresh token")
+	mockDB.AssertExpectations(t)
+}
+
+func TestRevokeToken_EndpointError(t *testing.T) {
+	mockDB := &MockDatabaseService{}
+	mockDB.On("GetTokenConfig").Return(&database.TokenConfig{RefreshToken: "refresh"}, nil)
+	service, _ := newRevokeTestService(t, mockDB, http.StatusServiceUnavailable, `{"error":"backend_error"}`)
+
+	err := service.RevokeToken(context.Background())
+	assert.ErrorContains(t, err, "status 503")
+	mockDB.AssertNotCalled(t, "DeleteTokenConfig")
+}
+
+func TestRevokeToken_NoStoredToken(t *testing.T) {
+	mockDB := &MockDatabaseService{}
+	mockDB.On("GetTokenConfig").Return(nil, errors.New("record not found"))
+	service, revoked := newRevokeTestService(t, mockDB, http.StatusOK, `{}`)
+
+	assert.ErrorContains(t, service.RevokeToken(context.Background()), "failed to get stored token")
+	assert.Empty(t, *revoked)
+}
+
+func TestOnAuthorized(t *testing.T) {
+	mockDB := &MockDatabaseService{}
+	mockDB.On("SaveTokenConfig", mock.Anything).Return(nil).Once()
+	service := NewService("client", "secret", "http://localhost:8081/callback", mockDB)
+
+	called := 0
+	service.OnAuthorized(func() { called++ })
+
+	assert.NoError(t, service.saveToken(&oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour)}))
+	assert.Equal(t, 1, called)
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// tokenExpiryLeeway matches GetValidToken, which refreshes tokens this long before they expire
+const tokenExpiryLeeway = 5 * time.Minute
+
+// serviceTokenSource hands out the stored token of a Service to long-lived clients
+type serviceTokenSource struct {
+	service *Service
+}
+
+// TokenSource returns a token source for long-lived clients such as the Drive client. The token is kept
+// in memory until it is about to expire, then refreshed through the store like GetValidToken; a new
+// authorization, refresh or revocation drops it.
+func (s *Service) TokenSource() oauth2.TokenSource {
+	return serviceTokenSource{service: s}
+}
+
+// Token returns the cached token or reads, and if needed refreshes, the stored one
+func (ts serviceTokenSource) Token() (*oauth2.Token, error) {
+	s := ts.service
+
+	s.mu.Lock()
+	cached := s.token
+	s.mu.Unlock()
+	if cached != nil && cached.Expiry.After(time.Now().Add(tokenExpiryLeeway)) {
+		return cached, nil
+	}
+
+	token, err := s.GetValidToken()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.token = token
+	s.mu.Unlock()
+	return token, nil
+}
+
+// forgetToken drops the in-memory token after the stored one changed
+func (s *Service) forgetToken() {
+	s.mu.Lock()
+	s.token = nil
+	s.mu.Unlock()
+}
+
+// TokenSource returns the reusing token source of the credentials
+func (s *CredentialsService) TokenSource() oauth2.TokenSource {
+	return s.tokenSource
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/oauth2"
+
+	"github.com/vfa-khuongdv/lazy/internal/database"
+)
+
+func TestTokenSource_CachesUntilTokenChanges(t *testing.T) {
+	mockDB := &MockDatabaseService{}
+	mockDB.On("GetTokenConfig").Return(&database.TokenConfig{
+		AccessToken:  "stored-access",
+		RefreshToken: "stored-refresh",
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(time.Hour),
+	}, nil)
+	mockDB.On("SaveTokenConfig", mock.Anything).Return(nil)
+	service := NewService("client", "secret", "http://localhost:8081/callback", mockDB)
+	tokenSource := service.TokenSource()
+
+	for i := 0; i < 3; i++ {
+		token, err := tokenSource.Token()
+		assert.NoError(t, err)
+		assert.Equal(t, "stored-access", token.AccessToken)
+	}
+	mockDB.AssertNumberOfCalls(t, "GetTokenConfig", 1)
+
+	// A new authorization drops the cached token
+	assert.NoError(t, service.saveToken(&oauth2.Token{AccessToken: "new-access", RefreshToken: "new-refresh", Expiry: time.Now().Add(time.Hour)}))
+	_, err := tokenSource.Token()
+	assert.NoError(t, err)
+	mockDB.AssertNumberOfCalls(t, "GetTokenConfig", 2)
+}
+
+func TestTokenSource_RereadsExpiringToken(t *testing.T) {
+	mockDB := &MockDatabaseService{}
+	service := NewService("client", "secret", "http://localhost:8081/callback", mockDB)
+	service.token = &oauth2.Token{AccessToken: "expiring", Expiry: time.Now().Add(time.Minute)}
+	mockDB.On("GetTokenConfig").Return(&database.TokenConfig{AccessToken: "fresh", Expiry: time.Now().Add(time.Hour)}, nil).Once()
+
+	token, err := service.TokenSource().Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "fresh", token.AccessToken)
+	mockDB.AssertExpectations(t)
+}
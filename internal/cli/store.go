@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/vfa-khuongdv/lazy/internal/database"
+)
+
+// StoreFlags holds the command-line flags selecting the metadata store
+type StoreFlags struct {
+	driver   *string
+	path     *string
+	host     *string
+	port     *string
+	user     *string
+	password *string
+	database *string
+	sslMode  *string
+}
+
+// RegisterStoreFlags registers the metadata store flags on a flag set
+func RegisterStoreFlags(flags *flag.FlagSet) *StoreFlags {
+	return &StoreFlags{
+		driver:   flags.String("driver", "mysql", "metadata store: mysql, sqlite or postgres"),
+		path:     flags.String("path", "", "SQLite database file"),
+		host:     flags.String("host", "127.0.0.1", "database host"),
+		port:     flags.String("port", "3306", "database port"),
+		user:     flags.String("user", "root", "database user"),
+		password: flags.String("password", os.Getenv("LAZY_DB_PASSWORD"), "database password (default $LAZY_DB_PASSWORD)"),
+		database: flags.String("database", "", "database name"),
+		sslMode:  flags.String("sslmode", "", "PostgreSQL sslmode"),
+	}
+}
+
+// Config builds the metadata store configuration from the parsed flags
+func (f *StoreFlags) Config() (database.ServiceConfig, error) {
+	switch database.Dialect(*f.driver) {
+	case database.DialectMySQL:
+		return &database.ServiceMySQLConfig{Host: *f.host, Port: *f.port, User: *f.user, Password: *f.password, Database: *f.database}, nil
+	case database.DialectPostgres:
+		return &database.ServicePostgresConfig{Host: *f.host, Port: *f.port, User: *f.user, Password: *f.password, Database: *f.database, SSLMode: *f.sslMode}, nil
+	case database.DialectSQLite:
+		return &database.ServiceSQLiteConfig{Path: *f.path}, nil
+	default:
+		return nil, fmt.Errorf("unsupported driver: %s", *f.driver)
+	}
+}
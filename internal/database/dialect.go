@@ -0,0 +1,142 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Dialect identifies the SQL database used as the metadata store
+type Dialect string
+
+const (
+	DialectMySQL    Dialect = "mysql"
+	DialectSQLite   Dialect = "sqlite"
+	DialectPostgres Dialect = "postgres"
+)
+
+// DisplayName returns the human-readable name of the dialect
+func (d Dialect) DisplayName() string {
+	switch d {
+	case DialectMySQL:
+		return "MySQL"
+	case DialectSQLite:
+		return "SQLite"
+	case DialectPostgres:
+		return "PostgreSQL"
+	default:
+		return string(d)
+	}
+}
+
+// ServiceConfig is implemented by every supported metadata store configuration
+type ServiceConfig interface {
+	// Dialect returns the SQL dialect of the store
+	Dialect() Dialect
+
+	// Validate validates the configuration
+	Validate() error
+
+	// Dialector returns the gorm dialector used to open the store
+	Dialector() (gorm.Dialector, error)
+}
+
+// Dialect returns the SQL dialect of the store
+func (c *ServiceMySQLConfig) Dialect() Dialect {
+	return DialectMySQL
+}
+
+// Dialector returns the gorm dialector used to open the store
+func (c *ServiceMySQLConfig) Dialector() (gorm.Dialector, error) {
+	// Build MySQL DSN (Data Source Name)
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+		c.User, c.Password, c.Host, c.Port, c.Database)
+	return mysql.Open(dsn), nil
+}
+
+// ServiceSQLiteConfig represents a SQLite file used as the metadata store
+type ServiceSQLiteConfig struct {
+	Path string `json:"path"` // database file, created if missing
+}
+
+// Dialect returns the SQL dialect of the store
+func (c *ServiceSQLiteConfig) Dialect() Dialect {
+	return DialectSQLite
+}
+
+// Validate validates the SQLite configuration
+func (c *ServiceSQLiteConfig) Validate() error {
+	if c.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	return nil
+}
+
+// Dialector returns the gorm dialector used to open the store
+func (c *ServiceSQLiteConfig) Dialector() (gorm.Dialector, error) {
+	if c.Path != ":memory:" {
+		if err := os.MkdirAll(filepath.Dir(c.Path), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	// Wait on locks instead of failing, and enforce foreign keys like the other dialects.
+	// The path is escaped so '?', '#' and '%' in it cannot cut off or alter these options.
+	dsn := fmt.Sprintf("file:%s?_busy_timeout=5000&_foreign_keys=on&_journal_mode=WAL", (&url.URL{Path: c.Path}).EscapedPath())
+	return sqlite.Open(dsn), nil
+}
+
+// ServicePostgresConfig represents PostgreSQL database configuration for the database service
+type ServicePostgresConfig struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	Database string `json:"database"`
+	SSLMode  string `json:"ssl_mode,omitempty"` // defaults to "require"
+}
+
+// Dialect returns the SQL dialect of the store
+func (c *ServicePostgresConfig) Dialect() Dialect {
+	return DialectPostgres
+}
+
+// Validate validates the PostgreSQL configuration
+func (c *ServicePostgresConfig) Validate() error {
+	if c.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if c.Port == "" {
+		return fmt.Errorf("port is required")
+	}
+	if c.User == "" {
+		return fmt.Errorf("user is required")
+	}
+	if c.Database == "" {
+		return fmt.Errorf("database is required")
+	}
+	return nil
+}
+
+// Dialector returns the gorm dialector used to open the store
+func (c *ServicePostgresConfig) Dialector() (gorm.Dialector, error) {
+	sslMode := c.SSLMode
+	if sslMode == "" {
+		sslMode = "require"
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     c.Host + ":" + c.Port,
+		Path:     "/" + c.Database,
+		RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+	}
+	return postgres.Open(dsn.String()), nil
+}
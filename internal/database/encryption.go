@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/vfa-khuongdv/lazy/internal/secrets"
+	"gorm.io/gorm/schema"
+)
+
+// keyring encrypts the fields tagged with serializer:encrypted; nil stores them in plaintext
+var keyring atomic.Pointer[secrets.Keyring]
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// SetKeyring sets the master keys used to encrypt secrets at rest.
+// Serializers are global in gorm, so the keyring applies to every Service in the process.
+func SetKeyring(k *secrets.Keyring) {
+	keyring.Store(k)
+}
+
+// GetKeyring returns the configured keyring, or nil when secrets are stored in plaintext
+func GetKeyring() *secrets.Keyring {
+	return keyring.Load()
+}
+
+// EncryptedSerializer envelope-encrypts a field with the configured keyring.
+// String fields are encrypted as-is, other types are JSON-encoded first.
+// Plaintext values written before encryption was enabled are still readable.
+type EncryptedSerializer struct{}
+
+// Scan implements serializer interface
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	fieldValue := reflect.New(field.FieldType)
+
+	if dbValue != nil {
+		var stored string
+		switch v := dbValue.(type) {
+		case []byte:
+			stored = string(v)
+		case string:
+			stored = v
+		default:
+			return fmt.Errorf("unsupported value type %T for encrypted field %s", dbValue, field.Name)
+		}
+
+		plaintext := []byte(stored)
+		if secrets.IsEncrypted(stored) {
+			k := GetKeyring()
+			if k == nil {
+				return fmt.Errorf("field %s is encrypted but no master key is configured", field.Name)
+			}
+
+			var err error
+			plaintext, err = k.Decrypt(stored)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt field %s: %w", field.Name, err)
+			}
+		}
+
+		if field.FieldType.Kind() == reflect.String {
+			fieldValue.Elem().SetString(string(plaintext))
+		} else if len(plaintext) > 0 {
+			if err := json.Unmarshal(plaintext, fieldValue.Interface()); err != nil {
+				return fmt.Errorf("failed to decode field %s: %w", field.Name, err)
+			}
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+// Value implements serializer interface
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var plaintext []byte
+	if s, ok := fieldValue.(string); ok {
+		plaintext = []byte(s)
+	} else {
+		data, err := json.Marshal(fieldValue)
+		if err != nil {
+			return nil, err
+		}
+		if string(data) == "null" && field.TagSettings["NOT NULL"] == "" {
+			return nil, nil
+		}
+		plaintext = data
+	}
+
+	k := GetKeyring()
+	if k == nil {
+		return string(plaintext), nil
+	}
+	return k.Encrypt(plaintext)
+}
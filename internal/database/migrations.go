@@ -0,0 +1,403 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrSchemaTooNew is returned when the metadata store was migrated by a newer version of Lazy
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+// Migration is a forward-only schema change compiled into the binary.
+// Up must be safe to run against databases created by AutoMigrate before versioning existed.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+}
+
+// MigrationStatus describes a known migration and whether it has been applied
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// SchemaMigration records an applied migration
+type SchemaMigration struct {
+	Version   int       `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `json:"name" gorm:"not null"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "dbu_schema_migrations"
+}
+
+// Migrations returns every known migration in version order
+func Migrations() []Migration {
+	migrations := []Migration{
+		{Version: 1, Name: "create metadata tables", Up: migrateCreateMetadataTables},
+		{Version: 2, Name: "add config source", Up: migrateAddConfigSource},
+		{Version: 3, Name: "link backup history to config", Up: migrateLinkBackupHistory},
+		{Version: 4, Name: "add drive connections", Up: migrateAddDriveConnections},
+		{Version: 5, Name: "create audit log", Up: migrateCreateAuditLog},
+		{Version: 6, Name: "add notification templates", Up: migrateAddNotificationTemplates},
+		{Version: 7, Name: "create notification outbox", Up: migrateCreateNotificationOutbox},
+		{Version: 8, Name: "add notification suppression", Up: migrateAddNotificationSuppression},
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations
+}
+
+// LatestSchemaVersion returns the highest migration version known to this binary
+func LatestSchemaVersion() int {
+	migrations := Migrations()
+	return migrations[len(migrations)-1].Version
+}
+
+// Migrate applies all pending migrations in order and returns the ones it applied.
+// It refuses to run when the database has been migrated past LatestSchemaVersion.
+func Migrate(db *gorm.DB) ([]MigrationStatus, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []MigrationStatus
+	for _, migration := range pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+
+		now := time.Now()
+		applied = append(applied, MigrationStatus{Version: migration.Version, Name: migration.Name, AppliedAt: &now})
+		log.Printf("Applied migration %d: %s", migration.Version, migration.Name)
+	}
+
+	return applied, nil
+}
+
+// PendingMigrations lists the migrations Migrate would apply, without changing anything
+func PendingMigrations(db *gorm.DB) ([]Migration, error) {
+	appliedVersions, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSchemaVersion(appliedVersions); err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range Migrations() {
+		if _, ok := appliedVersions[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// GetMigrationStatus lists every known migration with the time it was applied, if any
+func GetMigrationStatus(db *gorm.DB) ([]MigrationStatus, error) {
+	appliedVersions, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range Migrations() {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := appliedVersions[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// CheckSchemaVersion returns ErrSchemaTooNew when the database was migrated by a newer binary
+func CheckSchemaVersion(db *gorm.DB) error {
+	appliedVersions, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	return checkSchemaVersion(appliedVersions)
+}
+
+// checkSchemaVersion compares the applied versions against the known migrations
+func checkSchemaVersion(appliedVersions map[int]SchemaMigration) error {
+	latest := LatestSchemaVersion()
+	for version := range appliedVersions {
+		if version > latest {
+			return fmt.Errorf("%w: database is at version %d, binary supports up to %d", ErrSchemaTooNew, version, latest)
+		}
+	}
+	return nil
+}
+
+// appliedMigrations loads the applied migrations keyed by version
+func appliedMigrations(db *gorm.DB) (map[int]SchemaMigration, error) {
+	// Databases created before versioning have no migrations table yet
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return map[int]SchemaMigration{}, nil
+	}
+
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+
+	applied := make(map[int]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// The structs below are snapshots of the models at the time each migration was written.
+// They must not change once released; evolve the models through new migrations instead.
+
+type tokenConfigV1 struct {
+	ID           uint   `gorm:"primarykey"`
+	ClientID     string `gorm:"not null"`
+	ClientSecret string `gorm:"not null"`
+	AccessToken  string `gorm:"not null"`
+	RefreshToken string `gorm:"not null"`
+	TokenType    string `gorm:"default:Bearer"`
+	Expiry       time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (tokenConfigV1) TableName() string { return "dbu_token_configs" }
+
+type backupHistoryV1 struct {
+	ID          uint   `gorm:"primarykey"`
+	DatabaseURL string `gorm:"not null"`
+	BackupType  string `gorm:"not null"`
+	FileName    string `gorm:"not null"`
+	FileID      string
+	FileSize    int64
+	Status      string
+	ErrorMsg    string
+	StartedAt   time.Time
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (backupHistoryV1) TableName() string { return "dbu_backup_histories" }
+
+type backupConfigV1 struct {
+	ID           uint   `gorm:"primarykey"`
+	Name         string `gorm:"not null;unique"`
+	BackupMode   string `gorm:"not null"`
+	DatabaseURL  string `gorm:"not null"`
+	DatabaseType string `gorm:"not null"`
+	CronSchedule string `gorm:"not null"`
+	Enabled      bool   `gorm:"default:true"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (backupConfigV1) TableName() string { return "dbu_backup_configs" }
+
+type notificationConfigV1 struct {
+	ID              uint                   `gorm:"primarykey"`
+	Name            string                 `gorm:"not null;unique"`
+	Channel         string                 `gorm:"not null"`
+	Enabled         bool                   `gorm:"default:false"`
+	Config          map[string]interface{} `gorm:"serializer:json"`
+	NotifyOnSuccess bool                   `gorm:"default:false"`
+	NotifyOnError   bool                   `gorm:"default:false"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+func (notificationConfigV1) TableName() string { return "dbu_notification_configs" }
+
+// migrateCreateMetadataTables creates the original tables
+func migrateCreateMetadataTables(tx *gorm.DB) error {
+	return tx.AutoMigrate(
+		&tokenConfigV1{},
+		&backupHistoryV1{},
+		&backupConfigV1{},
+		&notificationConfigV1{},
+	)
+}
+
+type backupConfigV2 struct {
+	Source string `gorm:"default:runtime"`
+}
+
+func (backupConfigV2) TableName() string { return "dbu_backup_configs" }
+
+type notificationConfigV2 struct {
+	Source string `gorm:"default:runtime"`
+}
+
+func (notificationConfigV2) TableName() string { return "dbu_notification_configs" }
+
+// migrateAddConfigSource records whether a config is declared in lazy.Config or added at runtime
+func migrateAddConfigSource(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	if !migrator.HasColumn(&backupConfigV2{}, "Source") {
+		if err := migrator.AddColumn(&backupConfigV2{}, "Source"); err != nil {
+			return err
+		}
+	}
+	if !migrator.HasColumn(&notificationConfigV2{}, "Source") {
+		if err := migrator.AddColumn(&notificationConfigV2{}, "Source"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type backupHistoryV3 struct {
+	ID             uint            `gorm:"primarykey"`
+	BackupConfigID *uint           `gorm:"index"`
+	BackupConfig   *backupConfigV1 `gorm:"foreignKey:BackupConfigID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	ConfigName     string          `gorm:"index"`
+	DatabaseURL    string          `gorm:"not null"`
+	BackupType     string          `gorm:"not null"`
+	BackupMode     string
+	TriggerSource  string `gorm:"index"`
+	Host           string
+	FileName       string `gorm:"not null"`
+	FileID         string
+	FileSize       int64
+	Status         string
+	ErrorMsg       string
+	StartedAt      time.Time `gorm:"index"`
+	CompletedAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (backupHistoryV3) TableName() string { return "dbu_backup_histories" }
+
+// migrateLinkBackupHistory adds the config foreign key and run metadata to backup history
+func migrateLinkBackupHistory(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&backupHistoryV3{}); err != nil {
+		return err
+	}
+
+	// Runs recorded before the trigger source existed could only be scheduled
+	return tx.Model(&backupHistoryV3{}).
+		Where("trigger_source IS NULL OR trigger_source = ?", "").
+		Update("trigger_source", TriggerScheduled).Error
+}
+
+type tokenConfigV4 struct {
+	Connection string `gorm:"not null;uniqueIndex;default:default"`
+}
+
+func (tokenConfigV4) TableName() string { return "dbu_token_configs" }
+
+type backupConfigV4 struct {
+	Connection string `gorm:"not null;default:default"`
+}
+
+func (backupConfigV4) TableName() string { return "dbu_backup_configs" }
+
+// migrateAddDriveConnections names the token record and lets backup configs pick one.
+// Existing rows belong to the default connection.
+func migrateAddDriveConnections(tx *gorm.DB) error {
+	return tx.AutoMigrate(&tokenConfigV4{}, &backupConfigV4{})
+}
+
+type auditEntryV5 struct {
+	ID        uint   `gorm:"primarykey"`
+	Action    string `gorm:"not null;index"`
+	Target    string `gorm:"index"`
+	Detail    string
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (auditEntryV5) TableName() string { return "dbu_audit_entries" }
+
+// migrateCreateAuditLog adds the audit log of administrative actions
+func migrateCreateAuditLog(tx *gorm.DB) error {
+	return tx.AutoMigrate(&auditEntryV5{})
+}
+
+type notificationConfigV6 struct {
+	Templates map[string]interface{} `gorm:"serializer:json"`
+}
+
+func (notificationConfigV6) TableName() string { return "dbu_notification_configs" }
+
+// migrateAddNotificationTemplates stores the message templates of notification configs
+func migrateAddNotificationTemplates(tx *gorm.DB) error {
+	return tx.AutoMigrate(&notificationConfigV6{})
+}
+
+type notificationDeliveryV7 struct {
+	ID            uint   `gorm:"primarykey"`
+	ConfigName    string `gorm:"not null;index"`
+	Channel       string `gorm:"not null"`
+	Event         string `gorm:"index"`
+	Payload       string `gorm:"type:text;not null"`
+	Status        string `gorm:"not null;index"`
+	Attempts      int
+	NextAttemptAt time.Time `gorm:"index"`
+	LastError     string
+	DeliveredAt   *time.Time
+	CreatedAt     time.Time `gorm:"index"`
+	UpdatedAt     time.Time
+}
+
+func (notificationDeliveryV7) TableName() string { return "dbu_notification_deliveries" }
+
+type notificationAttemptV7 struct {
+	ID         uint   `gorm:"primarykey"`
+	DeliveryID *uint  `gorm:"index"`
+	ConfigName string `gorm:"index"`
+	Channel    string
+	Event      string
+	Attempt    int
+	Success    bool
+	Error      string
+	StatusCode int
+	SentAt     time.Time `gorm:"index"`
+}
+
+func (notificationAttemptV7) TableName() string { return "dbu_notification_attempts" }
+
+// migrateCreateNotificationOutbox adds the outbox of queued notifications and the log of every send attempt
+func migrateCreateNotificationOutbox(tx *gorm.DB) error {
+	return tx.AutoMigrate(&notificationDeliveryV7{}, &notificationAttemptV7{})
+}
+
+type notificationConfigV8 struct {
+	Suppression map[string]interface{} `gorm:"serializer:json"`
+}
+
+func (notificationConfigV8) TableName() string { return "dbu_notification_configs" }
+
+// migrateAddNotificationSuppression stores the alert suppression rules of notification configs
+func migrateAddNotificationSuppression(tx *gorm.DB) error {
+	return tx.AutoMigrate(&notificationConfigV8{})
+}
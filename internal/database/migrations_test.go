@@ -0,0 +1,125 @@
+package database
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func newMigrationTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestMigrate_FreshDatabase(t *testing.T) {
+	db := newMigrationTestDB(t)
+
+	pending, err := PendingMigrations(db)
+	assert.NoError(t, err)
+	assert.Len(t, pending, len(Migrations()))
+	assert.False(t, db.Migrator().HasTable(&SchemaMigration{}), "dry run must not change the database")
+
+	applied, err := Migrate(db)
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(Migrations()))
+
+	// Running again is a no-op
+	applied, err = Migrate(db)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := GetMigrationStatus(db)
+	assert.NoError(t, err)
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt, "migration %d should be applied", status.Version)
+	}
+}
+
+// The migrations must produce every column the current models use
+func TestMigrate_MatchesModels(t *testing.T) {
+	db := newMigrationTestDB(t)
+	_, err := Migrate(db)
+	assert.NoError(t, err)
+
+	models := []interface{}{&TokenConfig{}, &BackupHistory{}, &BackupConfig{}, &NotificationConfig{}, &AuditEntry{}, &NotificationDelivery{}, &NotificationAttempt{}}
+	for _, model := range models {
+		parsed, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+		assert.NoError(t, err)
+
+		for _, field := range parsed.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			assert.True(t, db.Migrator().HasColumn(model, field.DBName), "%s.%s is missing", parsed.Table, field.DBName)
+		}
+	}
+	assert.True(t, db.Migrator().HasConstraint(&BackupHistory{}, "BackupConfig"))
+}
+
+func TestMigrate_LegacyDatabase(t *testing.T) {
+	db := newMigrationTestDB(t)
+
+	// A database created by AutoMigrate before versioning existed
+	assert.NoError(t, db.AutoMigrate(&tokenConfigV1{}, &backupHistoryV1{}, &backupConfigV1{}, &notificationConfigV1{}))
+	assert.NoError(t, db.Create(&backupHistoryV1{DatabaseURL: "", BackupType: "mysql", FileName: "old.sql", Status: "success", StartedAt: time.Now()}).Error)
+	assert.NoError(t, db.Create(&backupConfigV1{Name: "app", BackupMode: "full", DatabaseURL: "mysql://", DatabaseType: "mysql", CronSchedule: "0 0 * * * *"}).Error)
+	assert.NoError(t, db.Create(&tokenConfigV1{ClientID: "id", ClientSecret: "secret", AccessToken: "access", RefreshToken: "refresh"}).Error)
+
+	_, err := Migrate(db)
+	assert.NoError(t, err)
+
+	// Existing rows survive and are backfilled
+	var history BackupHistory
+	assert.NoError(t, db.First(&history).Error)
+	assert.Equal(t, "old.sql", history.FileName)
+	assert.Equal(t, TriggerScheduled, history.TriggerSource)
+
+	var config BackupConfig
+	assert.NoError(t, db.First(&config).Error)
+	assert.Equal(t, "app", config.Name)
+	assert.Equal(t, ConfigSourceRuntime, config.Source)
+	assert.Equal(t, DefaultConnection, config.Connection)
+
+	// The single legacy token becomes the default connection
+	token, err := (&Service{db: db}).GetTokenConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "access", token.AccessToken)
+}
+
+func TestMigrate_SchemaTooNew(t *testing.T) {
+	db := newMigrationTestDB(t)
+	_, err := Migrate(db)
+	assert.NoError(t, err)
+
+	future := LatestSchemaVersion() + 1
+	assert.NoError(t, db.Create(&SchemaMigration{Version: future, Name: "from the future", AppliedAt: time.Now()}).Error)
+
+	_, err = Migrate(db)
+	assert.ErrorIs(t, err, ErrSchemaTooNew)
+
+	_, err = PendingMigrations(db)
+	assert.ErrorIs(t, err, ErrSchemaTooNew)
+
+	assert.ErrorIs(t, CheckSchemaVersion(db), ErrSchemaTooNew)
+}
+
+func TestMigrations_Ordered(t *testing.T) {
+	migrations := Migrations()
+	for i, migration := range migrations {
+		assert.Equal(t, i+1, migration.Version, "versions must be sequential")
+		assert.NotEmpty(t, migration.Name)
+		assert.NotNil(t, migration.Up)
+	}
+	assert.Equal(t, migrations[len(migrations)-1].Version, LatestSchemaVersion())
+}
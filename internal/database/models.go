@@ -7,13 +7,23 @@ import (
 	"gorm.io/gorm"
 )
 
-// TokenConfig stores Google OAuth2 tokens for Drive API access
+// Config sources record where a stored configuration came from
+const (
+	ConfigSourceStatic  = "static"  // declared in lazy.Config and reconciled on start
+	ConfigSourceRuntime = "runtime" // added through the API while running
+)
+
+// DefaultConnection is the Drive connection used when none is named
+const DefaultConnection = "default"
+
+// TokenConfig stores Google OAuth2 tokens for Drive API access, one record per Drive connection
 type TokenConfig struct {
 	ID           uint      `json:"id" gorm:"primarykey"`
+	Connection   string    `json:"connection" gorm:"not null;uniqueIndex;default:default"`
 	ClientID     string    `json:"client_id" gorm:"not null"`
-	ClientSecret string    `json:"client_secret" gorm:"not null"`
-	AccessToken  string    `json:"access_token" gorm:"not null"`
-	RefreshToken string    `json:"refresh_token" gorm:"not null"`
+	ClientSecret string    `json:"client_secret" gorm:"serializer:encrypted;not null"`
+	AccessToken  string    `json:"access_token" gorm:"serializer:encrypted;not null"`
+	RefreshToken string    `json:"refresh_token" gorm:"serializer:encrypted;not null"`
 	TokenType    string    `json:"token_type" gorm:"default:Bearer"`
 	Expiry       time.Time `json:"expiry"`
 	CreatedAt    time.Time `json:"created_at"`
@@ -22,46 +32,165 @@ type TokenConfig struct {
 
 // BackupHistory keeps track of backup operations
 type BackupHistory struct {
-	ID          uint       `json:"id" gorm:"primarykey"`
-	DatabaseURL string     `json:"database_url" gorm:"not null"`
-	BackupType  string     `json:"backup_type" gorm:"not null"` // mysql, postgres, etc.
-	FileName    string     `json:"file_name" gorm:"not null"`
-	FileID      string     `json:"file_id"`   // Google Drive file ID
-	FileSize    int64      `json:"file_size"` // File size in bytes
-	Status      string     `json:"status"`    // success, failed, in_progress
-	ErrorMsg    string     `json:"error_msg"` // Error message if failed
-	StartedAt   time.Time  `json:"started_at"`
-	CompletedAt *time.Time `json:"completed_at"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID             uint          `json:"id" gorm:"primarykey"`
+	BackupConfigID *uint         `json:"backup_config_id" gorm:"index"` // nil once the config is deleted
+	BackupConfig   *BackupConfig `json:"backup_config,omitempty" gorm:"foreignKey:BackupConfigID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+	ConfigName     string        `json:"config_name" gorm:"index"`     // kept after the config is deleted
+	DatabaseURL    string        `json:"database_url" gorm:"not null"` // password redacted
+	BackupType     string        `json:"backup_type" gorm:"not null"`  // mysql, postgres, etc.
+	BackupMode     string        `json:"backup_mode"`                  // full, schema
+	TriggerSource  string        `json:"trigger_source" gorm:"index"`  // scheduled, manual
+	Host           string        `json:"host"`                         // target database host
+	FileName       string        `json:"file_name" gorm:"not null"`
+	FileID         string        `json:"file_id"`   // Google Drive file ID
+	FileSize       int64         `json:"file_size"` // File size in bytes
+	Status         string        `json:"status"`    // success, failed, skipped, in_progress
+	ErrorMsg       string        `json:"error_msg"` // Error message if failed
+	StartedAt      time.Time     `json:"started_at" gorm:"index"`
+	CompletedAt    *time.Time    `json:"completed_at"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+// Backup history trigger sources
+const (
+	TriggerScheduled = "scheduled"
+	TriggerManual    = "manual"
+)
+
+// Backup history statuses
+const (
+	BackupStatusInProgress = "in_progress"
+	BackupStatusSuccess    = "success"
+	BackupStatusFailed     = "failed"
+	BackupStatusSkipped    = "skipped" // not run, e.g. because Drive storage is full
+)
+
+// HistoryFilter narrows backup history queries; zero values are ignored
+type HistoryFilter struct {
+	Status        string    `json:"status,omitempty"`
+	TriggerSource string    `json:"trigger_source,omitempty"`
+	From          time.Time `json:"from,omitempty"` // inclusive, on started_at
+	To            time.Time `json:"to,omitempty"`   // exclusive, on started_at
+	Limit         int       `json:"limit,omitempty"`
+	Offset        int       `json:"offset,omitempty"`
 }
 
 // BackupConfig stores backup configuration settings
 type BackupConfig struct {
 	ID           uint      `json:"id" gorm:"primarykey"`
 	Name         string    `json:"name" gorm:"not null;unique"`
-	BackupMode   string    `json:"backup_mode" gorm:"not null"` // full, schema, data
-	DatabaseURL  string    `json:"database_url" gorm:"not null"`
-	DatabaseType string    `json:"database_type" gorm:"not null"` // mysql, postgres, etc.
-	CronSchedule string    `json:"cron_schedule" gorm:"not null"` // e.g., "0 2 * * *" (daily at 2 AM)
+	BackupMode   string    `json:"backup_mode" gorm:"not null"`                       // full, schema, data
+	DatabaseURL  string    `json:"database_url" gorm:"serializer:encrypted;not null"` // embeds the password
+	DatabaseType string    `json:"database_type" gorm:"not null"`                     // mysql, postgres, etc.
+	CronSchedule string    `json:"cron_schedule" gorm:"not null"`                     // e.g., "0 2 * * *" (daily at 2 AM)
 	Enabled      bool      `json:"enabled" gorm:"default:true"`
+	Source       string    `json:"source" gorm:"default:runtime"`              // static, runtime
+	Connection   string    `json:"connection" gorm:"not null;default:default"` // Drive connection the backups are uploaded to
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 // NotificationConfig stores notification channel configurations
 type NotificationConfig struct {
-	ID              uint                   `json:"id" gorm:"primarykey"`
-	Name            string                 `json:"name" gorm:"not null;unique"`
-	Channel         string                 `json:"channel" gorm:"not null"`
-	Enabled         bool                   `json:"enabled" gorm:"default:false"`
-	Config          map[string]interface{} `json:"config" gorm:"serializer:json"`
-	NotifyOnSuccess bool                   `json:"notify_on_success" gorm:"default:false"`
-	NotifyOnError   bool                   `json:"notify_on_error" gorm:"default:false"`
-	CreatedAt       time.Time              `json:"created_at"`
-	UpdatedAt       time.Time              `json:"updated_at"`
+	ID              uint                     `json:"id" gorm:"primarykey"`
+	Name            string                   `json:"name" gorm:"not null;unique"`
+	Channel         string                   `json:"channel" gorm:"not null"`
+	Enabled         bool                     `json:"enabled" gorm:"default:false"`
+	Config          map[string]interface{}   `json:"config" gorm:"serializer:encrypted"` // webhook URLs, API tokens
+	NotifyOnSuccess bool                     `json:"notify_on_success" gorm:"default:false"`
+	NotifyOnError   bool                     `json:"notify_on_error" gorm:"default:false"`
+	Templates       *NotificationTemplates   `json:"templates,omitempty" gorm:"serializer:json"`   // nil uses the built-in messages
+	Suppression     *NotificationSuppression `json:"suppression,omitempty" gorm:"serializer:json"` // nil uses the global rules
+	Source          string                   `json:"source" gorm:"default:runtime"`                // static, runtime
+	CreatedAt       time.Time                `json:"created_at"`
+	UpdatedAt       time.Time                `json:"updated_at"`
+}
+
+// NotificationTemplates holds text/template sources overriding the backup messages of a notification config.
+// Empty templates keep the built-in title, text and fields.
+type NotificationTemplates struct {
+	Title  string            `json:"title,omitempty"`
+	Text   string            `json:"text,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"` // field name to template; replaces the built-in fields
+}
+
+// IsEmpty reports whether no template is set
+func (t *NotificationTemplates) IsEmpty() bool {
+	return t == nil || (t.Title == "" && t.Text == "" && len(t.Fields) == 0)
+}
+
+// NotificationSuppression limits repeated failure alerts of a backup config to a notification config.
+// The first failure is always notified, later ones only when a rule matches; zero values disable a rule.
+type NotificationSuppression struct {
+	RepeatEvery int           `json:"repeat_every,omitempty"` // notify again every N consecutive failures, 1 notifies every failure
+	RepeatAfter time.Duration `json:"repeat_after,omitempty"` // notify again once this long has passed since the last alert
+}
+
+// NotificationDelivery is a message queued in the notification outbox for one notification config
+type NotificationDelivery struct {
+	ID            uint       `json:"id" gorm:"primarykey"`
+	ConfigName    string     `json:"config_name" gorm:"not null;index"`
+	Channel       string     `json:"channel" gorm:"not null"`
+	Event         string     `json:"event" gorm:"index"`
+	Payload       string     `json:"payload" gorm:"type:text;not null"` // JSON encoded message
+	Status        string     `json:"status" gorm:"not null;index"`      // pending, delivered, dead
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"index"`
+	LastError     string     `json:"last_error"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"index"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
+// Notification delivery statuses
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusDead      = "dead" // gave up after too many or a permanent failure
+)
+
+// NotificationAttempt records the result of one attempt to send a notification
+type NotificationAttempt struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	DeliveryID *uint     `json:"delivery_id" gorm:"index"` // nil for notifications sent without the outbox
+	ConfigName string    `json:"config_name" gorm:"index"`
+	Channel    string    `json:"channel"`
+	Event      string    `json:"event"`
+	Attempt    int       `json:"attempt"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	StatusCode int       `json:"status_code,omitempty"` // HTTP status returned by the channel API, if any
+	SentAt     time.Time `json:"sent_at" gorm:"index"`
+}
+
+// NotificationFilter narrows notification delivery and attempt queries; zero values are ignored
+type NotificationFilter struct {
+	ConfigName string    `json:"config_name,omitempty"`
+	Channel    string    `json:"channel,omitempty"`
+	Event      string    `json:"event,omitempty"`
+	Status     string    `json:"status,omitempty"` // delivery status, or "success" / "failed" for attempts
+	From       time.Time `json:"from,omitempty"`   // inclusive, on created_at or sent_at
+	To         time.Time `json:"to,omitempty"`     // exclusive, on created_at or sent_at
+	Limit      int       `json:"limit,omitempty"`
+	Offset     int       `json:"offset,omitempty"`
+}
+
+// AuditEntry records an administrative action, e.g. revoking a Drive authorization
+type AuditEntry struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Action    string    `json:"action" gorm:"not null;index"`
+	Target    string    `json:"target" gorm:"index"` // e.g. the Drive connection acted on
+	Detail    string    `json:"detail"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// Audit actions
+const (
+	AuditAuthRevoked    = "auth.revoked"
+	AuditAuthAuthorized = "auth.authorized"
+)
+
 // Add table names
 func (TokenConfig) TableName() string {
 	return "dbu_token_configs"
@@ -79,6 +208,18 @@ func (NotificationConfig) TableName() string {
 	return "dbu_notification_configs"
 }
 
+func (AuditEntry) TableName() string {
+	return "dbu_audit_entries"
+}
+
+func (NotificationDelivery) TableName() string {
+	return "dbu_notification_deliveries"
+}
+
+func (NotificationAttempt) TableName() string {
+	return "dbu_notification_attempts"
+}
+
 // ServiceMySQLConfig represents MySQL database configuration for the database service
 type ServiceMySQLConfig struct {
 	Host     string `json:"host"`
@@ -105,12 +246,8 @@ func (c *ServiceMySQLConfig) Validate() error {
 	return nil
 }
 
-// AutoMigrate runs database migrations for all models
+// AutoMigrate applies all pending versioned migrations, see Migrate
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
-		&TokenConfig{},
-		&BackupHistory{},
-		&BackupConfig{},
-		&NotificationConfig{},
-	)
+	_, err := Migrate(db)
+	return err
 }
@@ -3,43 +3,89 @@ package database
 import (
 	"errors"
 	"fmt"
+	"time"
 
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
 type Service struct {
-	db *gorm.DB
+	db      *gorm.DB
+	dialect Dialect
 }
 
-// NewService creates a new database service using ServiceMySQLConfig
-func NewService(config *ServiceMySQLConfig) (*Service, error) {
+// NewService creates a new database service for the configured metadata store
+// and applies any pending migrations
+func NewService(config ServiceConfig) (*Service, error) {
+	service, err := OpenService(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := service.Migrate(); err != nil {
+		service.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	return service, nil
+}
+
+// OpenService connects to the configured metadata store without migrating it
+func OpenService(config ServiceConfig) (*Service, error) {
 	if config == nil {
-		return nil, fmt.Errorf("MySQL configuration is required")
+		return nil, fmt.Errorf("database configuration is required")
 	}
 
+	dialect := config.Dialect()
 	if err := config.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid MySQL configuration: %w", err)
+		return nil, fmt.Errorf("invalid %s configuration: %w", dialect.DisplayName(), err)
 	}
 
-	// Build MySQL DSN (Data Source Name)
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		config.User, config.Password, config.Host, config.Port, config.Database)
-
-	// Connect to MySQL database
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	dialector, err := config.Dialector()
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to MySQL database: %w", err)
+		return nil, fmt.Errorf("invalid %s configuration: %w", dialect.DisplayName(), err)
 	}
 
-	service := &Service{db: db}
+	// Connect to the database
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s database: %w", dialect.DisplayName(), err)
+	}
 
-	// Auto-migrate models
-	if err := AutoMigrate(db); err != nil {
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	// SQLite allows a single writer; serialize access instead of failing with "database is locked"
+	if dialect == DialectSQLite {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get SQLite connection pool: %w", err)
+		}
+		sqlDB.SetMaxOpenConns(1)
 	}
 
-	return service, nil
+	return &Service{db: db, dialect: dialect}, nil
+}
+
+// Migrate applies all pending migrations, see Migrate
+func (s *Service) Migrate() ([]MigrationStatus, error) {
+	return Migrate(s.db)
+}
+
+// PendingMigrations lists the migrations that have not been applied yet
+func (s *Service) PendingMigrations() ([]Migration, error) {
+	return PendingMigrations(s.db)
+}
+
+// GetMigrationStatus lists every known migration with the time it was applied, if any
+func (s *Service) GetMigrationStatus() ([]MigrationStatus, error) {
+	return GetMigrationStatus(s.db)
+}
+
+// CheckSchemaVersion returns ErrSchemaTooNew when the database was migrated by a newer binary
+func (s *Service) CheckSchemaVersion() error {
+	return CheckSchemaVersion(s.db)
+}
+
+// Dialect returns the SQL dialect of the metadata store
+func (s *Service) Dialect() Dialect {
+	return s.dialect
 }
 
 // GetDB returns the database instance
@@ -47,11 +93,15 @@ func (s *Service) GetDB() *gorm.DB {
 	return s.db
 }
 
-// SaveTokenConfig saves or updates token configuration
+// SaveTokenConfig saves or updates the token of config.Connection, the default connection when empty
 func (s *Service) SaveTokenConfig(config *TokenConfig) error {
+	if config.Connection == "" {
+		config.Connection = DefaultConnection
+	}
+
 	// Try to find existing config first
 	var existing TokenConfig
-	if err := s.db.First(&existing).Error; err != nil {
+	if err := s.db.Where("connection = ?", config.Connection).First(&existing).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			// Create new record
 			return s.db.Create(config).Error
@@ -65,15 +115,213 @@ func (s *Service) SaveTokenConfig(config *TokenConfig) error {
 	return s.db.Save(config).Error
 }
 
-// GetTokenConfig retrieves the token configuration
+// GetTokenConfig retrieves the token configuration of the default connection
 func (s *Service) GetTokenConfig() (*TokenConfig, error) {
+	return s.GetTokenConfigByConnection(DefaultConnection)
+}
+
+// GetTokenConfigByConnection retrieves the token configuration of a named Drive connection
+func (s *Service) GetTokenConfigByConnection(connection string) (*TokenConfig, error) {
 	var config TokenConfig
-	if err := s.db.First(&config).Error; err != nil {
+	if err := s.db.Where("connection = ?", connection).First(&config).Error; err != nil {
 		return nil, err
 	}
 	return &config, nil
 }
 
+// DeleteTokenConfig deletes the token configuration of the default connection
+func (s *Service) DeleteTokenConfig() error {
+	return s.DeleteTokenConfigByConnection(DefaultConnection)
+}
+
+// DeleteTokenConfigByConnection deletes the token configuration of a named Drive connection
+func (s *Service) DeleteTokenConfigByConnection(connection string) error {
+	return s.db.Where("connection = ?", connection).Delete(&TokenConfig{}).Error
+}
+
+// GetTokenConfigs retrieves the token configurations of all Drive connections
+func (s *Service) GetTokenConfigs() ([]TokenConfig, error) {
+	var configs []TokenConfig
+	err := s.db.Order("connection").Find(&configs).Error
+	return configs, err
+}
+
+// TokenStore returns the token storage of a single Drive connection
+func (s *Service) TokenStore(connection string) *TokenStore {
+	return &TokenStore{service: s, connection: connection}
+}
+
+// TokenStore reads and writes the token of one Drive connection
+type TokenStore struct {
+	service    *Service
+	connection string
+}
+
+// SaveTokenConfig saves the token under the store's connection
+func (t *TokenStore) SaveTokenConfig(config *TokenConfig) error {
+	config.Connection = t.connection
+	return t.service.SaveTokenConfig(config)
+}
+
+// GetTokenConfig retrieves the token of the store's connection
+func (t *TokenStore) GetTokenConfig() (*TokenConfig, error) {
+	return t.service.GetTokenConfigByConnection(t.connection)
+}
+
+// DeleteTokenConfig deletes the token of the store's connection
+func (t *TokenStore) DeleteTokenConfig() error {
+	return t.service.DeleteTokenConfigByConnection(t.connection)
+}
+
+// SaveAuditEntry records an administrative action
+func (s *Service) SaveAuditEntry(entry *AuditEntry) error {
+	return s.db.Create(entry).Error
+}
+
+// GetAuditEntries retrieves audit entries newest first, only those of target when it is not empty
+func (s *Service) GetAuditEntries(target string, limit int) ([]AuditEntry, error) {
+	query := s.db.Order("created_at DESC").Order("id DESC")
+	if target != "" {
+		query = query.Where("target = ?", target)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var entries []AuditEntry
+	err := query.Find(&entries).Error
+	return entries, err
+}
+
+// GetLatestAuditEntry retrieves the most recent entry of target with one of the given actions
+func (s *Service) GetLatestAuditEntry(target string, actions ...string) (*AuditEntry, error) {
+	var entry AuditEntry
+	err := s.db.Where("target = ? AND action IN ?", target, actions).
+		Order("created_at DESC").Order("id DESC").First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// SaveNotificationDelivery queues a notification in the outbox
+func (s *Service) SaveNotificationDelivery(delivery *NotificationDelivery) error {
+	return s.db.Create(delivery).Error
+}
+
+// UpdateNotificationDelivery updates a queued notification
+func (s *Service) UpdateNotificationDelivery(delivery *NotificationDelivery) error {
+	return s.db.Save(delivery).Error
+}
+
+// GetNotificationDelivery retrieves a queued notification by ID
+func (s *Service) GetNotificationDelivery(id uint) (*NotificationDelivery, error) {
+	var delivery NotificationDelivery
+	if err := s.db.First(&delivery, id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// GetDueNotificationDeliveries retrieves pending notifications whose next attempt is due, oldest first.
+// Each notification config is delivered in order: only its oldest pending notification is returned,
+// and only once it is due, so a resolve never overtakes the trigger waiting to be retried before it.
+func (s *Service) GetDueNotificationDeliveries(now time.Time, limit int) ([]NotificationDelivery, error) {
+	query := s.db.Where("status = ? AND next_attempt_at <= ?", DeliveryStatusPending, now).
+		Where("NOT EXISTS (SELECT 1 FROM dbu_notification_deliveries older"+
+			" WHERE older.config_name = dbu_notification_deliveries.config_name"+
+			" AND older.status = ? AND older.id < dbu_notification_deliveries.id)", DeliveryStatusPending).
+		Order("id ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var deliveries []NotificationDelivery
+	err := query.Find(&deliveries).Error
+	return deliveries, err
+}
+
+// GetNotificationDeliveries retrieves queued notifications newest first,
+// along with the total number of records matching the filter
+func (s *Service) GetNotificationDeliveries(filter NotificationFilter) ([]NotificationDelivery, int64, error) {
+	query := s.db.Model(&NotificationDelivery{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	query = applyNotificationFilter(query, filter, "created_at")
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []NotificationDelivery
+	if err := paginateNotifications(query.Order("created_at DESC").Order("id DESC"), filter).Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+// SaveNotificationAttempt records the result of sending a notification
+func (s *Service) SaveNotificationAttempt(attempt *NotificationAttempt) error {
+	return s.db.Create(attempt).Error
+}
+
+// GetNotificationAttempts retrieves notification send results newest first,
+// along with the total number of records matching the filter
+func (s *Service) GetNotificationAttempts(filter NotificationFilter) ([]NotificationAttempt, int64, error) {
+	query := s.db.Model(&NotificationAttempt{})
+	switch filter.Status {
+	case "success":
+		query = query.Where("success = ?", true)
+	case "failed":
+		query = query.Where("success = ?", false)
+	}
+	query = applyNotificationFilter(query, filter, "sent_at")
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var attempts []NotificationAttempt
+	if err := paginateNotifications(query.Order("sent_at DESC").Order("id DESC"), filter).Find(&attempts).Error; err != nil {
+		return nil, 0, err
+	}
+	return attempts, total, nil
+}
+
+// applyNotificationFilter narrows a notification query by config, channel, event and date range on timeColumn
+func applyNotificationFilter(query *gorm.DB, filter NotificationFilter, timeColumn string) *gorm.DB {
+	if filter.ConfigName != "" {
+		query = query.Where("config_name = ?", filter.ConfigName)
+	}
+	if filter.Channel != "" {
+		query = query.Where("channel = ?", filter.Channel)
+	}
+	if filter.Event != "" {
+		query = query.Where("event = ?", filter.Event)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where(timeColumn+" >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where(timeColumn+" < ?", filter.To)
+	}
+	return query
+}
+
+// paginateNotifications applies the limit and offset of a notification filter
+func paginateNotifications(query *gorm.DB, filter NotificationFilter) *gorm.DB {
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+	return query
+}
+
 // SaveBackupHistory saves backup history record
 func (s *Service) SaveBackupHistory(history *BackupHistory) error {
 	return s.db.Create(history).Error
@@ -91,14 +339,109 @@ func (s *Service) GetBackupHistory(limit, offset int) ([]BackupHistory, error) {
 	return history, err
 }
 
+// GetBackupHistoryByConfig retrieves the history of one backup config, newest first,
+// along with the total number of matching records for pagination
+func (s *Service) GetBackupHistoryByConfig(configID uint, filter HistoryFilter) ([]BackupHistory, int64, error) {
+	return s.findBackupHistory(s.db.Where("backup_config_id = ?", configID), filter)
+}
+
+// GetBackupHistoryByConfigName retrieves the history recorded under a config name, newest first,
+// including runs of configs that have since been deleted
+func (s *Service) GetBackupHistoryByConfigName(name string, filter HistoryFilter) ([]BackupHistory, int64, error) {
+	return s.findBackupHistory(s.db.Where("config_name = ?", name), filter)
+}
+
+// GetLatestBackupHistory retrieves the most recent history record of a backup config
+func (s *Service) GetLatestBackupHistory(configID uint, status string) (*BackupHistory, error) {
+	query := s.db.Where("backup_config_id = ?", configID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var history BackupHistory
+	if err := query.Order("started_at DESC").Order("id DESC").First(&history).Error; err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// GetRecentBackupSizes returns the file sizes of the latest successful backups recorded under a config name, newest first
+func (s *Service) GetRecentBackupSizes(configName string, limit int) ([]int64, error) {
+	var sizes []int64
+	err := s.db.Model(&BackupHistory{}).
+		Where("config_name = ? AND status = ?", configName, BackupStatusSuccess).
+		Order("started_at DESC").Order("id DESC").
+		Limit(limit).
+		Pluck("file_size", &sizes).Error
+	return sizes, err
+}
+
+// SumBackupSizesSince returns the total size of the successful backups of the given configs started since a time
+func (s *Service) SumBackupSizesSince(configNames []string, since time.Time) (int64, error) {
+	if len(configNames) == 0 {
+		return 0, nil
+	}
+
+	var total int64
+	err := s.db.Model(&BackupHistory{}).
+		Where("config_name IN ? AND status = ? AND started_at >= ?", configNames, BackupStatusSuccess, since).
+		Select("COALESCE(SUM(file_size), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// findBackupHistory applies a history filter to a scoped query
+func (s *Service) findBackupHistory(query *gorm.DB, filter HistoryFilter) ([]BackupHistory, int64, error) {
+	query = query.Model(&BackupHistory{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.TriggerSource != "" {
+		query = query.Where("trigger_source = ?", filter.TriggerSource)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("started_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("started_at < ?", filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Order("started_at DESC").Order("id DESC")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	var history []BackupHistory
+	if err := query.Find(&history).Error; err != nil {
+		return nil, 0, err
+	}
+	return history, total, nil
+}
+
 // SaveBackupConfig saves backup configuration
 func (s *Service) SaveBackupConfig(config *BackupConfig) error {
 	// create or update
 	var existing BackupConfig
 	if err := s.db.Where("name = ?", config.Name).First(&existing).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// Create new record
-			return s.db.Create(config).Error
+			// Create new record; the column default would turn a disabled config on
+			enabled := config.Enabled
+			if err := s.db.Create(config).Error; err != nil {
+				return err
+			}
+			if !enabled {
+				config.Enabled = false
+				return s.db.Model(config).Update("enabled", false).Error
+			}
+			return nil
 		}
 		return err
 	}
@@ -116,6 +459,22 @@ func (s *Service) GetBackupConfigs() ([]BackupConfig, error) {
 	return configs, err
 }
 
+// GetAllBackupConfigs retrieves all backup configurations, including disabled ones
+func (s *Service) GetAllBackupConfigs() ([]BackupConfig, error) {
+	var configs []BackupConfig
+	err := s.db.Order("id").Find(&configs).Error
+	return configs, err
+}
+
+// GetBackupConfigByID retrieves backup configuration by ID
+func (s *Service) GetBackupConfigByID(id uint) (*BackupConfig, error) {
+	var config BackupConfig
+	if err := s.db.First(&config, id).Error; err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
 // GetBackupConfigByName retrieves backup configuration by name
 func (s *Service) GetBackupConfigByName(name string) (*BackupConfig, error) {
 	var config BackupConfig
@@ -210,3 +569,49 @@ func (s *Service) DeleteAllBackupConfig() error {
 	}
 	return nil
 }
+
+// ReencryptSecrets rewrites every encrypted field with the current primary master key,
+// encrypting plaintext rows and rows written with a previous key. It returns the number of rows rewritten.
+func (s *Service) ReencryptSecrets() (int, error) {
+	count := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var tokens []TokenConfig
+		if err := tx.Find(&tokens).Error; err != nil {
+			return fmt.Errorf("failed to load token configs: %w", err)
+		}
+		for i := range tokens {
+			if err := tx.Select("client_secret", "access_token", "refresh_token").UpdateColumns(&tokens[i]).Error; err != nil {
+				return fmt.Errorf("failed to re-encrypt token config %d: %w", tokens[i].ID, err)
+			}
+			count++
+		}
+
+		var backupConfigs []BackupConfig
+		if err := tx.Find(&backupConfigs).Error; err != nil {
+			return fmt.Errorf("failed to load backup configs: %w", err)
+		}
+		for i := range backupConfigs {
+			if err := tx.Select("database_url").UpdateColumns(&backupConfigs[i]).Error; err != nil {
+				return fmt.Errorf("failed to re-encrypt backup config '%s': %w", backupConfigs[i].Name, err)
+			}
+			count++
+		}
+
+		var notificationConfigs []NotificationConfig
+		if err := tx.Find(&notificationConfigs).Error; err != nil {
+			return fmt.Errorf("failed to load notification configs: %w", err)
+		}
+		for i := range notificationConfigs {
+			if err := tx.Select("config").UpdateColumns(&notificationConfigs[i]).Error; err != nil {
+				return fmt.Errorf("failed to re-encrypt notification config '%s': %w", notificationConfigs[i].Name, err)
+			}
+			count++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -19,14 +20,18 @@ import (
 type Service struct {
 	cron          *cron.Cron
 	dbService     *database.Service
-	driveService  *gdrive.Service
+	driveServices map[string]*gdrive.Service // keyed by Drive connection
 	notifyManager *notification.Manager
 	tempDir       string
 	mutex         sync.RWMutex
 	jobs          map[string]cron.EntryID
+	jobConnection map[string]string    // Drive connection of each scheduled job
+	paused        map[string]bool      // Drive connections whose jobs are not scheduled
+	storageCheck  *StorageCheckOptions // nil when backups start without checking the Drive quota
+	storageQuota  func(driveService *gdrive.Service) (*gdrive.StorageQuota, error)
 }
 
-// NewService creates a new scheduler service
+// NewService creates a new scheduler service uploading to driveService for the default connection
 func NewService(dbService *database.Service, driveService *gdrive.Service) *Service {
 	// Create temporary directory for backups
 	tempDir := filepath.Join(os.TempDir(), "db-backups")
@@ -40,13 +45,46 @@ func NewService(dbService *database.Service, driveService *gdrive.Service) *Serv
 	return &Service{
 		cron:          cron.New(cron.WithSeconds()),
 		dbService:     dbService,
-		driveService:  driveService,
+		driveServices: map[string]*gdrive.Service{database.DefaultConnection: driveService},
 		notifyManager: notifyManager,
 		tempDir:       tempDir,
 		jobs:          make(map[string]cron.EntryID),
+		jobConnection: make(map[string]string),
+		paused:        make(map[string]bool),
+		storageQuota:  defaultStorageQuota,
 	}
 }
 
+// SetDriveService registers the Drive service backups of a named connection are uploaded with
+func (s *Service) SetDriveService(connection string, driveService *gdrive.Service) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.driveServices[connection] = driveService
+}
+
+// connectionOrDefault maps configs stored without a connection to the default one
+func connectionOrDefault(connection string) string {
+	if connection == "" {
+		return database.DefaultConnection
+	}
+	return connection
+}
+
+// driveServiceFor returns the Drive service of a connection, the default connection when empty
+func (s *Service) driveServiceFor(connection string) (*gdrive.Service, error) {
+	connection = connectionOrDefault(connection)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	driveService, ok := s.driveServices[connection]
+	if !ok {
+		return nil, fmt.Errorf("drive connection '%s' is not configured", connection)
+	}
+	return driveService, nil
+}
+
 // Start starts the scheduler
 func (s *Service) Start() {
 	s.cron.Start()
@@ -76,11 +114,18 @@ func (s *Service) AddBackupJob(config *database.BackupConfig) error {
 	if entryID, exists := s.jobs[config.Name]; exists {
 		s.cron.Remove(entryID)
 		delete(s.jobs, config.Name)
+		delete(s.jobConnection, config.Name)
+	}
+
+	// Jobs of a paused connection are scheduled again by ResumeConnection
+	if connection := connectionOrDefault(config.Connection); s.paused[connection] {
+		log.Printf("Backup job '%s' not scheduled, drive connection '%s' is paused", config.Name, connection)
+		return nil
 	}
 
 	// Add new job
 	entryID, err := s.cron.AddFunc(config.CronSchedule, func() {
-		s.executeBackup(config)
+		s.executeBackup(config, database.TriggerScheduled)
 	})
 
 	if err != nil {
@@ -88,6 +133,7 @@ func (s *Service) AddBackupJob(config *database.BackupConfig) error {
 	}
 
 	s.jobs[config.Name] = entryID
+	s.jobConnection[config.Name] = connectionOrDefault(config.Connection)
 	log.Printf("Added scheduled backup job '%s' with schedule '%s'", config.Name, config.CronSchedule)
 
 	return nil
@@ -101,10 +147,71 @@ func (s *Service) RemoveBackupJob(configName string) {
 	if entryID, exists := s.jobs[configName]; exists {
 		s.cron.Remove(entryID)
 		delete(s.jobs, configName)
+		delete(s.jobConnection, configName)
 		log.Printf("Removed scheduled backup job '%s'", configName)
 	}
 }
 
+// PauseConnection unschedules the jobs of a Drive connection until ResumeConnection and returns their names
+func (s *Service) PauseConnection(connection string) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.paused[connection] = true
+
+	var paused []string
+	for name, jobConnection := range s.jobConnection {
+		if jobConnection != connection {
+			continue
+		}
+		s.cron.Remove(s.jobs[name])
+		delete(s.jobs, name)
+		delete(s.jobConnection, name)
+		paused = append(paused, name)
+	}
+	sort.Strings(paused)
+
+	log.Printf("Paused drive connection '%s' with %d backup jobs", connection, len(paused))
+	return paused
+}
+
+// ResumeConnection schedules the enabled backup configs of a paused Drive connection again
+func (s *Service) ResumeConnection(connection string) error {
+	s.mutex.Lock()
+	wasPaused := s.paused[connection]
+	delete(s.paused, connection)
+	s.mutex.Unlock()
+
+	if !wasPaused {
+		return nil
+	}
+
+	configs, err := s.dbService.GetBackupConfigs()
+	if err != nil {
+		return fmt.Errorf("failed to load backup configurations: %w", err)
+	}
+
+	for _, config := range configs {
+		if connectionOrDefault(config.Connection) != connection {
+			continue
+		}
+		if err := s.AddBackupJob(&config); err != nil {
+			log.Printf("Failed to schedule backup job '%s': %v", config.Name, err)
+		}
+	}
+
+	log.Printf("Resumed drive connection '%s'", connection)
+	return nil
+}
+
+// IsConnectionPaused reports whether the jobs of a Drive connection are paused
+func (s *Service) IsConnectionPaused(connection string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.paused[connection]
+}
+
 // GetScheduledJobs returns information about currently scheduled jobs
 func (s *Service) GetScheduledJobs() []JobInfo {
 	s.mutex.RLock()
@@ -114,24 +221,42 @@ func (s *Service) GetScheduledJobs() []JobInfo {
 	for name, entryID := range s.jobs {
 		entry := s.cron.Entry(entryID)
 		jobs = append(jobs, JobInfo{
-			Name:     name,
-			EntryID:  entryID,
-			Next:     entry.Next,
-			Previous: entry.Prev,
+			Name:       name,
+			EntryID:    entryID,
+			Connection: s.jobConnection[name],
+			Next:       entry.Next,
+			Previous:   entry.Prev,
 		})
 	}
 
 	return jobs
 }
 
+// NextBackupFor returns when the next scheduled backup of a Drive connection runs, zero when none is scheduled
+func (s *Service) NextBackupFor(connection string) time.Time {
+	var next time.Time
+	for _, job := range s.GetScheduledJobs() {
+		if job.Connection != connection || job.Next.IsZero() {
+			continue
+		}
+		if next.IsZero() || job.Next.Before(next) {
+			next = job.Next
+		}
+	}
+	return next
+}
+
 // ExecuteBackupNow executes a backup job immediately
 func (s *Service) ExecuteBackupNow(configName string) error {
 	config, err := s.dbService.GetBackupConfigByName(configName)
 	if err != nil {
 		return fmt.Errorf("failed to get backup config: %w", err)
 	}
+	if connection := connectionOrDefault(config.Connection); s.IsConnectionPaused(connection) {
+		return fmt.Errorf("drive connection '%s' is paused", connection)
+	}
 
-	go s.executeBackup(config)
+	go s.executeBackup(config, database.TriggerManual)
 	return nil
 }
 
@@ -153,14 +278,25 @@ func (s *Service) loadAndScheduleConfigs() {
 }
 
 // executeBackup performs the actual backup operation
-func (s *Service) executeBackup(config *database.BackupConfig) {
-	log.Printf("Starting backup job '%s'", config.Name)
+func (s *Service) executeBackup(config *database.BackupConfig, trigger string) {
+	log.Printf("Starting %s backup job '%s'", trigger, config.Name)
 
 	history := &database.BackupHistory{
-		BackupType: config.DatabaseType,
-		FileName:   "", // Will be set after backup creation
-		Status:     "in_progress",
-		StartedAt:  time.Now(),
+		ConfigName:    config.Name,
+		DatabaseURL:   redactDatabaseURL(config.DatabaseURL),
+		BackupType:    config.DatabaseType,
+		BackupMode:    config.BackupMode,
+		TriggerSource: trigger,
+		FileName:      "", // Will be set after backup creation
+		Status:        database.BackupStatusInProgress,
+		StartedAt:     time.Now(),
+	}
+	if config.ID != 0 {
+		configID := config.ID
+		history.BackupConfigID = &configID
+	}
+	if dbConfig, err := backup.ParseMySQLURL(config.DatabaseURL); err == nil {
+		history.Host = dbConfig.Host
 	}
 
 	if err := s.dbService.SaveBackupHistory(history); err != nil {
@@ -168,16 +304,28 @@ func (s *Service) executeBackup(config *database.BackupConfig) {
 		return
 	}
 
+	// Resolve the Drive connection before spending time on the dump
+	driveService, err := s.driveServiceFor(config.Connection)
+	if err != nil {
+		s.updateBackupHistory(history, database.BackupStatusFailed, "", "", 0, err.Error())
+		return
+	}
+
+	// Skip or warn when the backup is not expected to fit in the Drive storage left
+	if !s.checkStorage(config, history, driveService) {
+		return
+	}
+
 	// Create backup instance
 	backupService, err := backup.NewBackupFromURL(config.DatabaseURL)
 	if err != nil {
-		s.updateBackupHistory(history, "failed", "", "", 0, fmt.Sprintf("Failed to create backup service: %v", err))
+		s.updateBackupHistory(history, database.BackupStatusFailed, "", "", 0, fmt.Sprintf("Failed to create backup service: %v", err))
 		return
 	}
 
 	// Test database connection first
 	if err := backupService.TestConnection(); err != nil {
-		s.updateBackupHistory(history, "failed", "", "", 0, fmt.Sprintf("Database connection failed: %v", err))
+		s.updateBackupHistory(history, database.BackupStatusFailed, "", "", 0, fmt.Sprintf("Database connection failed: %v", err))
 		return
 	}
 
@@ -189,13 +337,13 @@ func (s *Service) executeBackup(config *database.BackupConfig) {
 	case "full":
 		backupPath, err = backupService.BackupSchema(s.tempDir)
 		if err != nil {
-			s.updateBackupHistory(history, "failed", "", "", 0, fmt.Sprintf("Backup failed: %v", err))
+			s.updateBackupHistory(history, database.BackupStatusFailed, "", "", 0, fmt.Sprintf("Backup failed: %v", err))
 			return
 		}
 	case "schema":
 		backupPath, err = backupService.BackupSchemaOnly(s.tempDir)
 		if err != nil {
-			s.updateBackupHistory(history, "failed", "", "", 0, fmt.Sprintf("Backup failed: %v", err))
+			s.updateBackupHistory(history, database.BackupStatusFailed, "", "", 0, fmt.Sprintf("Backup failed: %v", err))
 			return
 		}
 	}
@@ -203,29 +351,20 @@ func (s *Service) executeBackup(config *database.BackupConfig) {
 	// Get file size
 	fileInfo, err := os.Stat(backupPath)
 	if err != nil {
-		s.updateBackupHistory(history, "failed", filepath.Base(backupPath), "", 0, fmt.Sprintf("Failed to get file info: %v", err))
+		s.updateBackupHistory(history, database.BackupStatusFailed, filepath.Base(backupPath), "", 0, fmt.Sprintf("Failed to get file info: %v", err))
 		return
 	}
 
-	// Create or get backup folder in Google Drive
-	folderName := fmt.Sprintf("DB Backups - %s", config.Name)
-	folder, err := s.driveService.GetOrCreateFolder(folderName)
+	// Upload to the backup folder in Google Drive
+	uploadResult, err := driveService.UploadBackup(backupPath, config.Name, history.StartedAt)
 	if err != nil {
-		s.updateBackupHistory(history, "failed", filepath.Base(backupPath), "", fileInfo.Size(), fmt.Sprintf("Failed to create Drive folder: %v", err))
-		s.cleanupTempFile(backupPath)
-		return
-	}
-
-	// Upload to Google Drive
-	uploadResult, err := s.driveService.UploadFile(backupPath, folder.Id)
-	if err != nil {
-		s.updateBackupHistory(history, "failed", filepath.Base(backupPath), "", fileInfo.Size(), fmt.Sprintf("Failed to upload to Drive: %v", err))
+		s.updateBackupHistory(history, database.BackupStatusFailed, filepath.Base(backupPath), "", fileInfo.Size(), fmt.Sprintf("Failed to upload to Drive: %v", err))
 		s.cleanupTempFile(backupPath)
 		return
 	}
 
 	// Update backup history with success
-	s.updateBackupHistory(history, "success", uploadResult.FileName, uploadResult.FileID, uploadResult.Size, "")
+	s.updateBackupHistory(history, database.BackupStatusSuccess, uploadResult.FileName, uploadResult.FileID, uploadResult.Size, "")
 
 	// Send success notification
 	completedAt := time.Now()
@@ -259,7 +398,7 @@ func (s *Service) updateBackupHistory(history *database.BackupHistory, status, f
 	history.CompletedAt = &now
 
 	// Send error notification if backup failed
-	if status == "failed" {
+	if status == database.BackupStatusFailed {
 		s.sendBackupErrorNotification(history, errorMsg)
 	}
 
@@ -284,16 +423,15 @@ func (s *Service) sendBackupErrorNotification(history *database.BackupHistory, e
 	s.notifyManager.SendBackupErrorNotification(notificationData)
 }
 
-// extractConfigNameFromHistory tries to extract config name from backup history
+// extractConfigNameFromHistory returns the name of the config a history record belongs to
 func (s *Service) extractConfigNameFromHistory(history *database.BackupHistory) string {
-	// Try to find a matching backup config
-	configs, err := s.dbService.GetBackupConfigs()
-	if err != nil {
-		return "Unknown Config"
+	if history.ConfigName != "" {
+		return history.ConfigName
 	}
 
-	for _, config := range configs {
-		if config.DatabaseURL == history.DatabaseURL {
+	// Fall back to the linked config for records created before the name was stored
+	if history.BackupConfigID != nil {
+		if config, err := s.dbService.GetBackupConfigByID(*history.BackupConfigID); err == nil {
 			return config.Name
 		}
 	}
@@ -301,6 +439,16 @@ func (s *Service) extractConfigNameFromHistory(history *database.BackupHistory)
 	return "Unknown Config"
 }
 
+// redactDatabaseURL hides the password of a database URL before it is stored in history
+func redactDatabaseURL(databaseURL string) string {
+	dbConfig, err := backup.ParseMySQLURL(databaseURL)
+	if err != nil {
+		return ""
+	}
+	dbConfig.Password = "***"
+	return "mysql://" + dbConfig.GetConnectionString()
+}
+
 // GetNotificationManager returns the notification manager instance
 func (s *Service) GetNotificationManager() *notification.Manager {
 	return s.notifyManager
@@ -0,0 +1,289 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vfa-khuongdv/lazy/internal/database"
+	"github.com/vfa-khuongdv/lazy/pkg/gdrive"
+	"github.com/vfa-khuongdv/lazy/pkg/notification"
+)
+
+// Storage check defaults
+const (
+	DefaultStorageHeadroom        = 1.2
+	DefaultStorageSamples         = 5
+	DefaultCapacityReportInterval = 24 * time.Hour
+	DefaultCapacityForecastDays   = 7
+	DefaultCapacityWarnWithinDays = 14
+)
+
+// StorageCheckOptions tunes the Drive quota check before each backup and the capacity report; zero values use the defaults
+type StorageCheckOptions struct {
+	SkipWhenFull   bool          // skip backups that would not fit instead of only warning
+	Headroom       float64       // the largest recent backup is multiplied by this to project the next one
+	Samples        int           // number of recent successful backups the projection is based on
+	ReportInterval time.Duration // time between capacity reports
+	ForecastDays   int           // days of backup history the daily growth is averaged over
+	WarnWithinDays int           // notify when a connection is forecast to be full within this many days
+}
+
+// withDefaults fills the zero values of the options
+func (o StorageCheckOptions) withDefaults() StorageCheckOptions {
+	if o.Headroom <= 0 {
+		o.Headroom = DefaultStorageHeadroom
+	}
+	if o.Samples <= 0 {
+		o.Samples = DefaultStorageSamples
+	}
+	if o.ReportInterval <= 0 {
+		o.ReportInterval = DefaultCapacityReportInterval
+	}
+	if o.ForecastDays <= 0 {
+		o.ForecastDays = DefaultCapacityForecastDays
+	}
+	if o.WarnWithinDays <= 0 {
+		o.WarnWithinDays = DefaultCapacityWarnWithinDays
+	}
+	return o
+}
+
+// CapacityReport forecasts when the Drive storage of a connection runs out
+type CapacityReport struct {
+	Connection    string    `json:"connection"`
+	Unlimited     bool      `json:"unlimited"`
+	Limit         int64     `json:"limit"`
+	Usage         int64     `json:"usage"`
+	Free          int64     `json:"free"`
+	DailyGrowth   int64     `json:"daily_growth"`    // average bytes uploaded per day by the connection's backups
+	DaysUntilFull int       `json:"days_until_full"` // -1 when unlimited or not growing
+	GeneratedAt   time.Time `json:"generated_at"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// SetStorageCheck enables the Drive quota check before each backup
+func (s *Service) SetStorageCheck(options StorageCheckOptions) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	options = options.withDefaults()
+	s.storageCheck = &options
+}
+
+// projectedBackupSize estimates the size of the next backup of a config, 0 when it has never succeeded
+func (s *Service) projectedBackupSize(configName string, options *StorageCheckOptions) (int64, error) {
+	sizes, err := s.dbService.GetRecentBackupSizes(configName, options.Samples)
+	if err != nil {
+		return 0, err
+	}
+
+	var largest int64
+	for _, size := range sizes {
+		largest = max(largest, size)
+	}
+	return int64(float64(largest) * options.Headroom), nil
+}
+
+// checkStorage compares the projected backup size with the free Drive space before the dump starts.
+// It returns false when the backup was skipped; quota lookup failures never block a backup.
+func (s *Service) checkStorage(config *database.BackupConfig, history *database.BackupHistory, driveService *gdrive.Service) bool {
+	s.mutex.RLock()
+	options := s.storageCheck
+	s.mutex.RUnlock()
+	if options == nil {
+		return true
+	}
+
+	projected, err := s.projectedBackupSize(config.Name, options)
+	if err != nil {
+		log.Printf("Failed to project the size of backup '%s': %v", config.Name, err)
+		return true
+	}
+	if projected == 0 {
+		return true
+	}
+
+	quota, err := s.storageQuota(driveService)
+	if err != nil {
+		log.Printf("Failed to check Drive storage quota for backup '%s': %v", config.Name, err)
+		return true
+	}
+	if quota.Unlimited() || quota.Free() >= projected {
+		return true
+	}
+
+	connection := connectionOrDefault(config.Connection)
+	data := &notification.StorageNotificationData{
+		Connection:    connection,
+		ConfigName:    config.Name,
+		ProjectedSize: projected,
+		FreeBytes:     quota.Free(),
+		Limit:         quota.Limit,
+		Usage:         quota.Usage,
+		Skipped:       options.SkipWhenFull,
+		CheckedAt:     time.Now(),
+	}
+	s.notifyManager.SendStorageNotification(data)
+
+	if !options.SkipWhenFull {
+		log.Printf("Backup '%s' is projected at %d bytes but connection '%s' has %d bytes free, running it anyway", config.Name, projected, connection, quota.Free())
+		return true
+	}
+
+	log.Printf("Skipping backup '%s': projected at %d bytes but connection '%s' has %d bytes free", config.Name, projected, connection, quota.Free())
+	s.updateBackupHistory(history, database.BackupStatusSkipped, "", "", 0,
+		fmt.Sprintf("Not enough Drive storage: projected %d bytes, %d bytes free", projected, quota.Free()))
+	return false
+}
+
+// CapacityReporter periodically forecasts when each Drive connection runs out of storage
+type CapacityReporter struct {
+	service *Service
+	options StorageCheckOptions
+	quota   func(driveService *gdrive.Service) (*gdrive.StorageQuota, error)
+	notify  func(data *notification.CapacityNotificationData)
+
+	mutex   sync.Mutex
+	reports map[string]CapacityReport
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewCapacityReporter creates a reporter for the scheduler's Drive connections, notifying through its notification manager
+func NewCapacityReporter(s *Service, options StorageCheckOptions) *CapacityReporter {
+	return &CapacityReporter{
+		service: s,
+		options: options.withDefaults(),
+		quota:   s.storageQuota,
+		notify: func(data *notification.CapacityNotificationData) {
+			s.GetNotificationManager().SendCapacityNotification(data)
+		},
+		reports: make(map[string]CapacityReport),
+	}
+}
+
+// Start runs a first report immediately and keeps reporting in the background until Stop
+func (r *CapacityReporter) Start() {
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.run()
+	log.Printf("Capacity reporter started, reporting every %s", r.options.ReportInterval)
+}
+
+// Stop stops the background reports and waits for a running report to finish
+func (r *CapacityReporter) Stop() {
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+	r.stop = nil
+}
+
+// GetReports returns the last report of every connection
+func (r *CapacityReporter) GetReports() []CapacityReport {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	reports := make([]CapacityReport, 0, len(r.reports))
+	for _, report := range r.reports {
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Connection < reports[j].Connection })
+	return reports
+}
+
+func (r *CapacityReporter) run() {
+	defer close(r.done)
+
+	r.ReportNow()
+	ticker := time.NewTicker(r.options.ReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.ReportNow()
+		}
+	}
+}
+
+// ReportNow forecasts every connection and notifies about the ones filling up within WarnWithinDays
+func (r *CapacityReporter) ReportNow() {
+	configs, err := r.service.dbService.GetAllBackupConfigs()
+	if err != nil {
+		log.Printf("Failed to get backup configs for the capacity report: %v", err)
+		return
+	}
+
+	configNames := make(map[string][]string)
+	for _, config := range configs {
+		connection := connectionOrDefault(config.Connection)
+		configNames[connection] = append(configNames[connection], config.Name)
+	}
+
+	r.service.mutex.RLock()
+	driveServices := make(map[string]*gdrive.Service, len(r.service.driveServices))
+	for connection, driveService := range r.service.driveServices {
+		driveServices[connection] = driveService
+	}
+	r.service.mutex.RUnlock()
+
+	for connection, driveService := range driveServices {
+		report := r.forecast(connection, driveService, configNames[connection])
+
+		r.mutex.Lock()
+		r.reports[connection] = report
+		r.mutex.Unlock()
+
+		if report.DaysUntilFull >= 0 && report.DaysUntilFull <= r.options.WarnWithinDays {
+			log.Printf("Drive connection '%s' is forecast to be full in %d day(s)", connection, report.DaysUntilFull)
+			r.notify(&notification.CapacityNotificationData{
+				Connection:    connection,
+				Limit:         report.Limit,
+				Usage:         report.Usage,
+				DailyGrowth:   report.DailyGrowth,
+				DaysUntilFull: report.DaysUntilFull,
+				GeneratedAt:   report.GeneratedAt,
+			})
+		}
+	}
+}
+
+// forecast averages the daily growth of a connection's backups over the forecast window
+func (r *CapacityReporter) forecast(connection string, driveService *gdrive.Service, configNames []string) CapacityReport {
+	now := time.Now()
+	report := CapacityReport{Connection: connection, DaysUntilFull: -1, GeneratedAt: now}
+
+	quota, err := r.quota(driveService)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.Limit = quota.Limit
+	report.Usage = quota.Usage
+	report.Unlimited = quota.Unlimited()
+	report.Free = quota.Free()
+
+	uploaded, err := r.service.dbService.SumBackupSizesSince(configNames, now.AddDate(0, 0, -r.options.ForecastDays))
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.DailyGrowth = uploaded / int64(r.options.ForecastDays)
+
+	if !report.Unlimited && report.DailyGrowth > 0 {
+		report.DaysUntilFull = int(report.Free / report.DailyGrowth)
+	}
+	return report
+}
+
+// defaultStorageQuota looks up the quota of a Drive service
+func defaultStorageQuota(driveService *gdrive.Service) (*gdrive.StorageQuota, error) {
+	return driveService.GetStorageQuota()
+}
@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vfa-khuongdv/lazy/internal/database"
+	"github.com/vfa-khuongdv/lazy/pkg/gdrive"
+	"github.com/vfa-khuongdv/lazy/pkg/notification"
+)
+
+// newStorageTestService returns a scheduler on a SQLite store whose Drive quota is *quota
+func newStorageTestService(t *testing.T, quota *gdrive.StorageQuota) (*Service, *database.Service) {
+	dbService, err := database.NewService(&database.ServiceSQLiteConfig{Path: filepath.Join(t.TempDir(), "lazy.db")})
+	assert.NoError(t, err)
+	t.Cleanup(func() { dbService.Close() })
+
+	s := NewService(dbService, nil)
+	s.storageQuota = func(*gdrive.Service) (*gdrive.StorageQuota, error) {
+		if quota == nil {
+			return nil, errors.New("quota unavailable")
+		}
+		return quota, nil
+	}
+	return s, dbService
+}
+
+func saveTestHistory(t *testing.T, dbService *database.Service, configName string, size int64, startedAt time.Time) {
+	assert.NoError(t, dbService.SaveBackupHistory(&database.BackupHistory{
+		ConfigName: configName,
+		BackupType: "mysql",
+		FileName:   "backup.sql",
+		FileSize:   size,
+		Status:     database.BackupStatusSuccess,
+		StartedAt:  startedAt,
+	}))
+}
+
+func TestCheckStorage(t *testing.T) {
+	quota := &gdrive.StorageQuota{Limit: 1000, Usage: 900}
+	s, dbService := newStorageTestService(t, quota)
+	config := &database.BackupConfig{Name: "orders"}
+
+	newHistory := func() *database.BackupHistory {
+		history := &database.BackupHistory{ConfigName: "orders", Status: database.BackupStatusInProgress, StartedAt: time.Now()}
+		assert.NoError(t, dbService.SaveBackupHistory(history))
+		return history
+	}
+
+	// Disabled by default
+	saveTestHistory(t, dbService, "orders", 200, time.Now().Add(-time.Hour))
+	assert.True(t, s.checkStorage(config, newHistory(), nil))
+
+	// Warn only: 200 bytes * 1.2 headroom does not fit in 100 free bytes, but the backup still runs
+	s.SetStorageCheck(StorageCheckOptions{})
+	assert.True(t, s.checkStorage(config, newHistory(), nil))
+
+	s.SetStorageCheck(StorageCheckOptions{SkipWhenFull: true})
+	history := newHistory()
+	assert.False(t, s.checkStorage(config, history, nil))
+	assert.Equal(t, database.BackupStatusSkipped, history.Status)
+	assert.Contains(t, history.ErrorMsg, "projected 240 bytes, 100 bytes free")
+
+	// Enough room, unlimited storage and unknown quotas do not block the backup
+	quota.Usage = 700
+	assert.True(t, s.checkStorage(config, newHistory(), nil))
+	quota.Limit = 0
+	assert.True(t, s.checkStorage(config, newHistory(), nil))
+
+	s.storageQuota = func(*gdrive.Service) (*gdrive.StorageQuota, error) { return nil, errors.New("quota unavailable") }
+	assert.True(t, s.checkStorage(config, newHistory(), nil))
+
+	// Configs that never succeeded have nothing to project from
+	assert.True(t, s.checkStorage(&database.BackupConfig{Name: "new"}, newHistory(), nil))
+}
+
+func TestCapacityReporter(t *testing.T) {
+	quota := &gdrive.StorageQuota{Limit: 10000, Usage: 9000}
+	s, dbService := newStorageTestService(t, quota)
+	assert.NoError(t, dbService.SaveBackupConfig(&database.BackupConfig{
+		Name: "orders", BackupMode: "full", DatabaseURL: "mysql://localhost/orders", DatabaseType: "mysql",
+		CronSchedule: "0 0 2 * * *", Enabled: true,
+	}))
+	saveTestHistory(t, dbService, "orders", 400, time.Now().Add(-24*time.Hour))
+	saveTestHistory(t, dbService, "orders", 300, time.Now().Add(-3*24*time.Hour))
+	saveTestHistory(t, dbService, "orders", 5000, time.Now().Add(-30*24*time.Hour)) // outside the forecast window
+	saveTestHistory(t, dbService, "other", 7000, time.Now())                        // not a config of the connection
+
+	reporter := NewCapacityReporter(s, StorageCheckOptions{ForecastDays: 7, WarnWithinDays: 14})
+	var sent []*notification.CapacityNotificationData
+	reporter.notify = func(data *notification.CapacityNotificationData) { sent = append(sent, data) }
+
+	reporter.ReportNow()
+	reports := reporter.GetReports()
+	assert.Len(t, reports, 1)
+	assert.Equal(t, database.DefaultConnection, reports[0].Connection)
+	assert.Equal(t, int64(1000), reports[0].Free)
+	assert.Equal(t, int64(100), reports[0].DailyGrowth)
+	assert.Equal(t, 10, reports[0].DaysUntilFull)
+	assert.Len(t, sent, 1)
+	assert.Equal(t, 10, sent[0].DaysUntilFull)
+
+	// Plenty of room left is reported without notifying
+	quota.Limit = 100000
+	reporter.ReportNow()
+	assert.Equal(t, 910, reporter.GetReports()[0].DaysUntilFull)
+	assert.Len(t, sent, 1)
+
+	// Unlimited storage never fills up
+	quota.Limit = 0
+	reporter.ReportNow()
+	assert.True(t, reporter.GetReports()[0].Unlimited)
+	assert.Equal(t, -1, reporter.GetReports()[0].DaysUntilFull)
+}
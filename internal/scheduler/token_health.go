@@ -0,0 +1,253 @@
+package scheduler
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vfa-khuongdv/lazy/pkg/notification"
+)
+
+// Token health check defaults
+const (
+	DefaultTokenCheckInterval = 6 * time.Hour
+	DefaultTokenCheckLead     = time.Hour
+	// replanInterval bounds how long the checker sleeps, so newly scheduled backups get their pre-backup check
+	replanInterval = 10 * time.Minute
+)
+
+// TokenCheck validates the authorization of one Drive connection
+type TokenCheck struct {
+	Connection string
+	Validate   func() error
+	// AuthURL returns a fresh authorization URL for the notification; nil for service credentials
+	AuthURL func() string
+}
+
+// TokenHealthOptions tunes the background token checker; zero values use the defaults
+type TokenHealthOptions struct {
+	Interval         time.Duration // time between regular checks
+	Lead             time.Duration // an extra check runs this long before each scheduled backup
+	FailureThreshold int           // consecutive failures before notifying, 1 by default
+}
+
+// TokenHealth is the last known authorization state of a Drive connection
+type TokenHealth struct {
+	Connection          string    `json:"connection"`
+	Healthy             bool      `json:"healthy"`
+	Paused              bool      `json:"paused,omitempty"` // not checked until the connection is resumed
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastCheckedAt       time.Time `json:"last_checked_at"`
+	NextBackupAt        time.Time `json:"next_backup_at,omitempty"`
+}
+
+// tokenState tracks a connection between checks
+type tokenState struct {
+	health      TokenHealth
+	notified    bool      // an alert was sent for the current failure streak
+	notifiedFor time.Time // the upcoming backup the last pre-backup alert was sent for
+}
+
+// TokenHealthChecker validates Drive tokens in the background and warns before a backup would fail at upload time
+type TokenHealthChecker struct {
+	checks     []TokenCheck
+	options    TokenHealthOptions
+	nextBackup func(connection string) time.Time
+	paused     func(connection string) bool
+	notify     func(data *notification.DriveAuthNotificationData)
+	recovered  func(data *notification.DriveAuthNotificationData)
+
+	mutex  sync.Mutex
+	states map[string]*tokenState
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewTokenHealthChecker creates a checker for the given connections, notifying through the scheduler's notification manager
+func NewTokenHealthChecker(s *Service, checks []TokenCheck, options TokenHealthOptions) *TokenHealthChecker {
+	if options.Interval <= 0 {
+		options.Interval = DefaultTokenCheckInterval
+	}
+	if options.Lead <= 0 {
+		options.Lead = DefaultTokenCheckLead
+	}
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = 1
+	}
+
+	states := make(map[string]*tokenState, len(checks))
+	for _, check := range checks {
+		states[check.Connection] = &tokenState{health: TokenHealth{Connection: check.Connection, Healthy: true}}
+	}
+
+	return &TokenHealthChecker{
+		checks:     checks,
+		options:    options,
+		nextBackup: s.NextBackupFor,
+		paused:     s.IsConnectionPaused,
+		notify: func(data *notification.DriveAuthNotificationData) {
+			s.GetNotificationManager().SendDriveAuthNotification(data)
+		},
+		recovered: func(data *notification.DriveAuthNotificationData) {
+			s.GetNotificationManager().SendDriveAuthRecoveredNotification(data)
+		},
+		states: states,
+	}
+}
+
+// Start runs a first check immediately and keeps checking in the background until Stop
+func (c *TokenHealthChecker) Start() {
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	go c.run()
+	log.Printf("Token health checker started, checking every %s and %s before each backup", c.options.Interval, c.options.Lead)
+}
+
+// Stop stops the background checks and waits for a running check to finish
+func (c *TokenHealthChecker) Stop() {
+	if c.stop == nil {
+		return
+	}
+	close(c.stop)
+	<-c.done
+	c.stop = nil
+}
+
+// GetHealth returns the last known state of every connection
+func (c *TokenHealthChecker) GetHealth() []TokenHealth {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	health := make([]TokenHealth, 0, len(c.states))
+	for _, state := range c.states {
+		health = append(health, state.health)
+	}
+	sort.Slice(health, func(i, j int) bool { return health[i].Connection < health[j].Connection })
+	return health
+}
+
+func (c *TokenHealthChecker) run() {
+	defer close(c.done)
+
+	c.CheckNow()
+	due := c.nextCheck(time.Now())
+
+	for {
+		sleep := time.Until(due)
+		if sleep > replanInterval {
+			sleep = replanInterval
+		}
+
+		timer := time.NewTimer(sleep)
+		select {
+		case <-c.stop:
+			timer.Stop()
+			return
+		case now := <-timer.C:
+			if !now.Before(due) {
+				c.CheckNow()
+				due = c.nextCheck(time.Now())
+			} else if next := c.nextCheck(now); next.Before(due) {
+				// A backup was scheduled since the last plan
+				due = next
+			}
+		}
+	}
+}
+
+// nextCheck returns the earlier of the next regular check and the next pre-backup check
+func (c *TokenHealthChecker) nextCheck(now time.Time) time.Time {
+	due := now.Add(c.options.Interval)
+	for _, check := range c.checks {
+		nextBackup := c.nextBackup(check.Connection)
+		if nextBackup.IsZero() {
+			continue
+		}
+		if preBackup := nextBackup.Add(-c.options.Lead); preBackup.After(now) && preBackup.Before(due) {
+			due = preBackup
+		}
+	}
+	return due
+}
+
+// CheckNow validates every connection and sends the notifications that are due
+func (c *TokenHealthChecker) CheckNow() {
+	for _, check := range c.checks {
+		c.checkConnection(check)
+	}
+}
+
+// checkConnection validates one connection. It notifies when the failure streak reaches the threshold,
+// again before each upcoming backup while the connection stays broken, and once when it recovers.
+// Paused connections are skipped, since they are paused because their token was revoked.
+func (c *TokenHealthChecker) checkConnection(check TokenCheck) {
+	if c.paused(check.Connection) {
+		c.mutex.Lock()
+		c.states[check.Connection] = &tokenState{health: TokenHealth{Connection: check.Connection, Healthy: true, Paused: true}}
+		c.mutex.Unlock()
+		return
+	}
+
+	err := check.Validate()
+	now := time.Now()
+	nextBackup := c.nextBackup(check.Connection)
+
+	c.mutex.Lock()
+	state := c.states[check.Connection]
+	state.health.Paused = false
+	state.health.LastCheckedAt = now
+	state.health.NextBackupAt = nextBackup
+
+	if err == nil {
+		failures, notified := state.health.ConsecutiveFailures, state.notified
+		if !state.health.Healthy {
+			log.Printf("Drive authorization of connection '%s' recovered after %d failed checks", check.Connection, failures)
+		}
+		state.health.Healthy = true
+		state.health.ConsecutiveFailures = 0
+		state.health.LastError = ""
+		state.notified = false
+		state.notifiedFor = time.Time{}
+		c.mutex.Unlock()
+
+		// Only a streak that was alerted about needs an all-clear
+		if notified {
+			c.recovered(&notification.DriveAuthNotificationData{Connection: check.Connection, ConsecutiveFailures: failures, CheckedAt: now})
+		}
+		return
+	}
+
+	state.health.Healthy = false
+	state.health.ConsecutiveFailures++
+	state.health.LastError = err.Error()
+	failures := state.health.ConsecutiveFailures
+	log.Printf("Drive authorization check of connection '%s' failed (%d in a row): %v", check.Connection, failures, err)
+
+	backupSoon := !nextBackup.IsZero() && nextBackup.Sub(now) <= c.options.Lead && !nextBackup.Equal(state.notifiedFor)
+	shouldNotify := failures >= c.options.FailureThreshold && (!state.notified || backupSoon)
+	if shouldNotify {
+		state.notified = true
+		if backupSoon {
+			state.notifiedFor = nextBackup
+		}
+	}
+	c.mutex.Unlock()
+
+	if !shouldNotify {
+		return
+	}
+
+	data := &notification.DriveAuthNotificationData{
+		Connection:          check.Connection,
+		ErrorMessage:        err.Error(),
+		ConsecutiveFailures: failures,
+		NextBackupAt:        nextBackup,
+		CheckedAt:           now,
+	}
+	if check.AuthURL != nil {
+		data.AuthURL = check.AuthURL()
+	}
+	c.notify(data)
+}
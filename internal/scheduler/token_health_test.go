@@ -0,0 +1,165 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vfa-khuongdv/lazy/pkg/notification"
+)
+
+// newTestTokenHealthChecker returns a checker whose connection fails while *failing is set
+func newTestTokenHealthChecker(t *testing.T, failing *bool, nextBackup *time.Time, options TokenHealthOptions) (*TokenHealthChecker, *[]*notification.DriveAuthNotificationData) {
+	check := TokenCheck{
+		Connection: "default",
+		Validate: func() error {
+			if *failing {
+				return errors.New("invalid_grant")
+			}
+			return nil
+		},
+		AuthURL: func() string { return "https://accounts.google.com/o/oauth2/auth?state=fresh" },
+	}
+
+	checker := NewTokenHealthChecker(NewService(nil, nil), []TokenCheck{check}, options)
+	checker.nextBackup = func(string) time.Time { return *nextBackup }
+
+	var sent []*notification.DriveAuthNotificationData
+	checker.notify = func(data *notification.DriveAuthNotificationData) { sent = append(sent, data) }
+	checker.recovered = func(*notification.DriveAuthNotificationData) {}
+	return checker, &sent
+}
+
+func TestTokenHealthChecker_NotifiesOncePerStreak(t *testing.T) {
+	failing := true
+	var nextBackup time.Time
+	checker, sent := newTestTokenHealthChecker(t, &failing, &nextBackup, TokenHealthOptions{FailureThreshold: 2})
+
+	checker.CheckNow()
+	assert.Empty(t, *sent, "below the threshold")
+
+	checker.CheckNow()
+	checker.CheckNow()
+	assert.Len(t, *sent, 1)
+	assert.Equal(t, "default", (*sent)[0].Connection)
+	assert.Equal(t, 2, (*sent)[0].ConsecutiveFailures)
+	assert.Contains(t, (*sent)[0].AuthURL, "state=fresh")
+
+	health := checker.GetHealth()
+	assert.False(t, health[0].Healthy)
+	assert.Equal(t, 3, health[0].ConsecutiveFailures)
+	assert.Equal(t, "invalid_grant", health[0].LastError)
+
+	// Recovery resets the streak
+	failing = false
+	checker.CheckNow()
+	health = checker.GetHealth()
+	assert.True(t, health[0].Healthy)
+	assert.Zero(t, health[0].ConsecutiveFailures)
+
+	failing = true
+	checker.CheckNow()
+	checker.CheckNow()
+	assert.Len(t, *sent, 2)
+}
+
+func TestTokenHealthChecker_RemindsBeforeEachBackup(t *testing.T) {
+	failing := true
+	nextBackup := time.Now().Add(30 * time.Minute)
+	checker, sent := newTestTokenHealthChecker(t, &failing, &nextBackup, TokenHealthOptions{Lead: time.Hour})
+
+	checker.CheckNow()
+	checker.CheckNow()
+	assert.Len(t, *sent, 1, "one reminder per upcoming backup")
+	assert.Equal(t, nextBackup, (*sent)[0].NextBackupAt)
+
+	nextBackup = nextBackup.Add(20 * time.Minute)
+	checker.CheckNow()
+	assert.Len(t, *sent, 2)
+}
+
+func TestTokenHealthChecker_NextCheck(t *testing.T) {
+	failing := false
+	var nextBackup time.Time
+	checker, _ := newTestTokenHealthChecker(t, &failing, &nextBackup, TokenHealthOptions{Interval: 6 * time.Hour, Lead: time.Hour})
+	now := time.Now()
+
+	assert.Equal(t, now.Add(6*time.Hour), checker.nextCheck(now), "no backup scheduled")
+
+	nextBackup = now.Add(3 * time.Hour)
+	assert.Equal(t, now.Add(2*time.Hour), checker.nextCheck(now), "check ahead of the backup")
+
+	nextBackup = now.Add(30 * time.Minute)
+	assert.Equal(t, now.Add(6*time.Hour), checker.nextCheck(now), "pre-backup check already passed")
+}
+
+func TestTokenHealthChecker_StartStop(t *testing.T) {
+	failing := true
+	var nextBackup time.Time
+	checker, sent := newTestTokenHealthChecker(t, &failing, &nextBackup, TokenHealthOptions{})
+
+	checker.Start()
+	assert.Eventually(t, func() bool { return len(checker.GetHealth()) == 1 && !checker.GetHealth()[0].Healthy }, time.Second, 10*time.Millisecond)
+	checker.Stop()
+	checker.Stop()
+
+	assert.Len(t, *sent, 1)
+}
+
+func TestTokenHealthChecker_SkipsPausedConnections(t *testing.T) {
+	failing := true
+	var nextBackup time.Time
+	checker, sent := newTestTokenHealthChecker(t, &failing, &nextBackup, TokenHealthOptions{})
+	paused := false
+	checker.paused = func(string) bool { return paused }
+
+	checker.CheckNow()
+	assert.Len(t, *sent, 1)
+
+	// A revoked token pauses the connection; it is neither validated nor reported as failing
+	paused = true
+	checker.CheckNow()
+	checker.CheckNow()
+	assert.Len(t, *sent, 1)
+	health := checker.GetHealth()
+	assert.True(t, health[0].Healthy)
+	assert.True(t, health[0].Paused)
+	assert.Zero(t, health[0].ConsecutiveFailures)
+	assert.Empty(t, health[0].LastError)
+
+	// Once resumed, a failure starts a new streak and is notified again
+	paused = false
+	checker.CheckNow()
+	assert.Len(t, *sent, 2)
+	assert.Equal(t, 1, (*sent)[1].ConsecutiveFailures)
+	assert.False(t, checker.GetHealth()[0].Paused)
+}
+
+func TestTokenHealthChecker_NotifiesRecovery(t *testing.T) {
+	failing := true
+	var nextBackup time.Time
+	checker, sent := newTestTokenHealthChecker(t, &failing, &nextBackup, TokenHealthOptions{FailureThreshold: 2})
+	var recovered []*notification.DriveAuthNotificationData
+	checker.recovered = func(data *notification.DriveAuthNotificationData) { recovered = append(recovered, data) }
+
+	// A streak below the threshold was never alerted, so there is nothing to clear
+	checker.CheckNow()
+	failing = false
+	checker.CheckNow()
+	assert.Empty(t, *sent)
+	assert.Empty(t, recovered)
+
+	failing = true
+	checker.CheckNow()
+	checker.CheckNow()
+	checker.CheckNow()
+	assert.Len(t, *sent, 1)
+
+	failing = false
+	checker.CheckNow()
+	checker.CheckNow()
+	assert.Len(t, recovered, 1)
+	assert.Equal(t, "default", recovered[0].Connection)
+	assert.Equal(t, 3, recovered[0].ConsecutiveFailures)
+}
@@ -8,8 +8,9 @@ import (
 
 // JobInfo contains information about a scheduled job
 type JobInfo struct {
-	Name     string       `json:"name"`
-	EntryID  cron.EntryID `json:"entry_id"`
-	Next     time.Time    `json:"next"`
-	Previous time.Time    `json:"previous"`
+	Name       string       `json:"name"`
+	EntryID    cron.EntryID `json:"entry_id"`
+	Connection string       `json:"connection"`
+	Next       time.Time    `json:"next"`
+	Previous   time.Time    `json:"previous"`
 }
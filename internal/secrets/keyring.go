@@ -0,0 +1,248 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Environment variables holding the master keys
+const (
+	EnvMasterKey     = "LAZY_MASTER_KEY"      // comma-separated keys, the first one encrypts
+	EnvMasterKeyFile = "LAZY_MASTER_KEY_FILE" // file with one key per line, the first one encrypts
+)
+
+// encryptedPrefix marks values produced by Keyring.Encrypt
+const encryptedPrefix = "enc:v1:"
+
+// keySize is the size of master and data keys (AES-256)
+const keySize = 32
+
+// Keyring holds the master keys used for envelope encryption.
+// Each value is encrypted with a fresh data key, which is itself encrypted with the primary master key.
+// The other keys are only used to decrypt values written before a rotation.
+type Keyring struct {
+	primaryID string
+	keys      map[string][]byte
+}
+
+// NewKeyring creates a keyring; primaryID must be one of the given keys
+func NewKeyring(primaryID string, keys map[string][]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("at least one master key is required")
+	}
+	if _, ok := keys[primaryID]; !ok {
+		return nil, fmt.Errorf("primary key '%s' not found", primaryID)
+	}
+
+	for id, key := range keys {
+		if id == "" || strings.ContainsAny(id, ":, \t\r\n") {
+			return nil, fmt.Errorf("invalid key id '%s'", id)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("master key '%s' must be %d bytes, got %d", id, keySize, len(key))
+		}
+	}
+
+	return &Keyring{primaryID: primaryID, keys: keys}, nil
+}
+
+// ParseKeyring parses a list of keys separated by commas or newlines.
+// Each key is either "id:base64" or a bare base64 key whose id is derived from its hash.
+// The first key is the primary key used for encryption.
+func ParseKeyring(spec string) (*Keyring, error) {
+	fields := strings.FieldsFunc(spec, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	})
+
+	keys := make(map[string][]byte)
+	primaryID := ""
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" || strings.HasPrefix(field, "#") {
+			continue
+		}
+
+		id, encoded, found := strings.Cut(field, ":")
+		if !found {
+			id, encoded = "", field
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 master key: %w", err)
+		}
+		if id == "" {
+			id = KeyID(key)
+		}
+		if _, exists := keys[id]; exists {
+			return nil, fmt.Errorf("duplicate key id '%s'", id)
+		}
+
+		keys[id] = key
+		if primaryID == "" {
+			primaryID = id
+		}
+	}
+
+	return NewKeyring(primaryID, keys)
+}
+
+// LoadKeyring loads the master keys from a file, falling back to the LAZY_MASTER_KEY_FILE
+// and LAZY_MASTER_KEY environment variables. It returns nil when no key is configured.
+func LoadKeyring(file string) (*Keyring, error) {
+	if file == "" {
+		file = os.Getenv(EnvMasterKeyFile)
+	}
+
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read master key file: %w", err)
+		}
+		return ParseKeyring(string(data))
+	}
+
+	if spec := os.Getenv(EnvMasterKey); spec != "" {
+		return ParseKeyring(spec)
+	}
+
+	return nil, nil
+}
+
+// GenerateKey returns a new random master key encoded in base64
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// KeyID derives a short stable identifier from a key
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// PrimaryKeyID returns the id of the key used for encryption
+func (k *Keyring) PrimaryKeyID() string {
+	return k.primaryID
+}
+
+// IsEncrypted reports whether a stored value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// EncryptedKeyID returns the id of the master key that encrypted a value
+func EncryptedKeyID(value string) string {
+	if !IsEncrypted(value) {
+		return ""
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(value, encryptedPrefix), ":")
+	return id
+}
+
+// Encrypt encrypts plaintext with a fresh data key wrapped by the primary master key.
+// The result has the form "enc:v1:<key id>:<wrapped data key>:<ciphertext>".
+func (k *Keyring) Encrypt(plaintext []byte) (string, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	wrappedKey, err := seal(k.keys[k.primaryID], dataKey, []byte(k.primaryID))
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	ciphertext, err := seal(dataKey, plaintext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt value: %w", err)
+	}
+
+	return encryptedPrefix + k.primaryID + ":" +
+		base64.StdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt decrypts a value produced by Encrypt with any key of the keyring
+func (k *Keyring) Decrypt(value string) ([]byte, error) {
+	if !IsEncrypted(value) {
+		return nil, fmt.Errorf("value is not encrypted")
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, encryptedPrefix), ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed encrypted value")
+	}
+
+	masterKey, ok := k.keys[parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("master key '%s' is not configured", parts[0])
+	}
+
+	wrappedKey, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed data key: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed ciphertext: %w", err)
+	}
+
+	dataKey, err := open(masterKey, wrappedKey, []byte(parts[0]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	plaintext, err := open(dataKey, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return plaintext, nil
+}
+
+// seal encrypts with AES-256-GCM and prepends the nonce
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts a value produced by seal
+func open(key, sealed, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// newGCM creates an AES-GCM cipher for a key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
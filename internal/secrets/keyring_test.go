@@ -0,0 +1,145 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, keySize)
+}
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	k, err := NewKeyring("k1", map[string][]byte{"k1": testKey(1)})
+	assert.NoError(t, err)
+
+	encrypted, err := k.Encrypt([]byte("refresh-token"))
+	assert.NoError(t, err)
+	assert.True(t, IsEncrypted(encrypted))
+	assert.Equal(t, "k1", EncryptedKeyID(encrypted))
+	assert.NotContains(t, encrypted, "refresh-token")
+
+	// Every value gets its own data key and nonce
+	again, err := k.Encrypt([]byte("refresh-token"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, encrypted, again)
+
+	plaintext, err := k.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "refresh-token", string(plaintext))
+}
+
+func TestKeyring_DecryptWithPreviousKey(t *testing.T) {
+	old, err := NewKeyring("old", map[string][]byte{"old": testKey(1)})
+	assert.NoError(t, err)
+	encrypted, err := old.Encrypt([]byte("secret"))
+	assert.NoError(t, err)
+
+	rotated, err := NewKeyring("new", map[string][]byte{"new": testKey(2), "old": testKey(1)})
+	assert.NoError(t, err)
+	plaintext, err := rotated.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+
+	reencrypted, err := rotated.Encrypt(plaintext)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", EncryptedKeyID(reencrypted))
+
+	// Without the old key the value cannot be read
+	newOnly, err := NewKeyring("new", map[string][]byte{"new": testKey(2)})
+	assert.NoError(t, err)
+	_, err = newOnly.Decrypt(encrypted)
+	assert.ErrorContains(t, err, "master key 'old' is not configured")
+}
+
+func TestKeyring_DecryptTampered(t *testing.T) {
+	k, err := NewKeyring("k1", map[string][]byte{"k1": testKey(1)})
+	assert.NoError(t, err)
+	encrypted, err := k.Encrypt([]byte("secret"))
+	assert.NoError(t, err)
+
+	parts := strings.Split(encrypted, ":")
+	ciphertext, _ := base64.StdEncoding.DecodeString(parts[len(parts)-1])
+	ciphertext[len(ciphertext)-1] ^= 0xff
+	parts[len(parts)-1] = base64.StdEncoding.EncodeToString(ciphertext)
+
+	_, err = k.Decrypt(strings.Join(parts, ":"))
+	assert.ErrorContains(t, err, "failed to decrypt value")
+
+	_, err = k.Decrypt("plaintext")
+	assert.Error(t, err)
+	_, err = k.Decrypt("enc:v1:k1:broken")
+	assert.ErrorContains(t, err, "malformed encrypted value")
+}
+
+func TestNewKeyring_Invalid(t *testing.T) {
+	_, err := NewKeyring("k1", nil)
+	assert.Error(t, err)
+
+	_, err = NewKeyring("missing", map[string][]byte{"k1": testKey(1)})
+	assert.ErrorContains(t, err, "primary key 'missing' not found")
+
+	_, err = NewKeyring("k1", map[string][]byte{"k1": []byte("short")})
+	assert.ErrorContains(t, err, "must be 32 bytes")
+
+	_, err = NewKeyring("a:b", map[string][]byte{"a:b": testKey(1)})
+	assert.ErrorContains(t, err, "invalid key id")
+}
+
+func TestParseKeyring(t *testing.T) {
+	primary := base64.StdEncoding.EncodeToString(testKey(2))
+	previous := base64.StdEncoding.EncodeToString(testKey(1))
+
+	k, err := ParseKeyring("2025:" + primary + ", " + previous)
+	assert.NoError(t, err)
+	assert.Equal(t, "2025", k.PrimaryKeyID())
+	assert.Len(t, k.keys, 2)
+	assert.Contains(t, k.keys, KeyID(testKey(1)))
+
+	_, err = ParseKeyring("not base64!")
+	assert.ErrorContains(t, err, "invalid base64")
+
+	_, err = ParseKeyring("a:" + primary + ",a:" + previous)
+	assert.ErrorContains(t, err, "duplicate key id")
+}
+
+func TestLoadKeyring(t *testing.T) {
+	t.Setenv(EnvMasterKey, "")
+	t.Setenv(EnvMasterKeyFile, "")
+
+	k, err := LoadKeyring("")
+	assert.NoError(t, err)
+	assert.Nil(t, k, "no key configured")
+
+	t.Setenv(EnvMasterKey, "env:"+base64.StdEncoding.EncodeToString(testKey(1)))
+	k, err = LoadKeyring("")
+	assert.NoError(t, err)
+	assert.Equal(t, "env", k.PrimaryKeyID())
+
+	// A key file takes precedence over the environment
+	file := filepath.Join(t.TempDir(), "master.keys")
+	content := "# rotated 2025-01-01\nfile:" + base64.StdEncoding.EncodeToString(testKey(2)) + "\n"
+	assert.NoError(t, os.WriteFile(file, []byte(content), 0600))
+
+	k, err = LoadKeyring(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "file", k.PrimaryKeyID())
+
+	_, err = LoadKeyring(filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorContains(t, err, "failed to read master key file")
+}
+
+func TestGenerateKey(t *testing.T) {
+	encoded, err := GenerateKey()
+	assert.NoError(t, err)
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	assert.NoError(t, err)
+	assert.Len(t, key, keySize)
+}
@@ -1,40 +1,113 @@
 package lazy
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"time"
 
 	"github.com/vfa-khuongdv/lazy/internal/auth"
 	"github.com/vfa-khuongdv/lazy/internal/database"
 	"github.com/vfa-khuongdv/lazy/internal/scheduler"
+	"github.com/vfa-khuongdv/lazy/internal/secrets"
 	"github.com/vfa-khuongdv/lazy/pkg/backup"
 	"github.com/vfa-khuongdv/lazy/pkg/gdrive"
 	"github.com/vfa-khuongdv/lazy/pkg/notification"
 	"golang.org/x/oauth2"
+	"gorm.io/gorm"
 )
 
 type LazyManager struct {
 	dbService        *database.Service
-	authService      *auth.Service
-	driveService     *gdrive.Service
+	connections      map[string]*driveConnection
 	schedulerService *scheduler.Service
+	tokenChecker     *scheduler.TokenHealthChecker
+	capacityReporter *scheduler.CapacityReporter
+	outbox           *notification.Outbox
 	config           *Config
 }
 
 type Config struct {
 	// Google OAuth2 credentials
 	OAuthConfig *oauth2.Config
+	// Service account or Application Default Credentials used for Google Drive instead of OAuthConfig
+	DriveCredentials *DriveCredentials
+	// Shared drive and root folder of the default connection (optional, backups go to the root of My Drive by default)
+	DriveFolder DriveFolderOptions
+	// Additional named Google Drive accounts; OAuthConfig or DriveCredentials above form the "default" connection (optional)
+	DriveConnections []DriveConnection
 	// MySQL database configuration for storing package metadata
 	DatabaseConfig *backup.MySQLConfig
+	// Metadata store used instead of DatabaseConfig, e.g. a SQLite file or PostgreSQL (optional)
+	MetadataStore MetadataStore
 	// Notification config for send notifications
 	NotificationConfig []notification.NotificationConfig
 	// Scheduler configured to control backup intervals
 	SchedulerConfig []backup.SchedulerConfig
 	// Backup temporary directory (optional, uses system temp by default)
 	TempDir string
+	// SyncOptions controls how the configs above are reconciled with stored ones (optional)
+	SyncOptions SyncOptions
+	// File holding the master keys that encrypt stored secrets (optional, falls back to
+	// LAZY_MASTER_KEY_FILE / LAZY_MASTER_KEY; secrets are stored in plaintext when none is set)
+	MasterKeyFile string
+	// TokenHealthCheck validates Drive tokens in the background and notifies before a backup would fail (optional)
+	TokenHealthCheck *TokenHealthOptions
+	// StorageCheck checks the Drive storage quota before each backup and reports when connections will be full (optional)
+	StorageCheck *StorageCheckOptions
+	// NotificationDelivery queues notifications in the metadata database and retries failed sends in the background;
+	// notifications are sent once, synchronously, when nil (optional)
+	NotificationDelivery *NotificationDeliveryOptions
+	// NotificationSuppression limits repeated failure alerts and rate limits every channel;
+	// every failure is notified when nil (optional)
+	NotificationSuppression *NotificationSuppressionOptions
+	// DisableAutoMigrate refuses to start with pending schema migrations instead of applying them;
+	// apply them with cmd/lazy-migrate (optional)
+	DisableAutoMigrate bool
 }
 
+// MetadataStore is the database holding Lazy's own tokens, configs and history
+type MetadataStore = database.ServiceConfig
+
+// HistoryFilter narrows backup history queries by status, trigger source and date range
+type HistoryFilter = database.HistoryFilter
+
+// TokenHealthOptions tunes the background token checker: check interval, lead time before backups and failure threshold
+type TokenHealthOptions = scheduler.TokenHealthOptions
+
+// TokenHealth is the last known authorization state of a Drive connection
+type TokenHealth = scheduler.TokenHealth
+
+// StorageCheckOptions tunes the Drive quota check before backups and the periodic capacity report
+type StorageCheckOptions = scheduler.StorageCheckOptions
+
+// CapacityReport forecasts when the Drive storage of a connection runs out
+type CapacityReport = scheduler.CapacityReport
+
+// NotificationDeliveryOptions tunes the notification outbox: attempts before dead-lettering, backoff and polling
+type NotificationDeliveryOptions = notification.OutboxOptions
+
+// NotificationSuppressionOptions sets the default alert suppression rules and the per-channel rate limit
+type NotificationSuppressionOptions = notification.SuppressionOptions
+
+// NotificationFilter narrows notification delivery and result queries by config, channel, event, status and date range
+type NotificationFilter = database.NotificationFilter
+
+// NotificationDelivery is a notification queued in the outbox, with its delivery status
+type NotificationDelivery = database.NotificationDelivery
+
+// NotificationAttempt records the result of one attempt to send a notification
+type NotificationAttempt = database.NotificationAttempt
+
+// AuditEntry records an administrative action such as revoking a Drive authorization
+type AuditEntry = database.AuditEntry
+
+// AuthCallbackServer serves the OAuth redirect URL until an authorization completes
+type AuthCallbackServer = auth.CallbackServer
+
 type BackupResult struct {
 	FileID       string               `json:"file_id"`
 	FileName     string               `json:"file_name"`
@@ -55,7 +128,37 @@ func NewMySQLConfig(host, port, user, password, database string) *backup.MySQLCo
 	}
 }
 
-// NewMySQLConfig creates a new Scheduler configuration
+// NewSQLiteMetadataStore creates a metadata store backed by a SQLite file
+func NewSQLiteMetadataStore(path string) MetadataStore {
+	return &database.ServiceSQLiteConfig{
+		Path: path,
+	}
+}
+
+// NewPostgresMetadataStore creates a metadata store backed by a PostgreSQL database
+func NewPostgresMetadataStore(host, port, user, password, databaseName, sslMode string) MetadataStore {
+	return &database.ServicePostgresConfig{
+		Host:     host,
+		Port:     port,
+		User:     user,
+		Password: password,
+		Database: databaseName,
+		SSLMode:  sslMode,
+	}
+}
+
+// NewMySQLMetadataStore creates a metadata store backed by a MySQL database
+func NewMySQLMetadataStore(config *backup.MySQLConfig) MetadataStore {
+	return &database.ServiceMySQLConfig{
+		Host:     config.Host,
+		Port:     config.Port,
+		User:     config.User,
+		Password: config.Password,
+		Database: config.Database,
+	}
+}
+
+// NewSchedulerConfig creates a new Scheduler configuration
 func NewSchedulerConfig(name string, backupMode string, databaseConfig *backup.MySQLConfig, cronExpression string) *backup.SchedulerConfig {
 	return &backup.SchedulerConfig{
 		Name:           name,
@@ -71,68 +174,150 @@ func NewBackupManager(config *Config) (*LazyManager, error) {
 		return nil, fmt.Errorf("configuration is required")
 	}
 
-	if config.DatabaseConfig == nil || config.OAuthConfig == nil {
-		return nil, fmt.Errorf("MySQL configuration database and OAuth configuration are required")
+	if (config.DatabaseConfig == nil && config.MetadataStore == nil) || (config.OAuthConfig == nil && config.DriveCredentials == nil) {
+		return nil, fmt.Errorf("metadata store (DatabaseConfig or MetadataStore) and OAuth configuration (OAuthConfig or DriveCredentials) are required")
 	}
 
-	if config.OAuthConfig.ClientID == "" || config.OAuthConfig.ClientSecret == "" || config.OAuthConfig.RedirectURL == "" {
+	if config.OAuthConfig != nil && config.DriveCredentials != nil {
+		return nil, fmt.Errorf("OAuthConfig and DriveCredentials are mutually exclusive")
+	}
+
+	if config.OAuthConfig != nil && (config.OAuthConfig.ClientID == "" || config.OAuthConfig.ClientSecret == "" || config.OAuthConfig.RedirectURL == "") {
 		return nil, fmt.Errorf("OAuth configuration must include ClientID, ClientSecret, and RedirectURL")
 	}
 
-	if config.DatabaseConfig.Host == "" || config.DatabaseConfig.Port == "" || config.DatabaseConfig.User == "" || config.DatabaseConfig.Database == "" {
-		return nil, fmt.Errorf("MySQL configuration must include Host, Port, User, and Database")
+	store := config.MetadataStore
+	if store == nil {
+		if config.DatabaseConfig.Host == "" || config.DatabaseConfig.Port == "" || config.DatabaseConfig.User == "" || config.DatabaseConfig.Database == "" {
+			return nil, fmt.Errorf("MySQL configuration must include Host, Port, User, and Database")
+		}
+		store = NewMySQLMetadataStore(config.DatabaseConfig)
 	}
 
-	// Convert to database service config
-	serviceConfig := &database.ServiceMySQLConfig{
-		Host:     config.DatabaseConfig.Host,
-		Port:     config.DatabaseConfig.Port,
-		User:     config.DatabaseConfig.User,
-		Password: config.DatabaseConfig.Password,
-		Database: config.DatabaseConfig.Database,
+	// Load master keys before the first read so stored secrets can be decrypted
+	keyring, err := secrets.LoadKeyring(config.MasterKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load master keys: %w", err)
 	}
+	if keyring == nil {
+		log.Println("No master key configured, secrets are stored in plaintext")
+	}
+	database.SetKeyring(keyring)
 
-	dbService, err := database.NewService(serviceConfig)
+	dbService, err := openDatabaseService(store, config.DisableAutoMigrate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database service: %w", err)
 	}
 
-	// Initialize auth service
-	authService := auth.NewService(config.OAuthConfig.ClientID, config.OAuthConfig.ClientSecret, config.OAuthConfig.RedirectURL, dbService)
-
-	// Initialize Google Drive service
-	driveService := gdrive.NewService(authService)
+	// Initialize auth and Google Drive services of every connection
+	connections, err := newDriveConnections(config, dbService)
+	if err != nil {
+		dbService.Close()
+		return nil, err
+	}
 
 	// Initialize scheduler service
-	schedulerService := scheduler.NewService(dbService, driveService)
+	schedulerService := scheduler.NewService(dbService, connections[DefaultDriveConnection].driveService)
+	for name, connection := range connections {
+		schedulerService.SetDriveService(name, connection.driveService)
+	}
 
 	manager := &LazyManager{
 		dbService:        dbService,
-		authService:      authService,
-		driveService:     driveService,
+		connections:      connections,
 		schedulerService: schedulerService,
 		config:           config,
 	}
+	manager.watchAuthorizations()
 
 	return manager, nil
 }
 
+// openDatabaseService connects to the metadata store and applies, or checks for, pending migrations
+func openDatabaseService(store MetadataStore, disableAutoMigrate bool) (*database.Service, error) {
+	if !disableAutoMigrate {
+		return database.NewService(store)
+	}
+
+	dbService, err := database.OpenService(store)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := dbService.PendingMigrations()
+	if err != nil {
+		dbService.Close()
+		return nil, err
+	}
+	if len(pending) > 0 {
+		dbService.Close()
+		return nil, fmt.Errorf("%d pending schema migration(s), latest is %d (%s)",
+			len(pending), pending[len(pending)-1].Version, pending[len(pending)-1].Name)
+	}
+
+	return dbService, nil
+}
+
+// GetMigrationStatus lists the metadata store schema migrations and when they were applied
+func (lm *LazyManager) GetMigrationStatus() ([]database.MigrationStatus, error) {
+	return lm.dbService.GetMigrationStatus()
+}
+
 // Initialize performs initial setup and starts the scheduler
 func (lm *LazyManager) Initialize() error {
 	log.Println("Initializing backup manager...")
 
+	if err := lm.checkDriveRoots(); err != nil {
+		return err
+	}
+
 	// Sync notification configs
 	if err := lm.SyncNotifications(); err != nil {
-		return fmt.Errorf("failed to sync notification configs: %w", err)
+		if !tolerateSyncError(err, lm.config.SyncOptions) {
+			return fmt.Errorf("failed to sync notification configs: %w", err)
+		}
+		log.Printf("Notification configs synced with errors: %v", err)
 	}
 	// Sync scheduler configs
 	if err := lm.SyncSchedulerConfig(); err != nil {
-		return fmt.Errorf("failed to scheduler configs: %w", err)
+		if !tolerateSyncError(err, lm.config.SyncOptions) {
+			return fmt.Errorf("failed to sync scheduler configs: %w", err)
+		}
+		log.Printf("Scheduler configs synced with errors: %v", err)
+	}
+
+	if lm.config.NotificationSuppression != nil {
+		if err := notification.ValidateSuppressionRules(lm.config.NotificationSuppression.Defaults); err != nil {
+			return fmt.Errorf("invalid notification suppression: %w", err)
+		}
+		lm.schedulerService.GetNotificationManager().SetSuppression(*lm.config.NotificationSuppression)
+	}
+	// Pick up failure streaks from before the restart, so their recoveries are still sent
+	if err := lm.schedulerService.GetNotificationManager().RestoreFailureStreaks(); err != nil {
+		log.Printf("Failed to restore backup failure streaks: %v", err)
+	}
+
+	// Queue notifications before the scheduler can send any
+	if lm.config.NotificationDelivery != nil {
+		lm.outbox = notification.NewOutbox(lm.dbService, *lm.config.NotificationDelivery)
+		lm.schedulerService.GetNotificationManager().SetOutbox(lm.outbox)
+		lm.outbox.Start()
 	}
 
 	// Start the scheduler
 	lm.schedulerService.Start()
 
+	if lm.config.TokenHealthCheck != nil {
+		lm.tokenChecker = scheduler.NewTokenHealthChecker(lm.schedulerService, lm.tokenChecks(), *lm.config.TokenHealthCheck)
+		lm.tokenChecker.Start()
+	}
+
+	if lm.config.StorageCheck != nil {
+		lm.schedulerService.SetStorageCheck(*lm.config.StorageCheck)
+		lm.capacityReporter = scheduler.NewCapacityReporter(lm.schedulerService, *lm.config.StorageCheck)
+		lm.capacityReporter.Start()
+	}
+
 	log.Println("Backup manager initialized successfully")
 	return nil
 }
@@ -141,9 +326,21 @@ func (lm *LazyManager) Initialize() error {
 func (lm *LazyManager) Close() error {
 	log.Println("Shutting down backup manager...")
 
+	if lm.tokenChecker != nil {
+		lm.tokenChecker.Stop()
+	}
+	if lm.capacityReporter != nil {
+		lm.capacityReporter.Stop()
+	}
+
 	// Stop scheduler
 	lm.schedulerService.Stop()
 
+	// Queued notifications stay in the outbox and are sent after the next start
+	if lm.outbox != nil {
+		lm.outbox.Stop()
+	}
+
 	// Close database connection
 	if err := lm.dbService.Close(); err != nil {
 		return fmt.Errorf("failed to close database service: %w", err)
@@ -153,61 +350,89 @@ func (lm *LazyManager) Close() error {
 	return nil
 }
 
+// RotateEncryptionKeys re-encrypts every stored secret with the primary master key.
+// Run it after adding a new primary key while keeping the previous one configured;
+// once it succeeds the previous key can be removed.
+func (lm *LazyManager) RotateEncryptionKeys() (int, error) {
+	if database.GetKeyring() == nil {
+		return 0, fmt.Errorf("no master key configured")
+	}
+
+	count, err := lm.dbService.ReencryptSecrets()
+	if err != nil {
+		return 0, fmt.Errorf("failed to re-encrypt secrets: %w", err)
+	}
+
+	log.Printf("Re-encrypted %d rows with master key '%s'", count, database.GetKeyring().PrimaryKeyID())
+	return count, nil
+}
+
 // Auth Methods
 
-// GetAuthURL returns the OAuth2 authorization URL
+// GetAuthURL returns the OAuth2 authorization URL of the default connection,
+// or an empty string when DriveCredentials are used
 func (lm *LazyManager) GetAuthURL() string {
-	return lm.authService.GetAuthURL()
+	return lm.defaultConnectionAuth().GetAuthURL()
 }
 
-// SetAuthCode exchanges the authorization code for tokens
+// SetAuthCode exchanges the authorization code for tokens of the default connection
 func (lm *LazyManager) SetAuthCode(authCode string) error {
-	return lm.authService.ExchangeToken(authCode)
+	return lm.defaultConnectionAuth().SetAuthCode(authCode)
+}
+
+// StartAuthCallbackServer serves the OAuth redirect URL so the authorization code is exchanged without copying it by hand.
+// An empty addr listens on the host and port of the redirect URL; the server stops after a successful authorization.
+func (lm *LazyManager) StartAuthCallbackServer(addr string) (*AuthCallbackServer, error) {
+	return lm.defaultConnectionAuth().StartAuthCallbackServer(addr)
+}
+
+// AuthCallbackHandler returns a handler for the OAuth redirect URL to mount on an existing HTTP server
+func (lm *LazyManager) AuthCallbackHandler(done func(error)) http.Handler {
+	return lm.defaultConnectionAuth().AuthCallbackHandler(done)
 }
 
-// GetTokenInfo returns information about the current token
+// StartDeviceAuth starts a device authorization for terminals without a browser, e.g. over SSH.
+// The operator visits the verification URL from any device and enters the user code; Wait saves the token.
+func (lm *LazyManager) StartDeviceAuth(ctx context.Context) (*DeviceAuth, error) {
+	return lm.defaultConnectionAuth().StartDeviceAuth(ctx)
+}
+
+// GetTokenInfo returns information about the current token of the default connection
 func (lm *LazyManager) GetTokenInfo() (*auth.TokenInfo, error) {
-	return lm.authService.GetTokenInfo()
+	return lm.defaultConnectionAuth().GetTokenInfo()
 }
 
-// ValidateToken validates the current token by making a test API call
+// ValidateToken validates the current token of the default connection by making a test API call
 func (lm *LazyManager) ValidateToken() error {
-	return lm.authService.ValidateToken()
+	return lm.defaultConnectionAuth().ValidateToken()
+}
+
+// RevokeAuth revokes the Google authorization of the default connection, deletes the stored token
+// and pauses its backup jobs until the connection is authorized again
+func (lm *LazyManager) RevokeAuth(ctx context.Context) error {
+	return lm.defaultConnectionAuth().RevokeAuth(ctx)
+}
+
+// GetAuditLog returns audit entries newest first, only those of target when it is not empty
+func (lm *LazyManager) GetAuditLog(target string, limit int) ([]AuditEntry, error) {
+	return lm.dbService.GetAuditEntries(target, limit)
 }
 
 // Backup Configuration Methods
 
 // AddBackupMySQLConfig adds a new backup configuration using DatabaseConfig interface
 func (lm *LazyManager) AddBackupMySQLConfig(name string, mode string, dbConfig *backup.MySQLConfig, expression string) error {
-	// Validate cron expression
-	if err := scheduler.ValidateCronExpression(expression); err != nil {
-		return fmt.Errorf("invalid cron expression: %w", err)
-	}
-
-	// Validate database configuration
-	if err := dbConfig.Validate(); err != nil {
-		return fmt.Errorf("invalid database configuration: %w", err)
-	}
-
-	// Test database connection
-	backupService, err := backup.NewMySQLBackupWithConfig(dbConfig)
+	config, err := buildBackupConfig(name, mode, dbConfig, expression)
 	if err != nil {
-		return fmt.Errorf("failed to create backup service: %w", err)
+		return err
 	}
 
-	if err := backupService.TestConnection(); err != nil {
-		return fmt.Errorf("database connection test failed: %w", err)
-	}
-
-	config := &database.BackupConfig{
-		Name:         name,
-		BackupMode:   mode,
-		DatabaseURL:  "mysql://" + dbConfig.GetConnectionString(),
-		DatabaseType: "mysql",
-		CronSchedule: expression,
-		Enabled:      true,
+	// Test database connection
+	if err := testBackupConnection(dbConfig); err != nil {
+		return err
 	}
 
+	config.Source = database.ConfigSourceRuntime
 	if err := lm.dbService.SaveBackupConfig(config); err != nil {
 		return fmt.Errorf("failed to save backup config: %w", err)
 	}
@@ -251,6 +476,34 @@ func (lm *LazyManager) UpdateBackupConfig(name, cronSchedule string, enabled boo
 	return nil
 }
 
+// SetBackupConnection moves a backup configuration to another Drive connection.
+// Configs declared in Config.SchedulerConfig are reset to their declared connection on the next sync.
+func (lm *LazyManager) SetBackupConnection(name, connection string) error {
+	if !lm.hasDriveConnection(connection) {
+		return fmt.Errorf("drive connection '%s' is not configured", connection)
+	}
+
+	config, err := lm.dbService.GetBackupConfigByName(name)
+	if err != nil {
+		return fmt.Errorf("backup config not found: %w", err)
+	}
+
+	config.Connection = connection
+	if err := lm.dbService.UpdateBackupConfig(config); err != nil {
+		return fmt.Errorf("failed to update backup config: %w", err)
+	}
+
+	// Scheduled jobs hold a copy of the config
+	if config.Enabled {
+		if err := lm.schedulerService.AddBackupJob(config); err != nil {
+			return fmt.Errorf("failed to reschedule backup job: %w", err)
+		}
+	}
+
+	log.Printf("Backup configuration '%s' now uploads to Drive connection '%s'", name, connection)
+	return nil
+}
+
 // DeleteBackupConfig removes a backup configuration
 func (lm *LazyManager) DeleteBackupConfig(name string) error {
 	// Remove from scheduler
@@ -274,38 +527,150 @@ func (lm *LazyManager) DeleteAllBackupConfig() error {
 	return nil
 }
 
-// Sync scheduler conffig
+// ExecuteBackupNow runs a backup of the named configuration immediately in the background
+func (lm *LazyManager) ExecuteBackupNow(name string) error {
+	return lm.schedulerService.ExecuteBackupNow(name)
+}
+
+// Backup History Methods
+
+// GetBackupHistory returns the backup history of a configuration, newest first,
+// along with the total number of records matching the filter
+func (lm *LazyManager) GetBackupHistory(name string, filter HistoryFilter) ([]database.BackupHistory, int64, error) {
+	config, err := lm.dbService.GetBackupConfigByName(name)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, fmt.Errorf("failed to get backup config: %w", err)
+		}
+		// The config may have been deleted; its history is still kept by name
+		return lm.dbService.GetBackupHistoryByConfigName(name, filter)
+	}
+
+	return lm.dbService.GetBackupHistoryByConfig(config.ID, filter)
+}
+
+// Notification Methods
+
+// PreviewNotification renders the backup message a notification config would send, without sending it.
+// A nil data previews made-up backup data; data with an ErrorMessage previews a failed backup.
+func (lm *LazyManager) PreviewNotification(name string, data *notification.BackupNotificationData) (*notification.Message, error) {
+	return lm.schedulerService.GetNotificationManager().PreviewNotification(name, data)
+}
+
+// GetNotificationResults returns the result of every notification send attempt, newest first,
+// along with the total number of records matching the filter; filter by Status "success" or "failed"
+func (lm *LazyManager) GetNotificationResults(filter NotificationFilter) ([]NotificationAttempt, int64, error) {
+	return lm.dbService.GetNotificationAttempts(filter)
+}
+
+// GetNotificationDeliveries returns the notifications queued in the outbox, newest first, along with
+// the total number matching the filter; filter by Status "dead" to list the dead-lettered ones
+func (lm *LazyManager) GetNotificationDeliveries(filter NotificationFilter) ([]NotificationDelivery, int64, error) {
+	return lm.dbService.GetNotificationDeliveries(filter)
+}
+
+// RetryNotificationDelivery queues a dead-lettered notification again with a fresh set of attempts
+func (lm *LazyManager) RetryNotificationDelivery(id uint) error {
+	if lm.outbox == nil {
+		return fmt.Errorf("notification delivery is not enabled")
+	}
+	return lm.outbox.Retry(id)
+}
+
+// Drive Backup Methods
+
+// ListBackups lists the backups of a configuration stored in Google Drive, newest first, one page at a time.
+// Pass the NextPageToken of a page to get the next one; it is empty on the last page.
+func (lm *LazyManager) ListBackups(name string, pageSize int64, pageToken string) (*gdrive.FileList, error) {
+	driveService, err := lm.backupDriveService(name)
+	if err != nil {
+		return nil, err
+	}
+	return driveService.ListBackups(name, pageSize, pageToken)
+}
+
+// DownloadBackup streams a backup of a configuration from Google Drive to w, starting at offset
+// to resume an interrupted download, and returns the number of bytes written
+func (lm *LazyManager) DownloadBackup(ctx context.Context, name, fileID string, offset int64, w io.Writer) (int64, error) {
+	driveService, err := lm.backupDriveService(name)
+	if err != nil {
+		return 0, err
+	}
+	return driveService.Download(ctx, fileID, offset, w)
+}
+
+// DownloadBackupToFile downloads a backup of a configuration from Google Drive to path and returns its size.
+// Calling it again after an interruption resumes the download; the file only appears at path once complete.
+func (lm *LazyManager) DownloadBackupToFile(ctx context.Context, name, fileID, path string) (int64, error) {
+	driveService, err := lm.backupDriveService(name)
+	if err != nil {
+		return 0, err
+	}
+	return driveService.DownloadToFile(ctx, fileID, path)
+}
+
+// backupDriveService returns the Drive service of the connection a configuration uploads to.
+// Backups of deleted configurations are looked up on the default connection.
+func (lm *LazyManager) backupDriveService(name string) (*gdrive.Service, error) {
+	connection := DefaultDriveConnection
+
+	config, err := lm.dbService.GetBackupConfigByName(name)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get backup config: %w", err)
+	}
+	if err == nil && config.Connection != "" {
+		connection = config.Connection
+	}
+
+	driveConnection, ok := lm.connections[connection]
+	if !ok {
+		return nil, fmt.Errorf("drive connection '%s' is not configured", connection)
+	}
+	return driveConnection.driveService, nil
+}
+
+// SyncSchedulerConfig reconciles the declared scheduler configs with the stored backup configs.
+// Configs are created, updated, disabled or deleted individually; failures are collected in a *SyncError.
 func (lm *LazyManager) SyncSchedulerConfig() error {
-	// Remove all existing backup configs
-	if err := lm.DeleteAllBackupConfig(); err != nil {
-		return fmt.Errorf("failed to clear backup configs: %w", err)
-	}
-	// Add new configs
-	for _, scheduler := range lm.config.SchedulerConfig {
-		if err := lm.AddBackupMySQLConfig(scheduler.Name, scheduler.BackupMode, scheduler.DatabaseConfig, scheduler.CronExpression); err != nil {
-			return fmt.Errorf("failed to add backup config scheduler")
+	steps, errs, err := lm.planBackupSync()
+	if err != nil {
+		return err
+	}
+
+	items := make([]SyncPlanItem, 0, len(steps))
+	for _, step := range steps {
+		items = append(items, step.item)
+	}
+	logSyncPlan(SyncKindBackup, items)
+
+	for _, step := range steps {
+		if err := lm.applyBackupSyncStep(step); err != nil {
+			errs = append(errs, &SyncItemError{Kind: SyncKindBackup, Name: step.item.Name, Action: step.item.Action, Err: err})
 		}
 	}
-	return nil
+
+	return syncErrorOrNil(errs)
 }
 
+// SyncNotifications reconciles the declared notification configs with the stored ones.
+// Configs are created, updated, disabled or deleted individually; failures are collected in a *SyncError.
 func (lm *LazyManager) SyncNotifications() error {
-	// Clear existing configs
-	if err := lm.dbService.DeleteAllNotificationConfig(); err != nil {
-		return fmt.Errorf("failed to clear notification configs: %w", err)
-	}
-	// Add new configs
-	for _, config := range lm.config.NotificationConfig {
-		if err := lm.dbService.SaveNotificationConfig(&database.NotificationConfig{
-			Name:            config.Name,
-			Channel:         config.Channel,
-			Config:          config.Config,
-			NotifyOnSuccess: config.NotifyOnSuccess,
-			NotifyOnError:   config.NotifyOnError,
-			Enabled:         config.Enabled,
-		}); err != nil {
-			return fmt.Errorf("failed to save notification config: %w", err)
+	steps, errs, err := lm.planNotificationSync()
+	if err != nil {
+		return err
+	}
+
+	items := make([]SyncPlanItem, 0, len(steps))
+	for _, step := range steps {
+		items = append(items, step.item)
+	}
+	logSyncPlan(SyncKindNotification, items)
+
+	for _, step := range steps {
+		if err := lm.applyNotificationSyncStep(step); err != nil {
+			errs = append(errs, &SyncItemError{Kind: SyncKindNotification, Name: step.item.Name, Action: step.item.Action, Err: err})
 		}
 	}
-	return nil
+
+	return syncErrorOrNil(errs)
 }
@@ -42,6 +42,9 @@ type SchedulerConfig struct {
 	BackupMode     string       `json:"backup_mode,omitempty"`
 	DatabaseConfig *MySQLConfig `json:"database_config,omitempty"`
 	CronExpression string       `json:"cron_expression,omitempty"`
+	Connection     string       `json:"connection,omitempty"` // Drive connection to upload to, the default connection when empty
+	// Enabled turns the schedule on or off; nil keeps the stored state, so a config disabled at runtime stays disabled
+	Enabled *bool `json:"enabled,omitempty"`
 }
 
 // Validate validates the MySQL configuration
@@ -0,0 +1,227 @@
+package gdrive
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// ConfigProperty is the app property naming the backup config of an uploaded backup
+const ConfigProperty = "lazy_config"
+
+// ListBackups lists the backups of a config, newest first, one page at a time. Backups are found by their
+// ConfigProperty, and by their config folder for backups uploaded before the property existed.
+// Pass the NextPageToken of a page to get the next one.
+func (s *Service) ListBackups(configName string, pageSize int64, pageToken string) (*FileList, error) {
+	driveService, err := s.driveClient()
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("appProperties has { key='%s' and value='%s' }", ConfigProperty, escapeQuery(configName))
+	folderID, err := s.configFolderID(configName)
+	if err != nil {
+		return nil, err
+	}
+	if folderID != "" {
+		query = fmt.Sprintf("(%s or '%s' in parents)", query, escapeQuery(folderID))
+	}
+	query += " and mimeType != 'application/vnd.google-apps.folder' and trashed=false"
+
+	call := s.listCall(driveService).
+		Q(query).
+		Fields("nextPageToken, files(id,name,size,createdTime,webViewLink)").
+		OrderBy("createdTime desc")
+	if pageSize > 0 {
+		call = call.PageSize(pageSize)
+	}
+	if pageToken != "" {
+		call = call.PageToken(pageToken)
+	}
+
+	res, err := call.Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	list := &FileList{Files: make([]File, 0, len(res.Files)), NextPageToken: res.NextPageToken}
+	for _, file := range res.Files {
+		list.Files = append(list.Files, toFile(file))
+	}
+	return list, nil
+}
+
+// configFolderID returns the existing folder the path template dedicates to a config, without creating it.
+// It is empty when the template has no such folder, e.g. when the config folder sits below a date folder.
+func (s *Service) configFolderID(configName string) (string, error) {
+	template := s.options.PathTemplate
+	if template == "" {
+		template = DefaultPathTemplate
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(template, "/") {
+		if strings.TrimSpace(segment) != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	var names []string
+	for i, segment := range segments {
+		withoutConfig := strings.ReplaceAll(segment, "{config}", "")
+		if pathPlaceholder.MatchString(withoutConfig) {
+			// A date folder comes first, so there is no single folder per config
+			return "", nil
+		}
+		if withoutConfig != segment {
+			expanded, err := ExpandPathTemplate(strings.Join(segments[:i+1], "/"), configName, time.Time{})
+			if err != nil {
+				return "", err
+			}
+			names = expanded
+			break
+		}
+	}
+	if names == nil {
+		return "", nil
+	}
+
+	parentID, err := s.RootFolderID()
+	if err != nil {
+		return "", err
+	}
+	for _, name := range names {
+		s.folderMutex.Lock()
+		folderID, cached := s.folderIDs[parentID+"/"+name]
+		s.folderMutex.Unlock()
+		if cached {
+			parentID = folderID
+			continue
+		}
+
+		folder, err := s.lookupFolderIn(name, parentID)
+		if err != nil {
+			return "", err
+		}
+		if folder == nil {
+			return "", nil
+		}
+		parentID = folder.Id
+	}
+	return parentID, nil
+}
+
+// toFile maps a Drive API file to a File
+func toFile(file *drive.File) File {
+	return File{
+		ID:          file.Id,
+		Name:        file.Name,
+		Size:        file.Size,
+		CreatedTime: file.CreatedTime,
+		WebViewLink: file.WebViewLink,
+	}
+}
+
+// Download streams a file to w starting at offset, so an interrupted download can be resumed,
+// and returns the number of bytes written
+func (s *Service) Download(ctx context.Context, fileID string, offset int64, w io.Writer) (int64, error) {
+	driveService, err := s.driveClient()
+	if err != nil {
+		return 0, err
+	}
+
+	call := driveService.Files.Get(fileID).SupportsAllDrives(true).Context(ctx)
+	if offset > 0 {
+		call.Header().Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := call.Download()
+	if err != nil {
+		return 0, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+		// The range was ignored, skip the part the caller already has
+		if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+			return 0, fmt.Errorf("failed to skip to offset %d: %w", offset, err)
+		}
+	}
+
+	written, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return written, fmt.Errorf("failed to download file: %w", err)
+	}
+	return written, nil
+}
+
+// DownloadToFile downloads a file to path and returns its size. The download is written to path + ".part"
+// first; calling it again after an interruption resumes from the bytes already there. The file is renamed
+// to path once complete and its checksum matches.
+func (s *Service) DownloadToFile(ctx context.Context, fileID, path string) (int64, error) {
+	info, err := s.GetFileInfo(fileID)
+	if err != nil {
+		return 0, err
+	}
+
+	partPath := path + ".part"
+	part, err := os.OpenFile(partPath, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open download file: %w", err)
+	}
+	defer part.Close()
+
+	stat, err := part.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get download file info: %w", err)
+	}
+	offset := stat.Size()
+	if offset > info.Size {
+		// Left over from a different file, start over
+		if err := part.Truncate(0); err != nil {
+			return 0, fmt.Errorf("failed to reset download file: %w", err)
+		}
+		offset = 0
+	}
+	if _, err := part.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to resume download: %w", err)
+	}
+
+	if offset < info.Size {
+		written, err := s.Download(ctx, fileID, offset, part)
+		if err != nil {
+			return offset + written, err
+		}
+	}
+
+	if info.Md5Checksum != "" {
+		if _, err := part.Seek(0, io.SeekStart); err != nil {
+			return 0, fmt.Errorf("failed to verify download: %w", err)
+		}
+		hash := md5.New()
+		if _, err := io.Copy(hash, part); err != nil {
+			return 0, fmt.Errorf("failed to verify download: %w", err)
+		}
+		if checksum := hex.EncodeToString(hash.Sum(nil)); checksum != info.Md5Checksum {
+			part.Close()
+			os.Remove(partPath)
+			return 0, fmt.Errorf("checksum mismatch for '%s': got %s, expected %s", info.Name, checksum, info.Md5Checksum)
+		}
+	}
+
+	if err := part.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write download file: %w", err)
+	}
+	if err := os.Rename(partPath, path); err != nil {
+		return 0, fmt.Errorf("failed to move download into place: %w", err)
+	}
+	return info.Size, nil
+}
@@ -0,0 +1,307 @@
+package gdrive
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// fakeDrive is an in-memory stand-in for the Drive v3 files API, enough for folder and upload calls
+type fakeDrive struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu       sync.Mutex
+	files    map[string]*fakeFile
+	order    []string
+	nextID   int
+	requests []*http.Request
+	quota    map[string]string // storageQuota returned by about.get
+	listErr  int               // status returned by files.list instead of results, 0 for none
+}
+
+type fakeFile struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	MimeType      string            `json:"mimeType,omitempty"`
+	Parents       []string          `json:"parents,omitempty"`
+	Size          int64             `json:"size,string,omitempty"`
+	CreatedTime   string            `json:"createdTime,omitempty"`
+	AppProperties map[string]string `json:"appProperties,omitempty"`
+	Md5Checksum   string            `json:"md5Checksum,omitempty"`
+	content       []byte
+}
+
+var (
+	fakeNameTerm   = regexp.MustCompile(`name\s*=\s*'((?:[^'\\]|\\.)*)'`)
+	fakeParentTerm = regexp.MustCompile(`'((?:[^'\\]|\\.)*)'\s+in\s+parents`)
+	fakeMimeTerm   = regexp.MustCompile(`mimeType\s*=\s*'([^']*)'`)
+	fakeNotMime    = regexp.MustCompile(`mimeType\s*!=\s*'([^']*)'`)
+	fakeAppProp    = regexp.MustCompile(`appProperties has \{ key='([^']*)' and value='((?:[^'\\]|\\.)*)' \}`)
+	fakeGroup      = regexp.MustCompile(`\(([^()]*)\)`)
+)
+
+func newFakeDrive(t *testing.T) *fakeDrive {
+	fd := &fakeDrive{t: t, files: make(map[string]*fakeFile)}
+	fd.server = httptest.NewServer(http.HandlerFunc(fd.handle))
+	t.Cleanup(fd.server.Close)
+	return fd
+}
+
+// service returns a gdrive service talking to the fake drive
+func (fd *fakeDrive) service(options FolderOptions) *Service {
+	authService := &MockAuthService{}
+	authService.On("GetClient").Return(&oauth2.Config{}, &oauth2.Token{AccessToken: "token", Expiry: time.Now().Add(time.Hour)}, nil)
+
+	service := NewServiceWithFolder(authService, options)
+	service.endpoint = fd.server.URL + "/"
+	return service
+}
+
+// add stores a file directly, e.g. a folder that already exists
+func (fd *fakeDrive) add(file *fakeFile) *fakeFile {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+
+	if file.ID == "" {
+		fd.nextID++
+		file.ID = fmt.Sprintf("file-%d", fd.nextID)
+	}
+	if file.CreatedTime == "" {
+		file.CreatedTime = time.Now().Add(time.Duration(len(fd.order)) * time.Second).UTC().Format(time.RFC3339)
+	}
+	fd.files[file.ID] = file
+	fd.order = append(fd.order, file.ID)
+	return file
+}
+
+// find returns the files named name
+func (fd *fakeDrive) find(name string) []*fakeFile {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+
+	var found []*fakeFile
+	for _, id := range fd.order {
+		if file, ok := fd.files[id]; ok && file.Name == name {
+			found = append(found, file)
+		}
+	}
+	return found
+}
+
+// queries returns the query values of the recorded requests with the given method
+func (fd *fakeDrive) queries(method string) []url.Values {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+
+	var queries []url.Values
+	for _, r := range fd.requests {
+		if r.Method == method {
+			queries = append(queries, r.URL.Query())
+		}
+	}
+	return queries
+}
+
+// mediaRanges returns the Range headers of the recorded downloads
+func (fd *fakeDrive) mediaRanges() []string {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+
+	var ranges []string
+	for _, r := range fd.requests {
+		if r.URL.Query().Get("alt") == "media" {
+			ranges = append(ranges, r.Header.Get("Range"))
+		}
+	}
+	return ranges
+}
+
+func (fd *fakeDrive) handle(w http.ResponseWriter, r *http.Request) {
+	fd.mu.Lock()
+	fd.requests = append(fd.requests, r)
+	fd.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/upload/drive/v3")
+	switch {
+	case r.Method == http.MethodGet && path == "/about":
+		fd.mu.Lock()
+		writeFakeJSON(w, map[string]interface{}{"storageQuota": fd.quota})
+		fd.mu.Unlock()
+	case r.Method == http.MethodPost && path == "/files":
+		fd.create(w, r)
+	case r.Method == http.MethodGet && path == "/files":
+		fd.mu.Lock()
+		listErr := fd.listErr
+		fd.mu.Unlock()
+		if listErr != 0 {
+			http.Error(w, `{"error":{"code":`+strconv.Itoa(listErr)+`,"message":"backend error"}}`, listErr)
+			return
+		}
+		fd.list(w, r)
+	case strings.HasPrefix(path, "/files/"):
+		fd.file(w, r, strings.TrimPrefix(path, "/files/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (fd *fakeDrive) create(w http.ResponseWriter, r *http.Request) {
+	file := &fakeFile{}
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		metadata, err := reader.NextPart()
+		if err != nil {
+			fd.t.Errorf("missing metadata part: %v", err)
+			return
+		}
+		json.NewDecoder(metadata).Decode(file)
+		if media, err := reader.NextPart(); err == nil {
+			file.content, _ = io.ReadAll(media)
+			file.Size = int64(len(file.content))
+			file.Md5Checksum = fmt.Sprintf("%x", md5.Sum(file.content))
+		}
+	} else {
+		json.NewDecoder(r.Body).Decode(file)
+	}
+
+	fd.mu.Lock()
+	for _, parent := range file.Parents {
+		if _, ok := fd.files[parent]; !ok {
+			fd.mu.Unlock()
+			w.WriteHeader(http.StatusNotFound)
+			writeFakeJSON(w, map[string]interface{}{"error": map[string]interface{}{"code": 404, "message": "File not found: " + parent}})
+			return
+		}
+	}
+	fd.mu.Unlock()
+
+	fd.add(file)
+	writeFakeJSON(w, file)
+}
+
+// remove deletes a file behind the service's back, e.g. a folder deleted in the Drive UI
+func (fd *fakeDrive) remove(id string) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+
+	delete(fd.files, id)
+}
+
+func (fd *fakeDrive) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+
+	fd.mu.Lock()
+	var matched []*fakeFile
+	for _, id := range fd.order {
+		file, ok := fd.files[id]
+		if ok && fakeMatches(file, query) {
+			matched = append(matched, file)
+		}
+	}
+	fd.mu.Unlock()
+
+	if strings.Contains(r.URL.Query().Get("orderBy"), "createdTime desc") {
+		sort.SliceStable(matched, func(i, j int) bool { return matched[i].CreatedTime > matched[j].CreatedTime })
+	}
+
+	// Page tokens are offsets
+	response := map[string]interface{}{}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("pageToken"))
+	matched = matched[min(offset, len(matched)):]
+	if pageSize, _ := strconv.Atoi(r.URL.Query().Get("pageSize")); pageSize > 0 && len(matched) > pageSize {
+		matched = matched[:pageSize]
+		response["nextPageToken"] = strconv.Itoa(offset + pageSize)
+	}
+	response["files"] = matched
+
+	writeFakeJSON(w, response)
+}
+
+func (fd *fakeDrive) file(w http.ResponseWriter, r *http.Request, id string) {
+	fd.mu.Lock()
+	file, ok := fd.files[id]
+	if ok && r.Method == http.MethodDelete {
+		delete(fd.files, id)
+	}
+	fd.mu.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		writeFakeJSON(w, map[string]interface{}{"error": map[string]interface{}{"code": 404, "message": "File not found: " + id}})
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Query().Get("alt") == "media":
+		http.ServeContent(w, r, file.Name, time.Time{}, bytes.NewReader(file.content))
+	default:
+		writeFakeJSON(w, file)
+	}
+}
+
+// fakeMatches evaluates the name, parent, mimeType and appProperties terms of a Drive query,
+// with one level of parenthesized "or" alternatives
+func fakeMatches(file *fakeFile, query string) bool {
+	for _, group := range fakeGroup.FindAllStringSubmatch(query, -1) {
+		matched := false
+		for _, alternative := range strings.Split(group[1], " or ") {
+			matched = matched || fakeMatches(file, alternative)
+		}
+		if !matched {
+			return false
+		}
+		query = strings.Replace(query, group[0], "", 1)
+	}
+
+	unescape := strings.NewReplacer(`\'`, `'`, `\\`, `\`).Replace
+	if m := fakeNameTerm.FindStringSubmatch(query); m != nil && file.Name != unescape(m[1]) {
+		return false
+	}
+	if m := fakeMimeTerm.FindStringSubmatch(query); m != nil && file.MimeType != m[1] {
+		return false
+	}
+	if m := fakeNotMime.FindStringSubmatch(query); m != nil && file.MimeType == m[1] {
+		return false
+	}
+	if m := fakeAppProp.FindStringSubmatch(query); m != nil && file.AppProperties[m[1]] != unescape(m[2]) {
+		return false
+	}
+	if m := fakeParentTerm.FindStringSubmatch(query); m != nil {
+		parents := file.Parents
+		if len(parents) == 0 {
+			parents = []string{"root"} // created without a parent, i.e. in My Drive
+		}
+		for _, parent := range parents {
+			if parent == unescape(m[1]) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func writeFakeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -2,12 +2,18 @@ package gdrive
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
@@ -19,18 +25,381 @@ type AuthService interface {
 	RefreshToken(token *oauth2.Token) (*oauth2.Token, error)
 }
 
+// TokenSourceProvider is implemented by auth services that hand out a token source for long-lived clients
+type TokenSourceProvider interface {
+	TokenSource() oauth2.TokenSource
+}
+
+// FolderOptions places backups in a shared drive and/or below a root folder instead of the root of My Drive
+type FolderOptions struct {
+	SharedDriveID  string // shared drive holding the backups
+	RootFolderID   string // folder the backup folders are created in, the shared drive or My Drive root when empty
+	RootFolderPath string // e.g. "Backups/Databases", created below RootFolderID when missing
+	// PathTemplate of the folder each backup is uploaded to, below the root folder, see ExpandPathTemplate.
+	// DefaultPathTemplate when empty.
+	PathTemplate string
+	// FullDriveAccess requests the full drive scope instead of drive.file, which only reaches files created
+	// by this app. It is needed when RootFolderID or SharedDriveID point to a folder created elsewhere.
+	FullDriveAccess bool
+}
+
+// Scope returns the Drive OAuth scope the options need
+func (o FolderOptions) Scope() string {
+	if o.FullDriveAccess {
+		return drive.DriveScope
+	}
+	return drive.DriveFileScope
+}
+
+// DefaultPathTemplate keeps one flat folder per backup config
+const DefaultPathTemplate = "DB Backups - {config}"
+
+// pathPlaceholder matches the placeholders of a path template
+var pathPlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// ExpandPathTemplate replaces the placeholders of a path template and returns its folder names.
+// Placeholders are {config}, {yyyy}, {mm}, {dd} and {hh}; slashes separate nested folders,
+// e.g. "{config}/{yyyy}/{mm}/{dd}".
+func ExpandPathTemplate(template, configName string, at time.Time) ([]string, error) {
+	if template == "" {
+		template = DefaultPathTemplate
+	}
+
+	var unknown string
+	expanded := pathPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		switch placeholder {
+		case "{config}":
+			// A config name must not add folder levels
+			return strings.ReplaceAll(configName, "/", "-")
+		case "{yyyy}":
+			return at.Format("2006")
+		case "{mm}":
+			return at.Format("01")
+		case "{dd}":
+			return at.Format("02")
+		case "{hh}":
+			return at.Format("15")
+		default:
+			if unknown == "" {
+				unknown = placeholder
+			}
+			return placeholder
+		}
+	})
+	if unknown != "" {
+		return nil, fmt.Errorf("unknown placeholder %s in path template '%s'", unknown, template)
+	}
+
+	var names []string
+	for _, name := range strings.Split(expanded, "/") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("path template '%s' has no folder names", template)
+	}
+	return names, nil
+}
+
+// ValidatePathTemplate reports unknown placeholders and templates without folder names
+func ValidatePathTemplate(template string) error {
+	_, err := ExpandPathTemplate(template, "config", time.Now())
+	return err
+}
+
 // Service handles Google Drive operations
 type Service struct {
 	authService AuthService
+	options     FolderOptions
+	endpoint    string // Drive API endpoint override for tests
+
+	rootMutex sync.Mutex
+	rootID    string // resolved root folder, set once RootFolderPath was resolved
+
+	folderMutex sync.Mutex
+	folderIDs   map[string]string   // resolved backup folders by parent ID and name
+	folderPaths map[string][]string // folderIDs keys of the last folder resolved per backup config
+	folderCalls singleflight.Group  // lookups in flight by folderIDs key, so a folder is created once
+
+	clientMutex sync.Mutex
+	tokenSource oauth2.TokenSource
+	client      *drive.Service
 }
 
-// NewService creates a new Google Drive service
+// NewService creates a new Google Drive service storing folders at the root of My Drive
 func NewService(authService AuthService) *Service {
 	return &Service{
 		authService: authService,
 	}
 }
 
+// NewServiceWithFolder creates a new Google Drive service storing folders as configured by options
+func NewServiceWithFolder(authService AuthService, options FolderOptions) *Service {
+	return &Service{
+		authService: authService,
+		options:     options,
+	}
+}
+
+// authTokenSource adapts an AuthService without a token source of its own
+type authTokenSource struct {
+	authService AuthService
+}
+
+func (ts authTokenSource) Token() (*oauth2.Token, error) {
+	_, token, err := ts.authService.GetClient()
+	return token, err
+}
+
+// driveClient returns the Drive API client, created on first use and shared by all calls.
+// Tokens come from the auth service's token source, so refreshed or re-authorized tokens are picked up;
+// a token is checked up front so calls fail fast with an authorization error.
+func (s *Service) driveClient() (*drive.Service, error) {
+	s.clientMutex.Lock()
+	defer s.clientMutex.Unlock()
+
+	if s.tokenSource == nil {
+		if provider, ok := s.authService.(TokenSourceProvider); ok {
+			s.tokenSource = provider.TokenSource()
+		} else {
+			s.tokenSource = oauth2.ReuseTokenSourceWithExpiry(nil, authTokenSource{authService: s.authService}, 5*time.Minute)
+		}
+	}
+
+	if _, err := s.tokenSource.Token(); err != nil {
+		return nil, fmt.Errorf("failed to get authenticated client: %w", err)
+	}
+
+	if s.client != nil {
+		return s.client, nil
+	}
+
+	// oauth2.NewClient would wrap the source in a cache of its own that keeps replaced tokens until they expire
+	ctx := context.Background()
+	httpClient := &http.Client{Transport: &oauth2.Transport{Source: s.tokenSource}}
+	clientOptions := []option.ClientOption{option.WithHTTPClient(httpClient)}
+	if s.endpoint != "" {
+		clientOptions = append(clientOptions, option.WithEndpoint(s.endpoint))
+	}
+
+	driveService, err := drive.NewService(ctx, clientOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create drive service: %w", err)
+	}
+	s.client = driveService
+	return s.client, nil
+}
+
+// escapeQuery escapes a value for a single-quoted string in a Drive query
+func escapeQuery(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+}
+
+// isNotFound reports whether a Drive call failed because a file or folder no longer exists
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+// InvalidateFolderCache forgets resolved folder IDs, e.g. after folders were deleted or moved in Drive
+func (s *Service) InvalidateFolderCache() {
+	s.rootMutex.Lock()
+	s.rootID = ""
+	s.rootMutex.Unlock()
+
+	s.folderMutex.Lock()
+	s.folderIDs = nil
+	s.folderPaths = nil
+	s.folderMutex.Unlock()
+}
+
+// forgetFolder drops the cached folders when a deleted file was one of them, along with the folders below it
+func (s *Service) forgetFolder(fileID string) {
+	s.rootMutex.Lock()
+	deletedRoot := s.rootID == fileID
+	s.rootMutex.Unlock()
+	if deletedRoot {
+		s.InvalidateFolderCache()
+		return
+	}
+
+	s.folderMutex.Lock()
+	defer s.folderMutex.Unlock()
+
+	for _, id := range s.folderIDs {
+		if id == fileID {
+			s.folderIDs = nil
+			s.folderPaths = nil
+			return
+		}
+	}
+}
+
+// ErrRootNotAccessible is returned by CheckRootAccess when Drive reports the configured root as not found
+var ErrRootNotAccessible = errors.New("root folder is not accessible")
+
+// CheckRootAccess makes sure the configured RootFolderID or SharedDriveID can be reached with the granted scope.
+// Under the drive.file scope Drive reports a folder created elsewhere as not found.
+func (s *Service) CheckRootAccess() error {
+	base := s.options.RootFolderID
+	if base == "" {
+		base = s.options.SharedDriveID
+	}
+	if base == "" {
+		return nil
+	}
+
+	driveService, err := s.driveClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = driveService.Files.Get(base).SupportsAllDrives(true).Fields("id").Do()
+	switch {
+	case err == nil:
+		return nil
+	case !isNotFound(err):
+		return fmt.Errorf("failed to check root folder '%s': %w", base, err)
+	case s.options.FullDriveAccess:
+		return fmt.Errorf("%w: '%s' does not exist or is not shared with this account", ErrRootNotAccessible, base)
+	default:
+		return fmt.Errorf("%w: '%s' was not created by this app, which the drive.file scope requires; set FullDriveAccess to use an existing folder", ErrRootNotAccessible, base)
+	}
+}
+
+// RootFolderID returns the folder backup folders are created in, creating RootFolderPath on first use.
+// An empty ID is the root of My Drive.
+func (s *Service) RootFolderID() (string, error) {
+	base := s.options.RootFolderID
+	if base == "" {
+		// The ID of a shared drive is also the ID of its root folder
+		base = s.options.SharedDriveID
+	}
+
+	if strings.Trim(s.options.RootFolderPath, "/ ") == "" {
+		return base, nil
+	}
+
+	s.rootMutex.Lock()
+	defer s.rootMutex.Unlock()
+
+	if s.rootID != "" {
+		return s.rootID, nil
+	}
+
+	parentID := base
+	for _, name := range strings.Split(s.options.RootFolderPath, "/") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		folder, err := s.getOrCreateFolderIn(name, parentID)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve root folder path '%s': %w", s.options.RootFolderPath, err)
+		}
+		parentID = folder.Id
+	}
+
+	s.rootID = parentID
+	return s.rootID, nil
+}
+
+// BackupFolderID resolves the path template for a backup taken at the given time into nested folders below
+// the root folder, creating missing ones, and returns the ID of the innermost folder. The current folder of
+// each config is cached, so only folders new to this process are looked up; older date partitions are dropped.
+func (s *Service) BackupFolderID(configName string, at time.Time) (string, error) {
+	names, err := ExpandPathTemplate(s.options.PathTemplate, configName, at)
+	if err != nil {
+		return "", err
+	}
+
+	parentID, err := s.RootFolderID()
+	if err != nil {
+		return "", err
+	}
+
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		key := parentID + "/" + name
+		keys = append(keys, key)
+
+		s.folderMutex.Lock()
+		folderID, cached := s.folderIDs[key]
+		s.folderMutex.Unlock()
+		if !cached {
+			// Backups resolving the same folder at once share the lookup, so it is not created twice
+			id, err, _ := s.folderCalls.Do(key, func() (interface{}, error) {
+				folder, err := s.getOrCreateFolderIn(name, parentID)
+				if err != nil {
+					return "", err
+				}
+				s.cacheFolder(key, folder.Id)
+				return folder.Id, nil
+			})
+			if err != nil {
+				return "", fmt.Errorf("failed to resolve backup folder '%s': %w", strings.Join(names, "/"), err)
+			}
+			folderID = id.(string)
+		}
+		parentID = folderID
+	}
+
+	s.keepFolderPath(configName, keys)
+	return parentID, nil
+}
+
+// cacheFolder remembers a resolved backup folder
+func (s *Service) cacheFolder(key, folderID string) {
+	s.folderMutex.Lock()
+	defer s.folderMutex.Unlock()
+
+	if s.folderIDs == nil {
+		s.folderIDs = make(map[string]string)
+	}
+	s.folderIDs[key] = folderID
+}
+
+// keepFolderPath records the folders a config uses now and drops cached folders no config uses anymore,
+// so date partitioned folders do not pile up in a long-running process
+func (s *Service) keepFolderPath(configName string, keys []string) {
+	s.folderMutex.Lock()
+	defer s.folderMutex.Unlock()
+
+	if s.folderPaths == nil {
+		s.folderPaths = make(map[string][]string)
+	}
+	s.folderPaths[configName] = keys
+
+	used := make(map[string]bool, len(s.folderIDs))
+	for _, path := range s.folderPaths {
+		for _, key := range path {
+			used[key] = true
+		}
+	}
+	for key := range s.folderIDs {
+		if !used[key] {
+			delete(s.folderIDs, key)
+		}
+	}
+}
+
+// parentOrRoot returns the given parent folder, or the root folder when none is given
+func (s *Service) parentOrRoot(parentFolderID []string) (string, error) {
+	if len(parentFolderID) > 0 && parentFolderID[0] != "" {
+		return parentFolderID[0], nil
+	}
+	return s.RootFolderID()
+}
+
+// listCall scopes a files.list call to the shared drive, or to every drive the user can access
+func (s *Service) listCall(driveService *drive.Service) *drive.FilesListCall {
+	call := driveService.Files.List().SupportsAllDrives(true).IncludeItemsFromAllDrives(true)
+	if s.options.SharedDriveID != "" {
+		call = call.Corpora("drive").DriveId(s.options.SharedDriveID)
+	}
+	return call
+}
+
 // UploadResult contains information about the uploaded file
 type UploadResult struct {
 	FileID      string `json:"file_id"`
@@ -41,16 +410,14 @@ type UploadResult struct {
 
 // UploadFile uploads a file to Google Drive
 func (s *Service) UploadFile(filePath string, folderID ...string) (*UploadResult, error) {
-	config, token, err := s.authService.GetClient()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get authenticated client: %w", err)
-	}
+	return s.uploadFile(filePath, nil, folderID...)
+}
 
-	ctx := context.Background()
-	client := config.Client(ctx, token)
-	driveService, err := drive.NewService(ctx, option.WithHTTPClient(client))
+// uploadFile uploads a file with app properties that later identify it, e.g. its backup config
+func (s *Service) uploadFile(filePath string, appProperties map[string]string, folderID ...string) (*UploadResult, error) {
+	driveService, err := s.driveClient()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create drive service: %w", err)
+		return nil, err
 	}
 
 	// Open the file
@@ -70,17 +437,25 @@ func (s *Service) UploadFile(filePath string, folderID ...string) (*UploadResult
 
 	// Create drive file metadata
 	driveFile := &drive.File{
-		Name:        fileName,
-		Description: fmt.Sprintf("Database backup created on %s", time.Now().Format("2006-01-02 15:04:05")),
+		Name:          fileName,
+		Description:   fmt.Sprintf("Database backup created on %s", time.Now().Format("2006-01-02 15:04:05")),
+		AppProperties: appProperties,
 	}
 
-	// Set parent folder if provided
-	if len(folderID) > 0 && folderID[0] != "" {
-		driveFile.Parents = []string{folderID[0]}
+	// Upload to the given folder, or the root folder
+	parentID, err := s.parentOrRoot(folderID)
+	if err != nil {
+		return nil, err
+	}
+	if parentID != "" {
+		driveFile.Parents = []string{parentID}
 	}
 
 	// Upload the file
-	res, err := driveService.Files.Create(driveFile).Media(file, googleapi.ContentType("application/sql")).Do()
+	res, err := driveService.Files.Create(driveFile).
+		Media(file, googleapi.ContentType("application/sql")).
+		SupportsAllDrives(true).
+		Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload file to drive: %w", err)
 	}
@@ -93,18 +468,41 @@ func (s *Service) UploadFile(filePath string, folderID ...string) (*UploadResult
 	}, nil
 }
 
-// CreateFolder creates a folder in Google Drive
+// UploadBackup uploads a backup into the folder its config and start time resolve to, tagged with
+// ConfigProperty so ListBackups finds it. When a cached folder was deleted in Drive meanwhile,
+// the folders are resolved again and the upload retried once.
+func (s *Service) UploadBackup(filePath, configName string, at time.Time) (*UploadResult, error) {
+	folderID, err := s.BackupFolderID(configName, at)
+	if err != nil {
+		return nil, err
+	}
+
+	appProperties := map[string]string{ConfigProperty: configName}
+	result, err := s.uploadFile(filePath, appProperties, folderID)
+	if err == nil || !isNotFound(err) {
+		return result, err
+	}
+
+	s.InvalidateFolderCache()
+	if folderID, err = s.BackupFolderID(configName, at); err != nil {
+		return nil, err
+	}
+	return s.uploadFile(filePath, appProperties, folderID)
+}
+
+// CreateFolder creates a folder in the given parent folder, or in the root folder
 func (s *Service) CreateFolder(name string, parentFolderID ...string) (*drive.File, error) {
-	config, token, err := s.authService.GetClient()
+	parentID, err := s.parentOrRoot(parentFolderID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get authenticated client: %w", err)
+		return nil, err
 	}
+	return s.createFolderIn(name, parentID)
+}
 
-	ctx := context.Background()
-	client := config.Client(ctx, token)
-	driveService, err := drive.NewService(ctx, option.WithHTTPClient(client))
+func (s *Service) createFolderIn(name, parentID string) (*drive.File, error) {
+	driveService, err := s.driveClient()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create drive service: %w", err)
+		return nil, err
 	}
 
 	folder := &drive.File{
@@ -113,11 +511,11 @@ func (s *Service) CreateFolder(name string, parentFolderID ...string) (*drive.Fi
 	}
 
 	// Set parent folder if provided
-	if len(parentFolderID) > 0 && parentFolderID[0] != "" {
-		folder.Parents = []string{parentFolderID[0]}
+	if parentID != "" {
+		folder.Parents = []string{parentID}
 	}
 
-	res, err := driveService.Files.Create(folder).Do()
+	res, err := driveService.Files.Create(folder).SupportsAllDrives(true).Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create folder: %w", err)
 	}
@@ -125,66 +523,83 @@ func (s *Service) CreateFolder(name string, parentFolderID ...string) (*drive.Fi
 	return res, nil
 }
 
-// FindFolder finds a folder by name
+// FindFolder finds a folder by name in the given parent folder, or in the root folder
 func (s *Service) FindFolder(name string, parentFolderID ...string) (*drive.File, error) {
-	config, token, err := s.authService.GetClient()
+	parentID, err := s.parentOrRoot(parentFolderID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get authenticated client: %w", err)
+		return nil, err
 	}
+	return s.findFolderIn(name, parentID)
+}
 
-	ctx := context.Background()
-	client := config.Client(ctx, token)
-	driveService, err := drive.NewService(ctx, option.WithHTTPClient(client))
+func (s *Service) findFolderIn(name, parentID string) (*drive.File, error) {
+	folder, err := s.lookupFolderIn(name, parentID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create drive service: %w", err)
+		return nil, err
+	}
+	if folder == nil {
+		return nil, fmt.Errorf("folder '%s' not found", name)
 	}
+	return folder, nil
+}
 
-	query := fmt.Sprintf("name='%s' and mimeType='application/vnd.google-apps.folder' and trashed=false", name)
+// lookupFolderIn returns the folder named name in the parent folder, nil when there is none.
+// An empty parent is the root of My Drive, so a same-named folder elsewhere or shared with the account is not picked up.
+func (s *Service) lookupFolderIn(name, parentID string) (*drive.File, error) {
+	driveService, err := s.driveClient()
+	if err != nil {
+		return nil, err
+	}
 
-	// Add parent folder constraint if provided
-	if len(parentFolderID) > 0 && parentFolderID[0] != "" {
-		query = fmt.Sprintf("%s and '%s' in parents", query, parentFolderID[0])
+	if parentID == "" {
+		parentID = "root"
 	}
+	query := fmt.Sprintf("name='%s' and mimeType='application/vnd.google-apps.folder' and trashed=false and '%s' in parents",
+		escapeQuery(name), escapeQuery(parentID))
 
-	res, err := driveService.Files.List().Q(query).Do()
+	res, err := s.listCall(driveService).Q(query).Fields("files(id,name,parents)").Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to search for folder: %w", err)
 	}
 
 	if len(res.Files) == 0 {
-		return nil, fmt.Errorf("folder '%s' not found", name)
+		return nil, nil
 	}
 
 	return res.Files[0], nil
 }
 
-// GetOrCreateFolder gets an existing folder or creates a new one
+// GetOrCreateFolder gets an existing folder or creates a new one, in the root folder when no parent is given
 func (s *Service) GetOrCreateFolder(name string, parentFolderID ...string) (*drive.File, error) {
-	// Try to find existing folder first
-	folder, err := s.FindFolder(name, parentFolderID...)
-	if err == nil {
+	parentID, err := s.parentOrRoot(parentFolderID)
+	if err != nil {
+		return nil, err
+	}
+	return s.getOrCreateFolderIn(name, parentID)
+}
+
+func (s *Service) getOrCreateFolderIn(name, parentID string) (*drive.File, error) {
+	// Try to find existing folder first; a failed lookup is not a missing folder, creating one would duplicate it
+	folder, err := s.lookupFolderIn(name, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if folder != nil {
 		return folder, nil
 	}
 
 	// Create new folder if not found
-	return s.CreateFolder(name, parentFolderID...)
+	return s.createFolderIn(name, parentID)
 }
 
 // ListFiles lists files in Google Drive with optional query
 func (s *Service) ListFiles(query string, maxResults int64) ([]*drive.File, error) {
-	config, token, err := s.authService.GetClient()
+	driveService, err := s.driveClient()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get authenticated client: %w", err)
+		return nil, err
 	}
 
-	ctx := context.Background()
-	client := config.Client(ctx, token)
-	driveService, err := drive.NewService(ctx, option.WithHTTPClient(client))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create drive service: %w", err)
-	}
-
-	call := driveService.Files.List().
+	call := s.listCall(driveService).
 		Fields("files(id,name,size,createdTime,modifiedTime,webViewLink)").
 		OrderBy("createdTime desc")
 
@@ -206,42 +621,30 @@ func (s *Service) ListFiles(query string, maxResults int64) ([]*drive.File, erro
 
 // DeleteFile deletes a file from Google Drive
 func (s *Service) DeleteFile(fileID string) error {
-	config, token, err := s.authService.GetClient()
+	driveService, err := s.driveClient()
 	if err != nil {
-		return fmt.Errorf("failed to get authenticated client: %w", err)
+		return err
 	}
 
-	ctx := context.Background()
-	client := config.Client(ctx, token)
-	driveService, err := drive.NewService(ctx, option.WithHTTPClient(client))
-	if err != nil {
-		return fmt.Errorf("failed to create drive service: %w", err)
-	}
-
-	err = driveService.Files.Delete(fileID).Do()
+	err = driveService.Files.Delete(fileID).SupportsAllDrives(true).Do()
 	if err != nil {
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
+	s.forgetFolder(fileID)
 
 	return nil
 }
 
 // GetFileInfo gets information about a file
 func (s *Service) GetFileInfo(fileID string) (*drive.File, error) {
-	config, token, err := s.authService.GetClient()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get authenticated client: %w", err)
-	}
-
-	ctx := context.Background()
-	client := config.Client(ctx, token)
-	driveService, err := drive.NewService(ctx, option.WithHTTPClient(client))
+	driveService, err := s.driveClient()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create drive service: %w", err)
+		return nil, err
 	}
 
 	file, err := driveService.Files.Get(fileID).
-		Fields("id,name,size,createdTime,modifiedTime,webViewLink").
+		SupportsAllDrives(true).
+		Fields("id,name,size,md5Checksum,createdTime,modifiedTime,webViewLink").
 		Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get file info: %w", err)
@@ -249,3 +652,50 @@ func (s *Service) GetFileInfo(fileID string) (*drive.File, error) {
 
 	return file, nil
 }
+
+// StorageQuota is the Drive storage of the authenticated account in bytes; Limit is 0 when unlimited
+type StorageQuota struct {
+	Limit        int64 `json:"limit"`
+	Usage        int64 `json:"usage"`
+	UsageInDrive int64 `json:"usage_in_drive"`
+}
+
+// Unlimited reports whether the account has no storage limit
+func (q *StorageQuota) Unlimited() bool {
+	return q.Limit <= 0
+}
+
+// Free returns the bytes left before the limit is reached
+func (q *StorageQuota) Free() int64 {
+	if free := q.Limit - q.Usage; free > 0 {
+		return free
+	}
+	return 0
+}
+
+// GetStorageQuota returns the storage quota of the authenticated account.
+// Files on a shared drive do not count against it, so a shared-drive service reports no limit.
+func (s *Service) GetStorageQuota() (*StorageQuota, error) {
+	if s.options.SharedDriveID != "" {
+		return &StorageQuota{}, nil
+	}
+
+	driveService, err := s.driveClient()
+	if err != nil {
+		return nil, err
+	}
+
+	about, err := driveService.About.Get().Fields("storageQuota").Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage quota: %w", err)
+	}
+	if about.StorageQuota == nil {
+		return &StorageQuota{}, nil
+	}
+
+	return &StorageQuota{
+		Limit:        about.StorageQuota.Limit,
+		Usage:        about.StorageQuota.Usage,
+		UsageInDrive: about.StorageQuota.UsageInDrive,
+	}, nil
+}
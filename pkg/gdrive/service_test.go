@@ -1,10 +1,14 @@
 package gdrive
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,6 +16,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"golang.org/x/oauth2"
+	"google.golang.org/api/drive/v3"
 )
 
 // MockAuthService is a mock implementation of the auth service
@@ -588,3 +593,482 @@ func BenchmarkNewService(b *testing.B) {
 		_ = NewService(mockAuth)
 	}
 }
+
+func TestFolderOptions_SharedDriveRootPath(t *testing.T) {
+	fd := newFakeDrive(t)
+	fd.add(&fakeFile{ID: "shared-1", Name: "Engineering", MimeType: "application/vnd.google-apps.folder"})
+	backups := fd.add(&fakeFile{Name: "Backups", MimeType: "application/vnd.google-apps.folder", Parents: []string{"shared-1"}})
+	service := fd.service(FolderOptions{SharedDriveID: "shared-1", RootFolderPath: "Backups/Databases"})
+
+	rootID, err := service.RootFolderID()
+	assert.NoError(t, err)
+
+	// The existing folder is reused and the missing one is created inside it
+	databases := fd.find("Databases")
+	assert.Len(t, databases, 1)
+	assert.Equal(t, databases[0].ID, rootID)
+	assert.Equal(t, []string{backups.ID}, databases[0].Parents)
+
+	// Backup folders are created below the root folder
+	folder, err := service.GetOrCreateFolder("DB Backups - orders")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{rootID}, fd.find("DB Backups - orders")[0].Parents)
+
+	dir := t.TempDir()
+	dump := filepath.Join(dir, "orders.sql")
+	assert.NoError(t, os.WriteFile(dump, []byte("CREATE TABLE orders (id INT);"), 0644))
+	result, err := service.UploadFile(dump, folder.Id)
+	assert.NoError(t, err)
+	assert.NoError(t, service.DeleteFile(result.FileID))
+
+	// The root path is resolved once
+	_, err = service.RootFolderID()
+	assert.NoError(t, err)
+	assert.Len(t, fd.find("Databases"), 1)
+
+	for _, query := range fd.queries(http.MethodGet) {
+		assert.Equal(t, "true", query.Get("supportsAllDrives"))
+		assert.Equal(t, "true", query.Get("includeItemsFromAllDrives"))
+		assert.Equal(t, "drive", query.Get("corpora"))
+		assert.Equal(t, "shared-1", query.Get("driveId"))
+	}
+	for _, query := range append(fd.queries(http.MethodPost), fd.queries(http.MethodDelete)...) {
+		assert.Equal(t, "true", query.Get("supportsAllDrives"))
+	}
+}
+
+func TestFolderOptions_RootFolderID(t *testing.T) {
+	fd := newFakeDrive(t)
+	fd.add(&fakeFile{ID: "folder-1", Name: "Backups", MimeType: "application/vnd.google-apps.folder"})
+	fd.add(&fakeFile{ID: "folder-2", Name: "Other", MimeType: "application/vnd.google-apps.folder"})
+	service := fd.service(FolderOptions{RootFolderID: "folder-1"})
+
+	rootID, err := service.RootFolderID()
+	assert.NoError(t, err)
+	assert.Equal(t, "folder-1", rootID)
+
+	_, err = service.CreateFolder("DB Backups - orders")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"folder-1"}, fd.find("DB Backups - orders")[0].Parents)
+
+	// An explicit parent still wins
+	_, err = service.CreateFolder("nested", "folder-2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"folder-2"}, fd.find("nested")[0].Parents)
+}
+
+func TestCheckRootAccess(t *testing.T) {
+	fd := newFakeDrive(t)
+	fd.add(&fakeFile{ID: "folder-1", Name: "Backups", MimeType: "application/vnd.google-apps.folder"})
+
+	assert.NoError(t, fd.service(FolderOptions{}).CheckRootAccess())
+	assert.NoError(t, fd.service(FolderOptions{RootFolderID: "folder-1"}).CheckRootAccess())
+
+	// Under drive.file a folder shared with the app looks missing
+	err := fd.service(FolderOptions{RootFolderID: "shared-with-me"}).CheckRootAccess()
+	assert.ErrorIs(t, err, ErrRootNotAccessible)
+	assert.ErrorContains(t, err, "set FullDriveAccess")
+	err = fd.service(FolderOptions{SharedDriveID: "shared-1", FullDriveAccess: true}).CheckRootAccess()
+	assert.ErrorIs(t, err, ErrRootNotAccessible)
+	assert.ErrorContains(t, err, "'shared-1' does not exist")
+}
+
+func TestFolderOptions_Scope(t *testing.T) {
+	assert.Equal(t, drive.DriveFileScope, FolderOptions{}.Scope())
+	assert.Equal(t, drive.DriveScope, FolderOptions{FullDriveAccess: true}.Scope())
+}
+
+func TestFolderOptions_MyDriveRoot(t *testing.T) {
+	fd := newFakeDrive(t)
+	service := fd.service(FolderOptions{})
+
+	rootID, err := service.RootFolderID()
+	assert.NoError(t, err)
+	assert.Empty(t, rootID)
+
+	_, err = service.CreateFolder("DB Backups - orders")
+	assert.NoError(t, err)
+	assert.Empty(t, fd.find("DB Backups - orders")[0].Parents)
+	assert.Empty(t, fd.queries(http.MethodGet), "no lookups without a root path")
+}
+
+func TestExpandPathTemplate(t *testing.T) {
+	at := time.Date(2026, time.March, 7, 14, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		template string
+		config   string
+		expected []string
+		err      string
+	}{
+		{template: "", config: "orders", expected: []string{"DB Backups - orders"}},
+		{template: "{config}/{yyyy}/{mm}/{dd}", config: "orders", expected: []string{"orders", "2026", "03", "07"}},
+		{template: "/backups//{config}/{yyyy}-{mm}/{hh}/", config: "eu/orders", expected: []string{"backups", "eu-orders", "2026-03", "14"}},
+		{template: "{config}/{week}", config: "orders", err: "unknown placeholder {week}"},
+		{template: " / ", config: "orders", err: "has no folder names"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			names, err := ExpandPathTemplate(tc.template, tc.config, at)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestBackupFolderID_DatePartitioned(t *testing.T) {
+	fd := newFakeDrive(t)
+	fd.add(&fakeFile{ID: "root-1", Name: "Backups", MimeType: "application/vnd.google-apps.folder"})
+	service := fd.service(FolderOptions{RootFolderID: "root-1", PathTemplate: "{config}/{yyyy}/{mm}/{dd}"})
+	day := time.Date(2026, time.March, 7, 2, 0, 0, 0, time.UTC)
+
+	folderID, err := service.BackupFolderID("orders", day)
+	assert.NoError(t, err)
+	dayFolder := fd.find("07")
+	assert.Len(t, dayFolder, 1)
+	assert.Equal(t, dayFolder[0].ID, folderID)
+	assert.Equal(t, []string{fd.find("03")[0].ID}, dayFolder[0].Parents)
+	assert.Equal(t, []string{"root-1"}, fd.find("orders")[0].Parents)
+	lookups := len(fd.queries(http.MethodGet))
+	assert.Equal(t, 4, lookups, "one lookup per level")
+
+	// The same day is served from the cache
+	again, err := service.BackupFolderID("orders", day.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, folderID, again)
+	assert.Len(t, fd.queries(http.MethodGet), lookups)
+
+	// The next day only resolves the new level
+	next, err := service.BackupFolderID("orders", day.Add(24*time.Hour))
+	assert.NoError(t, err)
+	assert.NotEqual(t, folderID, next)
+	assert.Len(t, fd.queries(http.MethodGet), lookups+1)
+	assert.Len(t, fd.find("orders"), 1)
+	assert.Len(t, fd.find("03"), 1)
+
+	// Only the current partition of each config stays cached
+	_, err = service.BackupFolderID("users", day)
+	assert.NoError(t, err)
+	assert.Len(t, service.folderIDs, 8)
+	_, err = service.BackupFolderID("orders", day.AddDate(0, 1, 0))
+	assert.NoError(t, err)
+	assert.Len(t, service.folderIDs, 8)
+}
+
+func TestBackupFolderID_Concurrent(t *testing.T) {
+	fd := newFakeDrive(t)
+	service := fd.service(FolderOptions{PathTemplate: "{yyyy}/{config}"})
+	day := time.Date(2026, time.March, 7, 2, 0, 0, 0, time.UTC)
+
+	// Backups starting together share the lookup of a folder instead of each creating it
+	var wg sync.WaitGroup
+	for _, config := range []string{"orders", "orders", "users", "users", "billing"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.BackupFolderID(config, day)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, fd.find("2026"), 1)
+	assert.Len(t, fd.find("orders"), 1)
+	assert.Len(t, fd.find("users"), 1)
+}
+
+func TestDriveClient_Reused(t *testing.T) {
+	fd := newFakeDrive(t)
+	service := fd.service(FolderOptions{})
+	authService := service.authService.(*MockAuthService)
+
+	for i := 0; i < 3; i++ {
+		_, err := service.GetOrCreateFolder("DB Backups - orders")
+		assert.NoError(t, err)
+	}
+
+	client, err := service.driveClient()
+	assert.NoError(t, err)
+	again, err := service.driveClient()
+	assert.NoError(t, err)
+	assert.Same(t, client, again)
+	authService.AssertNumberOfCalls(t, "GetClient", 1)
+	assert.Len(t, fd.find("DB Backups - orders"), 1)
+}
+
+// tokenSourceAuth is an auth service handing out its own token source
+type tokenSourceAuth struct {
+	MockAuthService
+	tokens int
+}
+
+func (a *tokenSourceAuth) TokenSource() oauth2.TokenSource {
+	return a
+}
+
+func (a *tokenSourceAuth) Token() (*oauth2.Token, error) {
+	a.tokens++
+	return &oauth2.Token{AccessToken: fmt.Sprintf("token-%d", a.tokens), Expiry: time.Now().Add(time.Hour)}, nil
+}
+
+func TestDriveClient_UsesProvidedTokenSource(t *testing.T) {
+	fd := newFakeDrive(t)
+	authService := &tokenSourceAuth{}
+	service := NewService(authService)
+	service.endpoint = fd.server.URL + "/"
+
+	_, err := service.CreateFolder("DB Backups - orders")
+	assert.NoError(t, err)
+	assert.Positive(t, authService.tokens)
+	authService.AssertNotCalled(t, "GetClient")
+}
+
+// swappableTokenAuth is an auth service whose token is replaced, e.g. by re-authorization
+type swappableTokenAuth struct {
+	MockAuthService
+	mu          sync.Mutex
+	accessToken string
+}
+
+func (a *swappableTokenAuth) TokenSource() oauth2.TokenSource {
+	return a
+}
+
+func (a *swappableTokenAuth) Token() (*oauth2.Token, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return &oauth2.Token{AccessToken: a.accessToken, Expiry: time.Now().Add(time.Hour)}, nil
+}
+
+func TestDriveClient_PicksUpReplacedToken(t *testing.T) {
+	fd := newFakeDrive(t)
+	authService := &swappableTokenAuth{accessToken: "revoked"}
+	service := NewService(authService)
+	service.endpoint = fd.server.URL + "/"
+
+	_, err := service.CreateFolder("DB Backups - orders")
+	assert.NoError(t, err)
+
+	authService.mu.Lock()
+	authService.accessToken = "reauthorized"
+	authService.mu.Unlock()
+
+	_, err = service.CreateFolder("DB Backups - users")
+	assert.NoError(t, err)
+
+	fd.mu.Lock()
+	last := fd.requests[len(fd.requests)-1]
+	fd.mu.Unlock()
+	assert.Equal(t, "Bearer reauthorized", last.Header.Get("Authorization"))
+}
+
+func TestGetOrCreateFolder_LookupFailure(t *testing.T) {
+	fd := newFakeDrive(t)
+	service := fd.service(FolderOptions{})
+	fd.add(&fakeFile{Name: "DB Backups - orders", MimeType: "application/vnd.google-apps.folder"})
+
+	// A failing search must not be mistaken for a missing folder
+	fd.listErr = http.StatusServiceUnavailable
+	_, err := service.GetOrCreateFolder("DB Backups - orders")
+	assert.Error(t, err)
+	assert.Len(t, fd.find("DB Backups - orders"), 1)
+
+	fd.listErr = 0
+	folder, err := service.GetOrCreateFolder("DB Backups - orders")
+	assert.NoError(t, err)
+	assert.Equal(t, fd.find("DB Backups - orders")[0].ID, folder.Id)
+}
+
+func TestFindFolder_EscapesQuery(t *testing.T) {
+	fd := newFakeDrive(t)
+	service := fd.service(FolderOptions{})
+	existing := fd.add(&fakeFile{Name: `Bob's \ backups`, MimeType: "application/vnd.google-apps.folder"})
+
+	folder, err := service.FindFolder(`Bob's \ backups`)
+	assert.NoError(t, err)
+	assert.Equal(t, existing.ID, folder.Id)
+	assert.Contains(t, fd.queries(http.MethodGet)[0].Get("q"), `name='Bob\'s \\ backups'`)
+}
+
+func TestGetOrCreateFolder_MyDriveRootOnly(t *testing.T) {
+	fd := newFakeDrive(t)
+	service := fd.service(FolderOptions{})
+	nested := fd.add(&fakeFile{Name: "DB Backups - orders", MimeType: "application/vnd.google-apps.folder", Parents: []string{"someone-elses-folder"}})
+
+	// A same-named folder outside the root is not the backup folder
+	folder, err := service.GetOrCreateFolder("DB Backups - orders")
+	assert.NoError(t, err)
+	assert.NotEqual(t, nested.ID, folder.Id)
+	assert.Contains(t, fd.queries(http.MethodGet)[0].Get("q"), "'root' in parents")
+	assert.Len(t, fd.find("DB Backups - orders"), 2)
+}
+
+func TestUploadBackup_RecreatesDeletedFolder(t *testing.T) {
+	fd := newFakeDrive(t)
+	service := fd.service(FolderOptions{PathTemplate: "{config}/{yyyy}"})
+	dump := filepath.Join(t.TempDir(), "orders.sql")
+	assert.NoError(t, os.WriteFile(dump, []byte("CREATE TABLE orders (id INT);"), 0644))
+	at := time.Date(2026, time.March, 7, 2, 0, 0, 0, time.UTC)
+
+	_, err := service.UploadBackup(dump, "orders", at)
+	assert.NoError(t, err)
+
+	// The year folder is deleted in the Drive UI while its ID is cached
+	deleted := fd.find("2026")[0]
+	fd.remove(deleted.ID)
+
+	result, err := service.UploadBackup(dump, "orders", at)
+	assert.NoError(t, err)
+	years := fd.find("2026")
+	assert.Len(t, years, 1)
+	assert.NotEqual(t, deleted.ID, years[0].ID)
+	assert.Equal(t, []string{years[0].ID}, fd.find("orders.sql")[1].Parents)
+	assert.NotEmpty(t, result.FileID)
+}
+
+func TestDeleteFile_ForgetsCachedFolder(t *testing.T) {
+	fd := newFakeDrive(t)
+	service := fd.service(FolderOptions{})
+	at := time.Now()
+
+	folderID, err := service.BackupFolderID("orders", at)
+	assert.NoError(t, err)
+	assert.NoError(t, service.DeleteFile(folderID))
+
+	again, err := service.BackupFolderID("orders", at)
+	assert.NoError(t, err)
+	assert.NotEqual(t, folderID, again)
+}
+
+func TestListBackups_Paginated(t *testing.T) {
+	fd := newFakeDrive(t)
+	service := fd.service(FolderOptions{PathTemplate: "{config}/{yyyy}/{mm}"})
+	dump := filepath.Join(t.TempDir(), "orders.sql")
+	assert.NoError(t, os.WriteFile(dump, []byte("CREATE TABLE orders (id INT);"), 0644))
+
+	// Backups spread over nested date folders, plus one of another config
+	start := time.Date(2026, time.January, 15, 2, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		_, err := service.UploadBackup(dump, "orders", start.AddDate(0, i, 0))
+		assert.NoError(t, err)
+	}
+	_, err := service.UploadBackup(dump, "users", start)
+	assert.NoError(t, err)
+
+	page, err := service.ListBackups("orders", 2, "")
+	assert.NoError(t, err)
+	assert.Len(t, page.Files, 2)
+	assert.NotEmpty(t, page.NextPageToken)
+	assert.Equal(t, int64(len("CREATE TABLE orders (id INT);")), page.Files[0].Size)
+	assert.GreaterOrEqual(t, page.Files[0].CreatedTime, page.Files[1].CreatedTime, "newest first")
+
+	next, err := service.ListBackups("orders", 2, page.NextPageToken)
+	assert.NoError(t, err)
+	assert.Len(t, next.Files, 1)
+	assert.Empty(t, next.NextPageToken)
+}
+
+func TestListBackups_LegacyConfigFolder(t *testing.T) {
+	fd := newFakeDrive(t)
+	service := fd.service(FolderOptions{})
+	folder := fd.add(&fakeFile{Name: "DB Backups - it's orders", MimeType: "application/vnd.google-apps.folder"})
+	legacy := fd.add(&fakeFile{Name: "orders_legacy.sql", Parents: []string{folder.ID}})
+	fd.add(&fakeFile{Name: "unrelated.sql"})
+
+	list, err := service.ListBackups("it's orders", 0, "")
+	assert.NoError(t, err)
+	assert.Len(t, list.Files, 1)
+	assert.Equal(t, legacy.ID, list.Files[0].ID)
+	assert.Empty(t, fd.find("DB Backups - orders"), "listing creates no folders")
+}
+
+func TestDownloadToFile_Resume(t *testing.T) {
+	fd := newFakeDrive(t)
+	service := fd.service(FolderOptions{})
+	content := []byte(strings.Repeat("INSERT INTO orders VALUES (1);\n", 100))
+	dump := filepath.Join(t.TempDir(), "orders.sql")
+	assert.NoError(t, os.WriteFile(dump, content, 0644))
+	uploaded, err := service.UploadFile(dump)
+	assert.NoError(t, err)
+
+	// An earlier attempt stopped half way
+	target := filepath.Join(t.TempDir(), "restore.sql")
+	assert.NoError(t, os.WriteFile(target+".part", content[:1000], 0600))
+
+	size, err := service.DownloadToFile(context.Background(), uploaded.FileID, target)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), size)
+
+	downloaded, err := os.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+	assert.NoFileExists(t, target+".part")
+
+	assert.Equal(t, []string{"bytes=1000-"}, fd.mediaRanges(), "only the missing part is downloaded")
+}
+
+func TestDownloadToFile_ChecksumMismatch(t *testing.T) {
+	fd := newFakeDrive(t)
+	service := fd.service(FolderOptions{})
+	dump := filepath.Join(t.TempDir(), "orders.sql")
+	assert.NoError(t, os.WriteFile(dump, []byte("CREATE TABLE orders (id INT);"), 0644))
+	uploaded, err := service.UploadFile(dump)
+	assert.NoError(t, err)
+
+	// A stale partial download of different content
+	target := filepath.Join(t.TempDir(), "restore.sql")
+	assert.NoError(t, os.WriteFile(target+".part", []byte("DROP"), 0600))
+
+	_, err = service.DownloadToFile(context.Background(), uploaded.FileID, target)
+	assert.ErrorContains(t, err, "checksum mismatch")
+	assert.NoFileExists(t, target)
+	assert.NoFileExists(t, target+".part")
+}
+
+func TestDownload_Stream(t *testing.T) {
+	fd := newFakeDrive(t)
+	service := fd.service(FolderOptions{})
+	dump := filepath.Join(t.TempDir(), "orders.sql")
+	assert.NoError(t, os.WriteFile(dump, []byte("CREATE TABLE orders (id INT);"), 0644))
+	uploaded, err := service.UploadFile(dump)
+	assert.NoError(t, err)
+
+	var buf strings.Builder
+	written, err := service.Download(context.Background(), uploaded.FileID, 13, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), written)
+	assert.Equal(t, "orders (id INT);", buf.String())
+
+	_, err = service.Download(context.Background(), "missing", 0, &buf)
+	assert.ErrorContains(t, err, "failed to download file")
+}
+
+func TestGetStorageQuota(t *testing.T) {
+	fd := newFakeDrive(t)
+	service := fd.service(FolderOptions{})
+
+	fd.quota = map[string]string{"limit": "16106127360", "usage": "16000000000", "usageInDrive": "15000000000"}
+	quota, err := service.GetStorageQuota()
+	assert.NoError(t, err)
+	assert.False(t, quota.Unlimited())
+	assert.Equal(t, int64(106127360), quota.Free())
+	assert.Equal(t, int64(15000000000), quota.UsageInDrive)
+
+	// Workspace accounts with pooled storage report no limit
+	fd.quota = map[string]string{"usage": "16000000000"}
+	quota, err = service.GetStorageQuota()
+	assert.NoError(t, err)
+	assert.True(t, quota.Unlimited())
+	assert.Zero(t, quota.Free())
+
+	// Backups on a shared drive do not use the account's quota
+	fd.quota = map[string]string{"limit": "16106127360", "usage": "16000000000"}
+	quota, err = fd.service(FolderOptions{SharedDriveID: "shared-1"}).GetStorageQuota()
+	assert.NoError(t, err)
+	assert.True(t, quota.Unlimited())
+}
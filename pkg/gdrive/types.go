@@ -8,3 +8,9 @@ type File struct {
 	CreatedTime string `json:"created_time"`
 	WebViewLink string `json:"web_view_link"`
 }
+
+// FileList is one page of files; NextPageToken is empty on the last page
+type FileList struct {
+	Files         []File `json:"files"`
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
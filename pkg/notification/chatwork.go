@@ -55,7 +55,7 @@ func (c *ChatworkNotifier) Send(message *Message) error {
 
 	// Check response
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("chatwork API returned status %d", resp.StatusCode)
+		return newStatusError(resp, "chatwork API returned status %d", resp.StatusCode)
 	}
 
 	return nil
@@ -0,0 +1,90 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// DeliveryError is returned by notifiers when a channel API rejects a message
+type DeliveryError struct {
+	StatusCode int           // HTTP status returned by the API
+	RetryAfter time.Duration // how long the API asked to wait before retrying, 0 when it did not say
+	Message    string
+}
+
+// Error returns the error message
+func (e *DeliveryError) Error() string {
+	return e.Message
+}
+
+// Temporary reports whether sending the message again later may succeed:
+// rate limits, timeouts and server errors are temporary, other rejections are not
+func (e *DeliveryError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout || e.StatusCode >= 500
+}
+
+// newStatusError creates the error of a rejected API request, keeping its Retry-After header
+func newStatusError(resp *http.Response, format string, args ...interface{}) *DeliveryError {
+	return &DeliveryError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		Message:    fmt.Sprintf(format, args...),
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// isPermanentError reports whether retrying a failed send is pointless, e.g. a deleted webhook
+// or an SMTP server refusing the recipients; network errors are always retried
+func isPermanentError(err error) bool {
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		return !deliveryErr.Temporary()
+	}
+
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code >= 500
+	}
+
+	return false
+}
+
+// errorStatusCode returns the HTTP status of a delivery error, 0 for other errors
+func errorStatusCode(err error) int {
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		return deliveryErr.StatusCode
+	}
+	return 0
+}
+
+// errorRetryAfter returns the wait a delivery error asked for, 0 for other errors
+func errorRetryAfter(err error) time.Duration {
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		return deliveryErr.RetryAfter
+	}
+	return 0
+}
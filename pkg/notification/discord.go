@@ -82,7 +82,7 @@ func (d *DiscordNotifier) Send(message *Message) error {
 
 	// Check response
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("discord webhook returned status %d", resp.StatusCode)
+		return newStatusError(resp, "discord webhook returned status %d", resp.StatusCode)
 	}
 
 	return nil
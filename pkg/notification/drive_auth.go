@@ -0,0 +1,62 @@
+package notification
+
+import (
+	"fmt"
+	"time"
+)
+
+// CreateDriveAuthMessage creates a warning that a Drive connection needs to be authorized again
+func CreateDriveAuthMessage(data *DriveAuthNotificationData) *Message {
+	fields := map[string]interface{}{
+		"Connection":           data.Connection,
+		"Consecutive Failures": data.ConsecutiveFailures,
+		"Error":                data.ErrorMessage,
+	}
+
+	if !data.NextBackupAt.IsZero() {
+		fields["Next Backup"] = data.NextBackupAt.Format(time.RFC3339)
+	}
+
+	text := fmt.Sprintf("Google Drive authorization for connection '%s' is failing, upcoming backups cannot be uploaded.", data.Connection)
+	if data.AuthURL != "" {
+		fields["Authorize"] = data.AuthURL
+		text += " Authorize it again with the link below."
+	} else {
+		text += " Check the service account credentials."
+	}
+
+	return &Message{
+		Type:      MessageTypeWarning,
+		Event:     EventDriveAuthFailing,
+		Title:     "Drive authorization needs attention",
+		Text:      text,
+		Fields:    fields,
+		Timestamp: data.CheckedAt,
+	}
+}
+
+// CreateDriveAuthRecoveredMessage creates a message that a failing Drive connection is authorized again
+func CreateDriveAuthRecoveredMessage(data *DriveAuthNotificationData) *Message {
+	return &Message{
+		Type:  MessageTypeSuccess,
+		Event: EventDriveAuthRecovered,
+		Title: "Drive authorization recovered",
+		Text:  fmt.Sprintf("Google Drive authorization for connection '%s' works again after %d failed checks.", data.Connection, data.ConsecutiveFailures),
+		Fields: map[string]interface{}{
+			"Connection":           data.Connection,
+			"Consecutive Failures": data.ConsecutiveFailures,
+		},
+		Timestamp: data.CheckedAt,
+	}
+}
+
+// SendDriveAuthNotification warns every enabled config that notifies on errors
+func (m *Manager) SendDriveAuthNotification(data *DriveAuthNotificationData) []NotificationResult {
+	return m.sendToErrorConfigs(CreateDriveAuthMessage(data), "Drive authorization")
+}
+
+// SendDriveAuthRecoveredNotification tells every enabled config that notifies on errors that an earlier
+// authorization warning is over; incident channels resolve the incident it opened
+func (m *Manager) SendDriveAuthRecoveredNotification(data *DriveAuthNotificationData) []NotificationResult {
+	return m.sendToErrorConfigs(CreateDriveAuthRecoveredMessage(data), "Drive authorization recovery")
+}
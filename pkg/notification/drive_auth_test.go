@@ -0,0 +1,36 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateDriveAuthMessage(t *testing.T) {
+	checkedAt := time.Now()
+	data := &DriveAuthNotificationData{
+		Connection:          "client-a",
+		ErrorMessage:        "oauth2: \"invalid_grant\"",
+		ConsecutiveFailures: 2,
+		AuthURL:             "https://accounts.google.com/o/oauth2/auth?state=abc",
+		NextBackupAt:        checkedAt.Add(time.Hour),
+		CheckedAt:           checkedAt,
+	}
+
+	message := CreateDriveAuthMessage(data)
+	assert.Equal(t, MessageTypeWarning, message.Type)
+	assert.Equal(t, "Drive authorization needs attention", message.Title)
+	assert.Contains(t, message.Text, "client-a")
+	assert.Equal(t, data.AuthURL, message.Fields["Authorize"])
+	assert.Equal(t, 2, message.Fields["Consecutive Failures"])
+	assert.Contains(t, message.Fields, "Next Backup")
+
+	// Service account connections have no link to follow
+	data.AuthURL = ""
+	data.NextBackupAt = time.Time{}
+	message = CreateDriveAuthMessage(data)
+	assert.NotContains(t, message.Fields, "Authorize")
+	assert.NotContains(t, message.Fields, "Next Backup")
+	assert.Contains(t, message.Text, "service account")
+}
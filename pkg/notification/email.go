@@ -0,0 +1,318 @@
+package notification
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Email connection security modes
+const (
+	EmailSecurityStartTLS = "starttls" // plain connection upgraded with STARTTLS, port 587 by default
+	EmailSecurityTLS      = "tls"      // implicit TLS, port 465 by default
+	EmailSecurityNone     = "none"     // no encryption, port 25 by default; for local relays only
+)
+
+// EmailNotifier implements the Notifier interface for email over SMTP
+type EmailNotifier struct {
+	config  EmailConfig
+	rootCAs *x509.CertPool // trusted server certificates, the system pool when nil
+}
+
+// NewEmailNotifier creates a new email notifier
+func NewEmailNotifier(config EmailConfig) *EmailNotifier {
+	return &EmailNotifier{
+		config: config,
+	}
+}
+
+// Send sends a notification message by email to every recipient
+func (e *EmailNotifier) Send(message *Message) error {
+	body, err := e.buildMessage(message)
+	if err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+
+	client, err := e.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if e.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	from, err := mail.ParseAddress(e.config.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	for _, recipient := range e.config.To {
+		to, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return fmt.Errorf("invalid recipient %q: %w", recipient, err)
+		}
+		if err := client.Rcpt(to.Address); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s failed: %w", to.Address, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := writer.Write(body); err != nil {
+		writer.Close()
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected the email: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// dial connects to the SMTP server with the configured security
+func (e *EmailNotifier) dial() (*smtp.Client, error) {
+	security := e.security()
+	addr := net.JoinHostPort(e.config.Host, strconv.Itoa(e.port()))
+	tlsConfig := &tls.Config{ServerName: e.config.Host, RootCAs: e.rootCAs}
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	var conn net.Conn
+	var err error
+	if security == EmailSecurityTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(time.Minute))
+
+	client, err := smtp.NewClient(conn, e.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+
+	if security == EmailSecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+// security returns the configured security mode, STARTTLS by default
+func (e *EmailNotifier) security() string {
+	if e.config.Security == "" {
+		return EmailSecurityStartTLS
+	}
+	return strings.ToLower(e.config.Security)
+}
+
+// port returns the configured port or the default one of the security mode
+func (e *EmailNotifier) port() int {
+	if e.config.Port != 0 {
+		return e.config.Port
+	}
+	switch e.security() {
+	case EmailSecurityTLS:
+		return 465
+	case EmailSecurityNone:
+		return 25
+	default:
+		return 587
+	}
+}
+
+// ValidateConfig validates the email configuration
+func (e *EmailNotifier) ValidateConfig(config map[string]interface{}) error {
+	jsonData, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	var emailConfig EmailConfig
+	if err := json.Unmarshal(jsonData, &emailConfig); err != nil {
+		return fmt.Errorf("invalid email config: %w", err)
+	}
+
+	if emailConfig.Host == "" {
+		return fmt.Errorf("host is required for email")
+	}
+	if _, err := mail.ParseAddress(emailConfig.From); err != nil {
+		return fmt.Errorf("from must be a valid email address")
+	}
+	if len(emailConfig.To) == 0 {
+		return fmt.Errorf("at least one recipient is required for email")
+	}
+	for _, recipient := range emailConfig.To {
+		if _, err := mail.ParseAddress(recipient); err != nil {
+			return fmt.Errorf("invalid recipient %q", recipient)
+		}
+	}
+
+	switch strings.ToLower(emailConfig.Security) {
+	case "", EmailSecurityStartTLS, EmailSecurityTLS, EmailSecurityNone:
+	default:
+		return fmt.Errorf("security must be %s, %s or %s", EmailSecurityStartTLS, EmailSecurityTLS, EmailSecurityNone)
+	}
+
+	return nil
+}
+
+// GetChannelType returns the notification channel type
+func (e *EmailNotifier) GetChannelType() NotificationChannel {
+	return ChannelEmail
+}
+
+// emailField is one row of the fields table
+type emailField struct {
+	Name  string
+	Value string
+}
+
+var emailHTMLTemplate = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; color: #333;">
+<h2 style="color: {{.Color}};">{{.Title}}</h2>
+{{if .Text}}<p>{{.Text}}</p>{{end}}
+{{if .Fields}}<table style="border-collapse: collapse;">
+{{range .Fields}}<tr><th style="text-align: left; padding: 4px 12px 4px 0;">{{.Name}}</th><td style="padding: 4px 0;">{{.Value}}</td></tr>
+{{end}}</table>{{end}}
+<p style="color: #888; font-size: 12px;">{{.Time}} · Database Backup Service</p>
+</body>
+</html>
+`))
+
+// buildMessage renders the message as a multipart/alternative email with text and HTML bodies
+func (e *EmailNotifier) buildMessage(message *Message) ([]byte, error) {
+	fields := e.sortedFields(message)
+	timestamp := message.Timestamp.Format("2006-01-02 15:04:05 MST")
+
+	var text strings.Builder
+	text.WriteString(message.Title + "\n\n")
+	if message.Text != "" {
+		text.WriteString(message.Text + "\n\n")
+	}
+	for _, field := range fields {
+		text.WriteString(fmt.Sprintf("%s: %s\n", field.Name, field.Value))
+	}
+	text.WriteString("\n" + timestamp + " - Database Backup Service\n")
+
+	var html bytes.Buffer
+	err := emailHTMLTemplate.Execute(&html, map[string]interface{}{
+		"Title":  message.Title,
+		"Text":   message.Text,
+		"Fields": fields,
+		"Color":  e.getColorForType(message.Type),
+		"Time":   timestamp,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	parts := multipart.NewWriter(&buffer)
+
+	subject := message.Title
+	if e.config.SubjectPrefix != "" {
+		subject = e.config.SubjectPrefix + " " + subject
+	}
+
+	headers := []string{
+		"From: " + e.config.From,
+		"To: " + strings.Join(e.config.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + message.Timestamp.Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + parts.Boundary(),
+	}
+	buffer.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	for _, body := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text.String()},
+		{"text/html; charset=utf-8", html.String()},
+	} {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {body.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := quotedprintable.NewWriter(part)
+		if _, err := encoder.Write([]byte(body.content)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// sortedFields returns the message fields in a stable order, followed by the configuration name
+func (e *EmailNotifier) sortedFields(message *Message) []emailField {
+	fields := make([]emailField, 0, len(message.Fields)+1)
+	for name, value := range message.Fields {
+		fields = append(fields, emailField{Name: name, Value: fmt.Sprintf("%v", value)})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+
+	if message.ConfigName != "" {
+		fields = append(fields, emailField{Name: "Configuration", Value: message.ConfigName})
+	}
+	return fields
+}
+
+// getColorForType returns an appropriate heading color for the message type
+func (e *EmailNotifier) getColorForType(msgType MessageType) string {
+	switch msgType {
+	case MessageTypeSuccess:
+		return "#2eb886" // Green
+	case MessageTypeError:
+		return "#d00000" // Red
+	case MessageTypeWarning:
+		return "#daa038" // Yellow
+	case MessageTypeInfo:
+		return "#439fe0" // Blue
+	default:
+		return "#808080" // Gray
+	}
+}
@@ -0,0 +1,328 @@
+package notification
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTP is a local SMTP stand-in accepting one session at a time
+type fakeSMTP struct {
+	listener  net.Listener
+	tlsConfig *tls.Config // offered with STARTTLS when set and the connection is not already TLS
+	rootCAs   *x509.CertPool
+
+	mu       sync.Mutex
+	auth     string
+	from     string
+	to       []string
+	data     string
+	startTLS bool
+}
+
+// newTestCertificate returns a self-signed certificate for 127.0.0.1 and a pool trusting it
+func newTestCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+// newFakeSMTP starts a stand-in speaking the given security mode
+func newFakeSMTP(t *testing.T, security string) *fakeSMTP {
+	cert, pool := newTestCertificate(t)
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	var listener net.Listener
+	var err error
+	if security == EmailSecurityTLS {
+		listener, err = tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	} else {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeSMTP{listener: listener, rootCAs: pool}
+	if security == EmailSecurityStartTLS {
+		server.tlsConfig = tlsConfig
+	}
+	go server.serve()
+	return server
+}
+
+func (s *fakeSMTP) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTP) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.session(conn)
+	}
+}
+
+func (s *fakeSMTP) session(conn net.Conn) {
+	defer func() { conn.Close() }()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	_, secure := conn.(*tls.Conn)
+
+	reply("220 fake.smtp ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0])
+
+		s.mu.Lock()
+		switch verb {
+		case "EHLO":
+			if s.tlsConfig != nil && !secure {
+				reply("250-fake.smtp")
+				reply("250 STARTTLS")
+			} else {
+				reply("250-fake.smtp")
+				reply("250 AUTH PLAIN")
+			}
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if tlsConn.Handshake() != nil {
+				s.mu.Unlock()
+				return
+			}
+			conn, reader, secure = tlsConn, bufio.NewReader(tlsConn), true
+			s.startTLS = true
+		case "AUTH":
+			decoded, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(command, "AUTH PLAIN "))
+			s.auth = string(decoded)
+			reply("235 authenticated")
+		case "MAIL":
+			s.from = command
+			reply("250 ok")
+		case "RCPT":
+			s.to = append(s.to, command)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.data = data.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			s.mu.Unlock()
+			return
+		default:
+			reply("502 unknown command")
+		}
+		s.mu.Unlock()
+	}
+}
+
+// received returns the parsed email of the last session
+func (s *fakeSMTP) received(t *testing.T) *mail.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	message, err := mail.ReadMessage(strings.NewReader(s.data))
+	assert.NoError(t, err)
+	return message
+}
+
+// readEmailParts returns the decoded bodies of a multipart/alternative email by content type
+func readEmailParts(t *testing.T, message *mail.Message) map[string]string {
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	bodies := make(map[string]string)
+	reader := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		body, err := io.ReadAll(part)
+		assert.NoError(t, err)
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		bodies[contentType] = string(body)
+	}
+	return bodies
+}
+
+func newTestEmailNotifier(server *fakeSMTP, security string) *EmailNotifier {
+	notifier := NewEmailNotifier(EmailConfig{
+		Host:          "127.0.0.1",
+		Port:          server.port(),
+		Security:      security,
+		Username:      "backup",
+		Password:      "secret",
+		From:          "Lazy Backups <backups@example.com>",
+		To:            []string{"oncall@example.com", "Ops <ops@example.com>"},
+		SubjectPrefix: "[lazy]",
+	})
+	notifier.rootCAs = server.rootCAs
+	return notifier
+}
+
+func TestEmailNotifier_Send(t *testing.T) {
+	for _, security := range []string{EmailSecurityStartTLS, EmailSecurityTLS, EmailSecurityNone} {
+		t.Run(security, func(t *testing.T) {
+			server := newFakeSMTP(t, security)
+			notifier := newTestEmailNotifier(server, security)
+
+			err := notifier.Send(&Message{
+				Type:       MessageTypeError,
+				Title:      "Backup Failed: orders",
+				Text:       "Database backup failed for <orders>",
+				Fields:     map[string]interface{}{"Error": "connection refused", "Duration": "3s"},
+				Timestamp:  time.Now(),
+				ConfigName: "orders",
+			})
+			assert.NoError(t, err)
+
+			server.mu.Lock()
+			assert.Equal(t, security == EmailSecurityStartTLS, server.startTLS)
+			assert.Equal(t, "\x00backup\x00secret", server.auth)
+			assert.Equal(t, "MAIL FROM:<backups@example.com>", server.from)
+			assert.Equal(t, []string{"RCPT TO:<oncall@example.com>", "RCPT TO:<ops@example.com>"}, server.to)
+			server.mu.Unlock()
+
+			message := server.received(t)
+			assert.Equal(t, "[lazy] Backup Failed: orders", message.Header.Get("Subject"))
+			assert.Equal(t, "oncall@example.com, Ops <ops@example.com>", message.Header.Get("To"))
+
+			bodies := readEmailParts(t, message)
+			assert.Contains(t, bodies["text/plain"], "Duration: 3s\r\nError: connection refused\r\nConfiguration: orders\r\n")
+			assert.Contains(t, bodies["text/html"], "&lt;orders&gt;")
+			assert.Contains(t, bodies["text/html"], "<th style=\"text-align: left; padding: 4px 12px 4px 0;\">Error</th><td style=\"padding: 4px 0;\">connection refused</td>")
+			assert.Contains(t, bodies["text/html"], "#d00000")
+		})
+	}
+}
+
+func TestEmailNotifier_StartTLSRequired(t *testing.T) {
+	// A server without STARTTLS is refused rather than sending credentials in plaintext
+	server := newFakeSMTP(t, EmailSecurityNone)
+	notifier := newTestEmailNotifier(server, EmailSecurityStartTLS)
+
+	err := notifier.Send(&Message{Title: "Test", Timestamp: time.Now()})
+	assert.ErrorContains(t, err, "does not support STARTTLS")
+}
+
+func TestEmailNotifier_DefaultPort(t *testing.T) {
+	assert.Equal(t, 587, NewEmailNotifier(EmailConfig{}).port())
+	assert.Equal(t, 465, NewEmailNotifier(EmailConfig{Security: "TLS"}).port())
+	assert.Equal(t, 25, NewEmailNotifier(EmailConfig{Security: EmailSecurityNone}).port())
+	assert.Equal(t, 2525, NewEmailNotifier(EmailConfig{Port: 2525}).port())
+}
+
+func TestEmailNotifier_ValidateConfig(t *testing.T) {
+	notifier := &EmailNotifier{}
+
+	tests := []struct {
+		name        string
+		config      map[string]interface{}
+		expectError string
+	}{
+		{
+			name: "valid config",
+			config: map[string]interface{}{
+				"host": "smtp.example.com", "port": 587, "from": "backups@example.com",
+				"to": []interface{}{"oncall@example.com", "ops@example.com"},
+			},
+		},
+		{
+			name:        "missing host",
+			config:      map[string]interface{}{"from": "backups@example.com", "to": []string{"oncall@example.com"}},
+			expectError: "host is required",
+		},
+		{
+			name:        "invalid from",
+			config:      map[string]interface{}{"host": "smtp.example.com", "from": "backups", "to": []string{"oncall@example.com"}},
+			expectError: "from must be a valid email address",
+		},
+		{
+			name:        "no recipients",
+			config:      map[string]interface{}{"host": "smtp.example.com", "from": "backups@example.com"},
+			expectError: "at least one recipient",
+		},
+		{
+			name:        "invalid recipient",
+			config:      map[string]interface{}{"host": "smtp.example.com", "from": "backups@example.com", "to": []string{"oncall"}},
+			expectError: "invalid recipient \"oncall\"",
+		},
+		{
+			name: "unknown security",
+			config: map[string]interface{}{
+				"host": "smtp.example.com", "from": "backups@example.com", "to": []string{"oncall@example.com"}, "security": "ssl",
+			},
+			expectError: "security must be",
+		},
+		{
+			name:        "port is not a number",
+			config:      map[string]interface{}{"host": "smtp.example.com", "port": strconv.Itoa(587)},
+			expectError: "invalid email config",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := notifier.ValidateConfig(tt.config)
+			if tt.expectError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestEmailNotifier_GetChannelType(t *testing.T) {
+	assert.Equal(t, ChannelEmail, NewEmailNotifier(EmailConfig{}).GetChannelType())
+}
@@ -0,0 +1,366 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Incident API endpoints
+const (
+	pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	opsgenieAPIURL     = "https://api.opsgenie.com/v2/alerts"
+	opsgenieEUAPIURL   = "https://api.eu.opsgenie.com/v2/alerts"
+)
+
+// opsgenieMessageLimit is the longest alert message Opsgenie accepts
+const opsgenieMessageLimit = 130
+
+// isIncidentChannel reports whether a channel opens incidents that the next successful backup resolves
+func isIncidentChannel(channel string) bool {
+	return channel == string(ChannelPagerDuty) || channel == string(ChannelOpsgenie)
+}
+
+// incidentRecoveries maps the event that resolves an incident to the event that opened it
+var incidentRecoveries = map[string]string{
+	EventDriveAuthRecovered: EventDriveAuthFailing,
+}
+
+// isResolvableIncident reports whether a later message resolves the incident a message opens.
+// Backup messages are resolved by the next successful backup, a few other alerts by their recovery event.
+func isResolvableIncident(message *Message) bool {
+	if message.ConfigName != "" || message.Type == MessageTypeSuccess || message.Event == EventNotificationTest {
+		return true
+	}
+	for _, opened := range incidentRecoveries {
+		if message.Event == opened {
+			return true
+		}
+	}
+	return false
+}
+
+// incidentDedupKey identifies the incident of a message: backups are keyed by config name,
+// other alerts by event and connection, so a repeated failure updates the open incident
+func incidentDedupKey(message *Message) string {
+	if message.ConfigName != "" {
+		return "lazy/backup/" + message.ConfigName
+	}
+
+	event := message.Event
+	if opened, ok := incidentRecoveries[event]; ok {
+		event = opened
+	}
+	key := "lazy/" + event
+	if message.Event == "" {
+		key = "lazy/" + message.Title
+	}
+	if connection, ok := message.Fields["Connection"]; ok {
+		key += fmt.Sprintf("/%v", connection)
+	}
+	return key
+}
+
+// incidentAction tells whether a message opens or resolves an incident
+func incidentAction(message *Message) string {
+	if message.Type == MessageTypeSuccess {
+		return "resolve"
+	}
+	return "trigger"
+}
+
+// incidentDetails returns the message fields as strings
+func incidentDetails(message *Message) map[string]string {
+	details := make(map[string]string, len(message.Fields)+1)
+	for name, value := range message.Fields {
+		details[name] = fmt.Sprintf("%v", value)
+	}
+	if message.ConfigName != "" {
+		details["Configuration"] = message.ConfigName
+	}
+	return details
+}
+
+// postIncidentJSON posts an incident API request and checks for a 2xx answer
+func postIncidentJSON(endpoint string, body interface{}, headers map[string]string, service string) error {
+	// Marshal to JSON
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s payload: %w", service, err)
+	}
+
+	// Create HTTP request
+	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DB-Backup-GDrive/1.0")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	// Send request
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check response
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newStatusError(resp, "%s API returned status %d", strings.ToLower(service), resp.StatusCode)
+	}
+
+	return nil
+}
+
+// PagerDutyNotifier implements the Notifier interface for PagerDuty Events API v2.
+// Error and warning messages trigger an incident, success messages resolve it.
+type PagerDutyNotifier struct {
+	config   PagerDutyConfig
+	eventURL string
+}
+
+// NewPagerDutyNotifier creates a new PagerDuty notifier
+func NewPagerDutyNotifier(config PagerDutyConfig) *PagerDutyNotifier {
+	return &PagerDutyNotifier{
+		config:   config,
+		eventURL: pagerDutyEventsURL,
+	}
+}
+
+// PagerDutyEvent represents an Events API v2 request
+type PagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *PagerDutyPayload `json:"payload,omitempty"`
+	Links       []PagerDutyLink   `json:"links,omitempty"`
+}
+
+// PagerDutyPayload describes a triggered event
+type PagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// PagerDutyLink represents a link shown on the incident
+type PagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// Send triggers or resolves the PagerDuty incident of the message.
+// Test notifications trigger and immediately resolve, so nobody stays paged.
+func (p *PagerDutyNotifier) Send(message *Message) error {
+	if err := p.sendEvent(message, incidentAction(message)); err != nil {
+		return err
+	}
+	if message.Event == EventNotificationTest {
+		return p.sendEvent(message, "resolve")
+	}
+	return nil
+}
+
+func (p *PagerDutyNotifier) sendEvent(message *Message, action string) error {
+	event := &PagerDutyEvent{
+		RoutingKey:  p.config.RoutingKey,
+		EventAction: action,
+		DedupKey:    incidentDedupKey(message),
+	}
+
+	if action == "trigger" {
+		event.Payload = &PagerDutyPayload{
+			Summary:       message.Title,
+			Source:        p.source(),
+			Severity:      p.getSeverityForType(message.Type),
+			Timestamp:     message.Timestamp.UTC().Format(time.RFC3339),
+			Component:     message.ConfigName,
+			CustomDetails: incidentDetails(message),
+		}
+		if message.Text != "" {
+			event.Payload.CustomDetails["Details"] = message.Text
+		}
+		for name, value := range event.Payload.CustomDetails {
+			if isLinkValue(value) {
+				event.Links = append(event.Links, PagerDutyLink{Href: value, Text: name})
+			}
+		}
+		sort.Slice(event.Links, func(i, j int) bool { return event.Links[i].Text < event.Links[j].Text })
+	}
+
+	return postIncidentJSON(p.eventURL, event, nil, "PagerDuty")
+}
+
+// ValidateConfig validates the PagerDuty configuration
+func (p *PagerDutyNotifier) ValidateConfig(config map[string]interface{}) error {
+	routingKey, ok := config["routing_key"].(string)
+	if !ok || routingKey == "" {
+		return fmt.Errorf("routing_key is required for PagerDuty")
+	}
+
+	return nil
+}
+
+// GetChannelType returns the notification channel type
+func (p *PagerDutyNotifier) GetChannelType() NotificationChannel {
+	return ChannelPagerDuty
+}
+
+// source returns the configured event source, "lazy" by default
+func (p *PagerDutyNotifier) source() string {
+	if p.config.Source == "" {
+		return "lazy"
+	}
+	return p.config.Source
+}
+
+// getSeverityForType returns the PagerDuty severity of a message type
+func (p *PagerDutyNotifier) getSeverityForType(msgType MessageType) string {
+	switch msgType {
+	case MessageTypeError:
+		return "error"
+	case MessageTypeWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// OpsgenieNotifier implements the Notifier interface for the Opsgenie Alert API.
+// Error and warning messages create an alert, success messages close it.
+type OpsgenieNotifier struct {
+	config OpsgenieConfig
+	apiURL string
+}
+
+// NewOpsgenieNotifier creates a new Opsgenie notifier
+func NewOpsgenieNotifier(config OpsgenieConfig) *OpsgenieNotifier {
+	apiURL := opsgenieAPIURL
+	if strings.EqualFold(config.Region, "eu") {
+		apiURL = opsgenieEUAPIURL
+	}
+
+	return &OpsgenieNotifier{
+		config: config,
+		apiURL: apiURL,
+	}
+}
+
+// OpsgenieAlert represents a create alert request
+type OpsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+	Source      string            `json:"source,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+}
+
+// OpsgenieClose represents a close alert request
+type OpsgenieClose struct {
+	Source string `json:"source,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Send creates or closes the Opsgenie alert of the message.
+// Test notifications create and immediately close an alert.
+func (o *OpsgenieNotifier) Send(message *Message) error {
+	if incidentAction(message) == "resolve" {
+		return o.closeAlert(message)
+	}
+
+	if err := o.createAlert(message); err != nil {
+		return err
+	}
+	if message.Event == EventNotificationTest {
+		return o.closeAlert(message)
+	}
+	return nil
+}
+
+func (o *OpsgenieNotifier) createAlert(message *Message) error {
+	summary := message.Title
+	if runes := []rune(summary); len(runes) > opsgenieMessageLimit {
+		summary = string(runes[:opsgenieMessageLimit-3]) + "..."
+	}
+
+	alert := &OpsgenieAlert{
+		Message:     summary,
+		Alias:       incidentDedupKey(message),
+		Description: message.Text,
+		Details:     incidentDetails(message),
+		Priority:    o.getPriorityForType(message.Type),
+		Source:      "lazy",
+		Tags:        o.config.Tags,
+	}
+
+	return postIncidentJSON(o.apiURL, alert, o.headers(), "Opsgenie")
+}
+
+func (o *OpsgenieNotifier) closeAlert(message *Message) error {
+	endpoint := fmt.Sprintf("%s/%s/close?identifierType=alias", o.apiURL, url.PathEscape(incidentDedupKey(message)))
+	request := &OpsgenieClose{Source: "lazy", Note: message.Title}
+
+	return postIncidentJSON(endpoint, request, o.headers(), "Opsgenie")
+}
+
+func (o *OpsgenieNotifier) headers() map[string]string {
+	return map[string]string{"Authorization": "GenieKey " + o.config.APIKey}
+}
+
+// ValidateConfig validates the Opsgenie configuration
+func (o *OpsgenieNotifier) ValidateConfig(config map[string]interface{}) error {
+	apiKey, ok := config["api_key"].(string)
+	if !ok || apiKey == "" {
+		return fmt.Errorf("api_key is required for Opsgenie")
+	}
+
+	if region, ok := config["region"].(string); ok && region != "" && !strings.EqualFold(region, "us") && !strings.EqualFold(region, "eu") {
+		return fmt.Errorf("region must be us or eu for Opsgenie")
+	}
+
+	if priority, ok := config["priority"].(string); ok && priority != "" {
+		switch priority {
+		case "P1", "P2", "P3", "P4", "P5":
+		default:
+			return fmt.Errorf("priority must be P1 to P5 for Opsgenie")
+		}
+	}
+
+	return nil
+}
+
+// GetChannelType returns the notification channel type
+func (o *OpsgenieNotifier) GetChannelType() NotificationChannel {
+	return ChannelOpsgenie
+}
+
+// getPriorityForType returns the alert priority of a message type; errors use the configured priority
+func (o *OpsgenieNotifier) getPriorityForType(msgType MessageType) string {
+	switch msgType {
+	case MessageTypeError:
+		if o.config.Priority != "" {
+			return o.config.Priority
+		}
+		return "P2"
+	case MessageTypeWarning:
+		return "P3"
+	default:
+		return "P5"
+	}
+}
@@ -0,0 +1,194 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vfa-khuongdv/lazy/internal/database"
+)
+
+// incidentRequest is a request received by the incident API stand-in
+type incidentRequest struct {
+	Path  string
+	Query string
+	Auth  string
+	Body  map[string]interface{}
+}
+
+// newIncidentServer records every request and answers with status
+func newIncidentServer(t *testing.T, status int) (*httptest.Server, func() []incidentRequest) {
+	var mu sync.Mutex
+	var requests []incidentRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := incidentRequest{Path: r.URL.Path, Query: r.URL.RawQuery, Auth: r.Header.Get("Authorization")}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request.Body))
+
+		mu.Lock()
+		requests = append(requests, request)
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []incidentRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]incidentRequest(nil), requests...)
+	}
+}
+
+func newIncidentBackupData() *BackupNotificationData {
+	startedAt := time.Now().Add(-time.Minute)
+	return &BackupNotificationData{
+		ConfigName:   "orders",
+		DatabaseType: "mysql",
+		FileName:     "orders.sql",
+		WebViewLink:  "https://drive.google.com/file/d/abc/view",
+		ErrorMessage: "connection refused",
+		StartedAt:    startedAt,
+		CompletedAt:  startedAt.Add(time.Minute),
+	}
+}
+
+func TestIncidentDedupKey(t *testing.T) {
+	assert.Equal(t, "lazy/backup/orders", incidentDedupKey(CreateBackupErrorMessage(newIncidentBackupData())))
+	assert.Equal(t, "lazy/backup/orders", incidentDedupKey(CreateBackupSuccessMessage(newIncidentBackupData())))
+	assert.Equal(t, "lazy/drive.auth_failing/client-a", incidentDedupKey(CreateDriveAuthMessage(&DriveAuthNotificationData{Connection: "client-a"})))
+
+	// Recoveries and skipped backups share the key of the incident they resolve
+	assert.Equal(t, "lazy/drive.auth_failing/client-a", incidentDedupKey(CreateDriveAuthRecoveredMessage(&DriveAuthNotificationData{Connection: "client-a"})))
+	assert.Equal(t, "lazy/backup/orders", incidentDedupKey(CreateStorageMessage(&StorageNotificationData{Connection: "client-a", ConfigName: "orders", Skipped: true})))
+}
+
+func TestManager_KeepsUnresolvableAlertsFromIncidentChannels(t *testing.T) {
+	outbox, dbService := newTestOutbox(t, OutboxOptions{})
+	assert.NoError(t, dbService.SaveNotificationConfig(&database.NotificationConfig{
+		Name:          "ops-pagerduty",
+		Channel:       "pagerduty",
+		Config:        map[string]interface{}{"routing_key": "routing-key"},
+		NotifyOnError: true,
+		Enabled:       true,
+	}))
+	manager := NewManager(dbService)
+	manager.SetOutbox(outbox)
+
+	channels := func(results []NotificationResult) []NotificationChannel {
+		var names []NotificationChannel
+		for _, result := range results {
+			names = append(names, result.Channel)
+		}
+		return names
+	}
+
+	// Nothing resolves a capacity forecast, so it only reaches chat channels
+	assert.Equal(t, []NotificationChannel{ChannelSlack}, channels(manager.SendCapacityNotification(&CapacityNotificationData{Connection: "client-a", DaysUntilFull: 3})))
+
+	// Authorization failures are resolved by the recovery, skipped backups by the next success
+	auth := &DriveAuthNotificationData{Connection: "client-a", ConsecutiveFailures: 2}
+	assert.ElementsMatch(t, []NotificationChannel{ChannelSlack, ChannelPagerDuty}, channels(manager.SendDriveAuthNotification(auth)))
+	assert.ElementsMatch(t, []NotificationChannel{ChannelSlack, ChannelPagerDuty}, channels(manager.SendDriveAuthRecoveredNotification(auth)))
+	assert.ElementsMatch(t, []NotificationChannel{ChannelSlack, ChannelPagerDuty}, channels(manager.SendStorageNotification(&StorageNotificationData{Connection: "client-a", ConfigName: "orders", Skipped: true})))
+}
+
+func TestPagerDutyNotifier_TriggerAndResolve(t *testing.T) {
+	server, requests := newIncidentServer(t, http.StatusAccepted)
+	notifier := NewPagerDutyNotifier(PagerDutyConfig{RoutingKey: "routing-key"})
+	notifier.eventURL = server.URL
+
+	assert.NoError(t, notifier.Send(CreateBackupErrorMessage(newIncidentBackupData())))
+	assert.NoError(t, notifier.Send(CreateBackupSuccessMessage(newIncidentBackupData())))
+
+	sent := requests()
+	assert.Len(t, sent, 2)
+
+	trigger := sent[0].Body
+	assert.Equal(t, "routing-key", trigger["routing_key"])
+	assert.Equal(t, "trigger", trigger["event_action"])
+	assert.Equal(t, "lazy/backup/orders", trigger["dedup_key"])
+	payload := trigger["payload"].(map[string]interface{})
+	assert.Equal(t, "Backup Failed: orders", payload["summary"])
+	assert.Equal(t, "lazy", payload["source"])
+	assert.Equal(t, "error", payload["severity"])
+	assert.Equal(t, "orders", payload["component"])
+	assert.Equal(t, "connection refused", payload["custom_details"].(map[string]interface{})["Error"])
+
+	resolve := sent[1].Body
+	assert.Equal(t, "resolve", resolve["event_action"])
+	assert.Equal(t, "lazy/backup/orders", resolve["dedup_key"])
+	assert.NotContains(t, resolve, "payload")
+}
+
+func TestPagerDutyNotifier_TestNotificationResolvesItself(t *testing.T) {
+	server, requests := newIncidentServer(t, http.StatusAccepted)
+	notifier := NewPagerDutyNotifier(PagerDutyConfig{RoutingKey: "routing-key", Source: "backup-host"})
+	notifier.eventURL = server.URL
+
+	assert.NoError(t, notifier.Send(&Message{Type: MessageTypeInfo, Event: EventNotificationTest, Title: "Test Notification", Timestamp: time.Now()}))
+
+	sent := requests()
+	assert.Len(t, sent, 2)
+	assert.Equal(t, "trigger", sent[0].Body["event_action"])
+	assert.Equal(t, "backup-host", sent[0].Body["payload"].(map[string]interface{})["source"])
+	assert.Equal(t, "resolve", sent[1].Body["event_action"])
+}
+
+func TestPagerDutyNotifier_Error(t *testing.T) {
+	server, _ := newIncidentServer(t, http.StatusBadRequest)
+	notifier := NewPagerDutyNotifier(PagerDutyConfig{RoutingKey: "routing-key"})
+	notifier.eventURL = server.URL
+
+	err := notifier.Send(CreateBackupErrorMessage(newIncidentBackupData()))
+	assert.ErrorContains(t, err, "pagerduty API returned status 400")
+}
+
+func TestOpsgenieNotifier_CreateAndClose(t *testing.T) {
+	server, requests := newIncidentServer(t, http.StatusAccepted)
+	notifier := NewOpsgenieNotifier(OpsgenieConfig{APIKey: "genie-key", Priority: "P1", Tags: []string{"backups"}})
+	notifier.apiURL = server.URL + "/v2/alerts"
+
+	assert.NoError(t, notifier.Send(CreateBackupErrorMessage(newIncidentBackupData())))
+	assert.NoError(t, notifier.Send(CreateBackupSuccessMessage(newIncidentBackupData())))
+
+	sent := requests()
+	assert.Len(t, sent, 2)
+
+	create := sent[0]
+	assert.Equal(t, "/v2/alerts", create.Path)
+	assert.Equal(t, "GenieKey genie-key", create.Auth)
+	assert.Equal(t, "Backup Failed: orders", create.Body["message"])
+	assert.Equal(t, "lazy/backup/orders", create.Body["alias"])
+	assert.Equal(t, "P1", create.Body["priority"])
+	assert.Equal(t, []interface{}{"backups"}, create.Body["tags"])
+
+	closed := sent[1]
+	assert.Equal(t, "/v2/alerts/lazy/backup/orders/close", closed.Path)
+	assert.Equal(t, "identifierType=alias", closed.Query)
+	assert.Equal(t, "GenieKey genie-key", closed.Auth)
+}
+
+func TestOpsgenieNotifier_Region(t *testing.T) {
+	assert.Equal(t, opsgenieAPIURL, NewOpsgenieNotifier(OpsgenieConfig{}).apiURL)
+	assert.Equal(t, opsgenieEUAPIURL, NewOpsgenieNotifier(OpsgenieConfig{Region: "EU"}).apiURL)
+}
+
+func TestIncidentNotifiers_ValidateConfig(t *testing.T) {
+	assert.NoError(t, (&PagerDutyNotifier{}).ValidateConfig(map[string]interface{}{"routing_key": "key"}))
+	assert.ErrorContains(t, (&PagerDutyNotifier{}).ValidateConfig(map[string]interface{}{}), "routing_key is required")
+
+	opsgenie := &OpsgenieNotifier{}
+	assert.NoError(t, opsgenie.ValidateConfig(map[string]interface{}{"api_key": "key", "region": "eu", "priority": "P3"}))
+	assert.ErrorContains(t, opsgenie.ValidateConfig(map[string]interface{}{}), "api_key is required")
+	assert.ErrorContains(t, opsgenie.ValidateConfig(map[string]interface{}{"api_key": "key", "region": "apac"}), "region must be us or eu")
+	assert.ErrorContains(t, opsgenie.ValidateConfig(map[string]interface{}{"api_key": "key", "priority": "high"}), "priority must be P1 to P5")
+}
+
+func TestIsIncidentChannel(t *testing.T) {
+	assert.True(t, isIncidentChannel("pagerduty"))
+	assert.True(t, isIncidentChannel("opsgenie"))
+	assert.False(t, isIncidentChannel("slack"))
+}
@@ -1,7 +1,6 @@
 package notification
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"sync"
@@ -14,6 +13,8 @@ import (
 type Manager struct {
 	dbService *database.Service
 	notifiers map[string]Notifier
+	outbox    *Outbox // nil sends synchronously without retries
+	alerts    *suppressor
 	mutex     sync.RWMutex
 }
 
@@ -22,6 +23,7 @@ func NewManager(dbService *database.Service) *Manager {
 	return &Manager{
 		dbService: dbService,
 		notifiers: make(map[string]Notifier),
+		alerts:    newSuppressor(SuppressionOptions{}),
 	}
 }
 
@@ -34,6 +36,82 @@ func (m *Manager) AddNotifier(configName string, notifier Notifier) {
 	log.Printf("Added %s notifier for config '%s'", notifier.GetChannelType(), configName)
 }
 
+// SetOutbox queues backup and alert notifications in the outbox instead of sending them synchronously
+func (m *Manager) SetOutbox(outbox *Outbox) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.outbox = outbox
+}
+
+// SetSuppression sets the default alert suppression rules and the channel rate limit
+func (m *Manager) SetSuppression(options SuppressionOptions) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.alerts = newSuppressor(options)
+}
+
+// RestoreFailureStreaks rebuilds the failure streaks of backup configs from their history, so repeat rules
+// and recovery messages carry on across a restart. Which alerts were delivered is not stored, so every
+// config that notifies on errors is taken as alerted when the streak started.
+func (m *Manager) RestoreFailureStreaks() error {
+	backupConfigs, err := m.dbService.GetAllBackupConfigs()
+	if err != nil {
+		return fmt.Errorf("failed to get backup configs: %w", err)
+	}
+	notificationConfigs, err := m.getEnabledNotificationConfigs()
+	if err != nil {
+		return fmt.Errorf("failed to get notification configs: %w", err)
+	}
+
+	var alerted []string
+	for _, config := range notificationConfigs {
+		if config.NotifyOnError {
+			alerted = append(alerted, config.Name)
+		}
+	}
+
+	alerts := m.getSuppressor()
+	for _, config := range backupConfigs {
+		failures, since, err := m.failureStreakOf(config.Name)
+		if err != nil {
+			return err
+		}
+		if failures > 0 {
+			alerts.restoreStreak(config.Name, failures, since, alerted)
+		}
+	}
+	return nil
+}
+
+// failureStreakOf counts the failed backups of a config since its last success and returns when the first of them started.
+// Skipped and unfinished runs do not end a streak.
+func (m *Manager) failureStreakOf(configName string) (int, time.Time, error) {
+	const pageSize = 50
+
+	var failures int
+	var since time.Time
+	for offset := 0; ; offset += pageSize {
+		history, _, err := m.dbService.GetBackupHistoryByConfigName(configName, database.HistoryFilter{Limit: pageSize, Offset: offset})
+		if err != nil {
+			return 0, time.Time{}, fmt.Errorf("failed to get backup history of '%s': %w", configName, err)
+		}
+		for _, run := range history {
+			switch run.Status {
+			case database.BackupStatusSuccess:
+				return failures, since, nil
+			case database.BackupStatusFailed:
+				failures++
+				since = run.StartedAt
+			}
+		}
+		if len(history) < pageSize {
+			return failures, since, nil
+		}
+	}
+}
+
 // RemoveNotifier removes a notifier for a specific configuration
 func (m *Manager) RemoveNotifier(configName string) {
 	m.mutex.Lock()
@@ -72,6 +150,7 @@ func (m *Manager) SendNotification(message *Message) []NotificationResult {
 				result.Success = true
 				log.Printf("Successfully sent notification via %s (config: %s)", n.GetChannelType(), name)
 			}
+			m.recordAttempt(name, string(n.GetChannelType()), message, err)
 
 			resultChan <- result
 		}(configName, notifier)
@@ -91,8 +170,14 @@ func (m *Manager) SendNotification(message *Message) []NotificationResult {
 	return results
 }
 
-// SendBackupSuccessNotification sends a backup success notification
+// SendBackupSuccessNotification sends a backup success notification.
+// A success ending a failure streak is also sent to every config that was alerted about the failures.
 func (m *Manager) SendBackupSuccessNotification(data *BackupNotificationData) []NotificationResult {
+	streak := m.getSuppressor().recordSuccess(data.ConfigName)
+	if streak != nil {
+		data.ConsecutiveFailures = streak.failures
+	}
+
 	// Get all enabled notification configs
 	configs, err := m.getEnabledNotificationConfigs()
 	if err != nil {
@@ -103,54 +188,27 @@ func (m *Manager) SendBackupSuccessNotification(data *BackupNotificationData) []
 	var allResults []NotificationResult
 
 	for _, config := range configs {
-		if !config.NotifyOnSuccess {
-			continue
-		}
-
-		// Create channel-specific message
-		var message *Message
-		switch config.Channel {
-		case "discord":
-			message = CreateDiscordBackupSuccessMessage(data)
-		case "slack":
-			message = CreateSlackBackupSuccessMessage(data)
-		case "chatwork":
-			message = CreateBackupSuccessMessage(data)
-		default:
-			message = CreateBackupSuccessMessage(data)
-		}
-
-		// Create notifier if not exists
-		notifier, err := m.createNotifierFromConfig(&config)
-		if err != nil {
-			log.Printf("Failed to create notifier for config '%s': %v", config.Name, err)
+		// Incident channels always hear about successes so they can resolve the incident of an earlier failure
+		_, alerted := streak.alerted(config.Name)
+		if !config.NotifyOnSuccess && !(config.NotifyOnError && isIncidentChannel(config.Channel)) && !alerted {
 			continue
 		}
 
-		// Send notification
-		result := NotificationResult{
-			Channel: notifier.GetChannelType(),
-			SentAt:  time.Now(),
-		}
-
-		err = notifier.Send(message)
-		if err != nil {
-			result.Success = false
-			result.Error = err.Error()
-			log.Printf("Failed to send success notification via %s (config: %s): %v", config.Channel, config.Name, err)
-		} else {
-			result.Success = true
-			log.Printf("Successfully sent success notification via %s (config: %s)", config.Channel, config.Name)
-		}
-
-		allResults = append(allResults, result)
+		// Create channel-specific message and send or queue it
+		message := m.backupMessage(&config, data, true)
+		allResults = append(allResults, m.deliver(&config, message, "success"))
 	}
 
 	return allResults
 }
 
-// SendBackupErrorNotification sends a backup error notification
+// SendBackupErrorNotification sends a backup error notification.
+// Repeated failures of the same backup config are only sent as the suppression rules allow.
 func (m *Manager) SendBackupErrorNotification(data *BackupNotificationData) []NotificationResult {
+	now := time.Now()
+	alerts := m.getSuppressor()
+	data.ConsecutiveFailures = alerts.recordFailure(data.ConfigName, now)
+
 	// Get all enabled notification configs
 	configs, err := m.getEnabledNotificationConfigs()
 	if err != nil {
@@ -165,43 +223,173 @@ func (m *Manager) SendBackupErrorNotification(data *BackupNotificationData) []No
 			continue
 		}
 
-		// Create channel-specific message
-		var message *Message
-		switch config.Channel {
+		if !alerts.shouldAlert(data.ConfigName, &config, now) {
+			log.Printf("Suppressed error notification via %s (config: %s), %d failures in a row of '%s'",
+				config.Channel, config.Name, data.ConsecutiveFailures, data.ConfigName)
+			allResults = append(allResults, NotificationResult{Channel: NotificationChannel(config.Channel), SentAt: now, Suppressed: true})
+			continue
+		}
+
+		// Create channel-specific message and send or queue it
+		message := m.backupMessage(&config, data, false)
+		result := m.deliver(&config, message, "error")
+		// A send dropped by the rate limit leaves the config unalerted, so the next failure tries again
+		if !result.Suppressed {
+			alerts.markAlerted(data.ConfigName, config.Name, now)
+		}
+		allResults = append(allResults, result)
+	}
+
+	return allResults
+}
+
+// deliver queues message for a config in the outbox, or sends it right away when there is none;
+// kind names the notification in the logs
+func (m *Manager) deliver(config *database.NotificationConfig, message *Message, kind string) NotificationResult {
+	result := NotificationResult{
+		Channel: NotificationChannel(config.Channel),
+		SentAt:  time.Now(),
+	}
+
+	m.mutex.RLock()
+	outbox := m.outbox
+	m.mutex.RUnlock()
+	alerts := m.getSuppressor()
+
+	// Recoveries are always sent so nobody is left believing a backup is still failing
+	if !alerts.allowSend(config.Channel, result.SentAt, message.Event == EventBackupRecovered) {
+		log.Printf("Rate limit of %s reached, dropped %s notification (config: %s)", config.Channel, kind, config.Name)
+		result.Suppressed = true
+		return result
+	}
+
+	if outbox != nil {
+		delivery, err := outbox.Enqueue(config, message)
+		if err != nil {
+			result.Error = err.Error()
+			log.Printf("Failed to queue %s notification via %s (config: %s): %v", kind, config.Channel, config.Name, err)
+			return result
+		}
+		result.Queued = true
+		result.MessageID = deliveryMessageID(delivery)
+		return result
+	}
+
+	notifier, err := m.createNotifierFromConfig(config)
+	if err == nil {
+		err = notifier.Send(message)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		log.Printf("Failed to send %s notification via %s (config: %s): %v", kind, config.Channel, config.Name, err)
+	} else {
+		result.Success = true
+		log.Printf("Successfully sent %s notification via %s (config: %s)", kind, config.Channel, config.Name)
+	}
+	m.recordAttempt(config.Name, config.Channel, message, err)
+
+	return result
+}
+
+// recordAttempt stores the result of a notification sent without the outbox
+func (m *Manager) recordAttempt(configName, channel string, message *Message, sendErr error) {
+	if m.dbService == nil {
+		return
+	}
+
+	attempt := &database.NotificationAttempt{
+		ConfigName: configName,
+		Channel:    channel,
+		Event:      message.Event,
+		Attempt:    1,
+		Success:    sendErr == nil,
+		SentAt:     time.Now(),
+	}
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+		attempt.StatusCode = errorStatusCode(sendErr)
+	}
+	if err := m.dbService.SaveNotificationAttempt(attempt); err != nil {
+		log.Printf("Failed to record notification attempt: %v", err)
+	}
+}
+
+// backupMessage creates the message of a backup for a config, rendered with its templates.
+// The built-in message is sent when the templates fail to render.
+func (m *Manager) backupMessage(config *database.NotificationConfig, data *BackupNotificationData, succeeded bool) *Message {
+	message, err := renderBackupMessage(config.Templates, createBackupMessage(config.Channel, data, succeeded), data)
+	if err != nil {
+		log.Printf("Failed to render templates of config '%s', sending the built-in message: %v", config.Name, err)
+		return createBackupMessage(config.Channel, data, succeeded)
+	}
+	return message
+}
+
+// createBackupMessage creates the channel-specific built-in message of a backup, tagged with its event
+func createBackupMessage(channel string, data *BackupNotificationData, succeeded bool) *Message {
+	var message *Message
+	if succeeded {
+		switch channel {
 		case "discord":
-			message = CreateDiscordBackupErrorMessage(data)
+			message = CreateDiscordBackupSuccessMessage(data)
 		case "slack":
-			message = CreateSlackBackupErrorMessage(data)
-		case "chatwork":
-			message = CreateBackupErrorMessage(data)
+			message = CreateSlackBackupSuccessMessage(data)
+		case "teams":
+			message = CreateTeamsBackupSuccessMessage(data)
+		case "webhook":
+			message = CreateWebhookBackupSuccessMessage(data)
 		default:
-			message = CreateBackupErrorMessage(data)
+			message = CreateBackupSuccessMessage(data)
 		}
-
-		// Create notifier if not exists
-		notifier, err := m.createNotifierFromConfig(&config)
-		if err != nil {
-			log.Printf("Failed to create notifier for config '%s': %v", config.Name, err)
-			continue
+		message.Event = EventBackupSucceeded
+		if data.ConsecutiveFailures > 0 {
+			message.Event = EventBackupRecovered
+			if message.Fields == nil {
+				message.Fields = make(map[string]interface{})
+			}
+			message.Fields["Recovered After"] = fmt.Sprintf("%d failed backups", data.ConsecutiveFailures)
 		}
+		return message
+	}
 
-		// Send notification
-		result := NotificationResult{
-			Channel: notifier.GetChannelType(),
-			SentAt:  time.Now(),
+	switch channel {
+	case "discord":
+		message = CreateDiscordBackupErrorMessage(data)
+	case "slack":
+		message = CreateSlackBackupErrorMessage(data)
+	case "teams":
+		message = CreateTeamsBackupErrorMessage(data)
+	case "webhook":
+		message = CreateWebhookBackupErrorMessage(data)
+	default:
+		message = CreateBackupErrorMessage(data)
+	}
+	message.Event = EventBackupFailed
+	if data.ConsecutiveFailures > 1 {
+		if message.Fields == nil {
+			message.Fields = make(map[string]interface{})
 		}
+		message.Fields["Consecutive Failures"] = data.ConsecutiveFailures
+	}
+	return message
+}
 
-		err = notifier.Send(message)
-		if err != nil {
-			result.Success = false
-			result.Error = err.Error()
-			log.Printf("Failed to send error notification via %s (config: %s): %v", config.Channel, config.Name, err)
-		} else {
-			result.Success = true
-			log.Printf("Successfully sent error notification via %s (config: %s)", config.Channel, config.Name)
+// sendToErrorConfigs sends message to every enabled config that notifies on errors; kind names it in the logs.
+// Incident channels only get messages whose incident is resolved later, so none stays open forever.
+func (m *Manager) sendToErrorConfigs(message *Message, kind string) []NotificationResult {
+	configs, err := m.getEnabledNotificationConfigs()
+	if err != nil {
+		log.Printf("Failed to get notification configs: %v", err)
+		return nil
+	}
+
+	var allResults []NotificationResult
+	for _, config := range configs {
+		if !config.NotifyOnError || (isIncidentChannel(config.Channel) && !isResolvableIncident(message)) {
+			continue
 		}
 
-		allResults = append(allResults, result)
+		allResults = append(allResults, m.deliver(&config, message, kind))
 	}
 
 	return allResults
@@ -223,6 +411,7 @@ func (m *Manager) TestNotification(configName string) error {
 	// Create test message
 	message := &Message{
 		Type:      MessageTypeInfo,
+		Event:     EventNotificationTest,
 		Title:     "Test Notification",
 		Text:      fmt.Sprintf("This is a test notification from the Database Backup Service via %s", config.Channel),
 		Timestamp: time.Now(),
@@ -234,102 +423,41 @@ func (m *Manager) TestNotification(configName string) error {
 	}
 
 	// Send notification
-	return notifier.Send(message)
+	err = notifier.Send(message)
+	m.recordAttempt(config.Name, config.Channel, message, err)
+	return err
 }
 
-// getEnabledNotificationConfigs returns all enabled notification configurations
-func (m *Manager) getEnabledNotificationConfigs() ([]database.NotificationConfig, error) {
-	return m.dbService.GetEnabledNotificationConfigs()
-}
-
-// createNotifierFromConfig creates a notifier instance from configuration
-func (m *Manager) createNotifierFromConfig(config *database.NotificationConfig) (Notifier, error) {
-	// Convert string channel to NotificationChannel type
-	var channel NotificationChannel
-	switch config.Channel {
-	case "chatwork":
-		channel = ChannelChatwork
-	case "discord":
-		channel = ChannelDiscord
-	case "slack":
-		channel = ChannelSlack
-	default:
-		return nil, fmt.Errorf("unsupported notification channel: %s", config.Channel)
-	}
-
-	switch channel {
-	case ChannelChatwork:
-		chatworkConfig, err := m.parseChatworkConfig(config.Config)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse Chatwork config: %w", err)
-		}
-		return NewChatworkNotifier(*chatworkConfig), nil
-
-	case ChannelDiscord:
-		discordConfig, err := m.parseDiscordConfig(config.Config)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse Discord config: %w", err)
-		}
-		return NewDiscordNotifier(*discordConfig), nil
-
-	case ChannelSlack:
-		slackConfig, err := m.parseSlackConfig(config.Config)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse Slack config: %w", err)
-		}
-		return NewSlackNotifier(*slackConfig), nil
-
-	default:
-		return nil, fmt.Errorf("unsupported notification channel: %s", config.Channel)
-	}
-}
-
-// parseChatworkConfig parses configuration map to ChatworkConfig
-func (m *Manager) parseChatworkConfig(config map[string]interface{}) (*ChatworkConfig, error) {
-	jsonData, err := json.Marshal(config)
+// PreviewNotification renders the backup message a config would send for data without sending it.
+// A nil data previews SampleBackupData; data with an ErrorMessage previews a failed backup.
+func (m *Manager) PreviewNotification(configName string, data *BackupNotificationData) (*Message, error) {
+	config, err := m.dbService.GetNotificationConfigByName(configName)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get notification config: %w", err)
 	}
 
-	var chatworkConfig ChatworkConfig
-	err = json.Unmarshal(jsonData, &chatworkConfig)
-	if err != nil {
-		return nil, err
+	if data == nil {
+		data = SampleBackupData(false)
 	}
-
-	return &chatworkConfig, nil
+	return renderBackupMessage(config.Templates, createBackupMessage(config.Channel, data, data.ErrorMessage == ""), data)
 }
 
-// parseDiscordConfig parses configuration map to DiscordConfig
-func (m *Manager) parseDiscordConfig(config map[string]interface{}) (*DiscordConfig, error) {
-	jsonData, err := json.Marshal(config)
-	if err != nil {
-		return nil, err
-	}
-
-	var discordConfig DiscordConfig
-	err = json.Unmarshal(jsonData, &discordConfig)
-	if err != nil {
-		return nil, err
-	}
+// getSuppressor returns the current alert suppression state
+func (m *Manager) getSuppressor() *suppressor {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 
-	return &discordConfig, nil
+	return m.alerts
 }
 
-// parseSlackConfig parses configuration map to SlackConfig
-func (m *Manager) parseSlackConfig(config map[string]interface{}) (*SlackConfig, error) {
-	jsonData, err := json.Marshal(config)
-	if err != nil {
-		return nil, err
-	}
-
-	var slackConfig SlackConfig
-	err = json.Unmarshal(jsonData, &slackConfig)
-	if err != nil {
-		return nil, err
-	}
+// getEnabledNotificationConfigs returns all enabled notification configurations
+func (m *Manager) getEnabledNotificationConfigs() ([]database.NotificationConfig, error) {
+	return m.dbService.GetEnabledNotificationConfigs()
+}
 
-	return &slackConfig, nil
+// createNotifierFromConfig creates a notifier instance from configuration through the channel registry
+func (m *Manager) createNotifierFromConfig(config *database.NotificationConfig) (Notifier, error) {
+	return NewNotifier(NotificationChannel(config.Channel), config.Config)
 }
 
 // LoadNotifiers loads all notification configurations and creates notifiers
@@ -0,0 +1,329 @@
+package notification
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/vfa-khuongdv/lazy/internal/database"
+)
+
+// Outbox delivery defaults
+const (
+	DefaultOutboxMaxAttempts    = 5
+	DefaultOutboxInitialBackoff = 30 * time.Second
+	DefaultOutboxMaxBackoff     = time.Hour
+	DefaultOutboxPollInterval   = 15 * time.Second
+	DefaultOutboxBatchSize      = 50
+)
+
+// OutboxOptions tunes notification delivery through the outbox; zero values use the defaults
+type OutboxOptions struct {
+	MaxAttempts    int           // attempts before a message is dead-lettered
+	InitialBackoff time.Duration // wait after the first failure, doubled after each further one
+	MaxBackoff     time.Duration // longest wait between attempts, unless a channel asks for more with Retry-After
+	PollInterval   time.Duration // how often due messages are looked up
+	BatchSize      int           // messages sent per poll
+}
+
+// withDefaults fills in the zero values
+func (o OutboxOptions) withDefaults() OutboxOptions {
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = DefaultOutboxMaxAttempts
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = DefaultOutboxInitialBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = DefaultOutboxMaxBackoff
+	}
+	if o.MaxBackoff < o.InitialBackoff {
+		o.MaxBackoff = o.InitialBackoff
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = DefaultOutboxPollInterval
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultOutboxBatchSize
+	}
+	return o
+}
+
+// Outbox stores notifications in the metadata database and delivers them in the background,
+// retrying failed sends with exponential backoff until they succeed or are dead-lettered.
+// Queued messages survive restarts.
+type Outbox struct {
+	dbService *database.Service
+	options   OutboxOptions
+	send      func(config *database.NotificationConfig, message *Message) error
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewOutbox creates an outbox storing its messages through dbService
+func NewOutbox(dbService *database.Service, options OutboxOptions) *Outbox {
+	return &Outbox{
+		dbService: dbService,
+		options:   options.withDefaults(),
+		send: func(config *database.NotificationConfig, message *Message) error {
+			notifier, err := NewNotifier(NotificationChannel(config.Channel), config.Config)
+			if err != nil {
+				return err
+			}
+			return notifier.Send(message)
+		},
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Start delivers queued messages in the background until Stop, starting with those left from a previous run
+func (o *Outbox) Start() {
+	o.stop = make(chan struct{})
+	o.done = make(chan struct{})
+	go o.run()
+	log.Printf("Notification outbox started, retrying failed sends up to %d times", o.options.MaxAttempts)
+}
+
+// Stop stops the background delivery and waits for a running batch to finish
+func (o *Outbox) Stop() {
+	if o.stop == nil {
+		return
+	}
+	close(o.stop)
+	<-o.done
+	o.stop = nil
+}
+
+// Enqueue stores a message for a notification config and wakes the delivery loop
+func (o *Outbox) Enqueue(config *database.NotificationConfig, message *Message) (*database.NotificationDelivery, error) {
+	if message.ID == "" {
+		id, err := newMessageID()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate message ID: %w", err)
+		}
+		message.ID = id
+	}
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	delivery := &database.NotificationDelivery{
+		ConfigName:    config.Name,
+		Channel:       config.Channel,
+		Event:         message.Event,
+		Payload:       string(payload),
+		Status:        database.DeliveryStatusPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := o.dbService.SaveNotificationDelivery(delivery); err != nil {
+		return nil, fmt.Errorf("failed to queue notification: %w", err)
+	}
+
+	o.notify()
+	return delivery, nil
+}
+
+// Retry queues a dead-lettered or pending message for immediate delivery with a fresh set of attempts
+func (o *Outbox) Retry(id uint) error {
+	delivery, err := o.dbService.GetNotificationDelivery(id)
+	if err != nil {
+		return fmt.Errorf("failed to get notification delivery: %w", err)
+	}
+	if delivery.Status == database.DeliveryStatusDelivered {
+		return fmt.Errorf("notification delivery %d was already delivered", id)
+	}
+
+	delivery.Status = database.DeliveryStatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	if err := o.dbService.UpdateNotificationDelivery(delivery); err != nil {
+		return fmt.Errorf("failed to update notification delivery: %w", err)
+	}
+
+	o.notify()
+	return nil
+}
+
+// DeliverNow sends every message whose next attempt is due and returns how many were attempted.
+// Messages of a config go out in the order they were queued: one waiting to be retried, e.g. after a
+// Retry-After, holds back the later ones so a resolve cannot overtake its trigger.
+func (o *Outbox) DeliverNow() int {
+	attempted := 0
+	// Each round sends the oldest due message of every config; a delivered one lets the next of its config go
+	for attempted < o.options.BatchSize {
+		deliveries, err := o.dbService.GetDueNotificationDeliveries(time.Now(), o.options.BatchSize-attempted)
+		if err != nil {
+			log.Printf("Failed to load queued notifications: %v", err)
+			break
+		}
+		if len(deliveries) == 0 {
+			break
+		}
+
+		for i := range deliveries {
+			o.deliver(&deliveries[i])
+			attempted++
+		}
+	}
+	return attempted
+}
+
+func (o *Outbox) run() {
+	defer close(o.done)
+
+	ticker := time.NewTicker(o.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep sending while full batches are due
+		for o.DeliverNow() >= o.options.BatchSize {
+			select {
+			case <-o.stop:
+				return
+			default:
+			}
+		}
+
+		select {
+		case <-o.stop:
+			return
+		case <-o.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// notify wakes the delivery loop without blocking
+func (o *Outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliver makes one attempt to send a queued message, records the result and schedules the next attempt
+func (o *Outbox) deliver(delivery *database.NotificationDelivery) {
+	config, err := o.dbService.GetNotificationConfigByName(delivery.ConfigName)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			o.deadLetter(delivery, "notification config no longer exists")
+		} else {
+			log.Printf("Failed to get notification config '%s': %v", delivery.ConfigName, err)
+			delivery.NextAttemptAt = time.Now().Add(o.options.PollInterval)
+			if err := o.dbService.UpdateNotificationDelivery(delivery); err != nil {
+				log.Printf("Failed to update queued notification %d: %v", delivery.ID, err)
+			}
+		}
+		return
+	}
+	if !config.Enabled {
+		o.deadLetter(delivery, "notification config is disabled")
+		return
+	}
+
+	// Numbers stay json.Number so fields print as they were queued
+	var message Message
+	decoder := json.NewDecoder(strings.NewReader(delivery.Payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&message); err != nil {
+		o.deadLetter(delivery, fmt.Sprintf("invalid queued message: %v", err))
+		return
+	}
+
+	delivery.Attempts++
+	sendErr := o.send(config, &message)
+	now := time.Now()
+
+	attempt := &database.NotificationAttempt{
+		DeliveryID: &delivery.ID,
+		ConfigName: delivery.ConfigName,
+		Channel:    delivery.Channel,
+		Event:      delivery.Event,
+		Attempt:    delivery.Attempts,
+		Success:    sendErr == nil,
+		SentAt:     now,
+	}
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+		attempt.StatusCode = errorStatusCode(sendErr)
+	}
+	if err := o.dbService.SaveNotificationAttempt(attempt); err != nil {
+		log.Printf("Failed to record notification attempt: %v", err)
+	}
+
+	switch {
+	case sendErr == nil:
+		delivery.Status = database.DeliveryStatusDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		log.Printf("Delivered queued notification %d via %s (config: %s)", delivery.ID, delivery.Channel, delivery.ConfigName)
+
+	case isPermanentError(sendErr) || delivery.Attempts >= o.options.MaxAttempts:
+		delivery.Status = database.DeliveryStatusDead
+		delivery.LastError = sendErr.Error()
+		log.Printf("Gave up on notification %d via %s (config: %s) after %d attempts: %v",
+			delivery.ID, delivery.Channel, delivery.ConfigName, delivery.Attempts, sendErr)
+
+	default:
+		delivery.LastError = sendErr.Error()
+		delivery.NextAttemptAt = now.Add(o.backoff(delivery.Attempts, errorRetryAfter(sendErr)))
+		log.Printf("Failed to send notification %d via %s (config: %s), retrying at %s: %v",
+			delivery.ID, delivery.Channel, delivery.ConfigName, delivery.NextAttemptAt.Format(time.RFC3339), sendErr)
+	}
+
+	if err := o.dbService.UpdateNotificationDelivery(delivery); err != nil {
+		log.Printf("Failed to update queued notification %d: %v", delivery.ID, err)
+	}
+}
+
+// deadLetter gives up on a message without attempting it
+func (o *Outbox) deadLetter(delivery *database.NotificationDelivery, reason string) {
+	delivery.Status = database.DeliveryStatusDead
+	delivery.LastError = reason
+	if err := o.dbService.UpdateNotificationDelivery(delivery); err != nil {
+		log.Printf("Failed to update queued notification %d: %v", delivery.ID, err)
+	}
+	log.Printf("Gave up on notification %d (config: %s): %s", delivery.ID, delivery.ConfigName, reason)
+}
+
+// backoff returns the wait before the next attempt: the initial backoff doubled per failed attempt,
+// capped at MaxBackoff, but never shorter than the Retry-After the channel asked for
+func (o *Outbox) backoff(attempts int, retryAfter time.Duration) time.Duration {
+	wait := o.options.InitialBackoff
+	for i := 1; i < attempts && wait < o.options.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > o.options.MaxBackoff {
+		wait = o.options.MaxBackoff
+	}
+	if retryAfter > wait {
+		wait = retryAfter
+	}
+	return wait
+}
+
+// deliveryMessageID returns the message ID reported for a queued delivery
+func deliveryMessageID(delivery *database.NotificationDelivery) string {
+	return strconv.FormatUint(uint64(delivery.ID), 10)
+}
+
+// newMessageID returns a random message ID
+func newMessageID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,301 @@
+package notification
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vfa-khuongdv/lazy/internal/database"
+)
+
+func newTestOutbox(t *testing.T, options OutboxOptions) (*Outbox, *database.Service) {
+	dbService, err := database.NewService(&database.ServiceSQLiteConfig{Path: filepath.Join(t.TempDir(), "lazy.db")})
+	assert.NoError(t, err)
+	t.Cleanup(func() { dbService.Close() })
+
+	assert.NoError(t, dbService.SaveNotificationConfig(&database.NotificationConfig{
+		Name:          "ops-slack",
+		Channel:       "slack",
+		Config:        map[string]interface{}{"webhook_url": "https://hooks.slack.com/services/test"},
+		NotifyOnError: true,
+		Enabled:       true,
+	}))
+
+	return NewOutbox(dbService, options), dbService
+}
+
+// makeDue moves the next attempt of every pending delivery into the past
+func makeDue(t *testing.T, dbService *database.Service) {
+	deliveries, _, err := dbService.GetNotificationDeliveries(database.NotificationFilter{Status: database.DeliveryStatusPending})
+	assert.NoError(t, err)
+	for i := range deliveries {
+		deliveries[i].NextAttemptAt = time.Now().Add(-time.Second)
+		assert.NoError(t, dbService.UpdateNotificationDelivery(&deliveries[i]))
+	}
+}
+
+func TestOutbox_RetriesUntilDelivered(t *testing.T) {
+	outbox, dbService := newTestOutbox(t, OutboxOptions{InitialBackoff: time.Minute})
+
+	failures := 2
+	var sent []*Message
+	outbox.send = func(config *database.NotificationConfig, message *Message) error {
+		sent = append(sent, message)
+		if failures > 0 {
+			failures--
+			return &DeliveryError{StatusCode: http.StatusServiceUnavailable, Message: "slack webhook returned status 503"}
+		}
+		return nil
+	}
+
+	config, err := dbService.GetNotificationConfigByName("ops-slack")
+	assert.NoError(t, err)
+	delivery, err := outbox.Enqueue(config, CreateBackupErrorMessage(SampleBackupData(true)))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, outbox.DeliverNow())
+	stored, err := dbService.GetNotificationDelivery(delivery.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, database.DeliveryStatusPending, stored.Status)
+	assert.Equal(t, 1, stored.Attempts)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), stored.NextAttemptAt, 5*time.Second)
+
+	// Nothing is due until the backoff is over
+	assert.Equal(t, 0, outbox.DeliverNow())
+
+	makeDue(t, dbService)
+	outbox.DeliverNow()
+	makeDue(t, dbService)
+	outbox.DeliverNow()
+
+	stored, err = dbService.GetNotificationDelivery(delivery.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, database.DeliveryStatusDelivered, stored.Status)
+	assert.Equal(t, 3, stored.Attempts)
+	assert.NotNil(t, stored.DeliveredAt)
+
+	assert.Len(t, sent, 3)
+	assert.Equal(t, "Backup Failed: orders-db", sent[2].Title)
+	assert.Equal(t, "mysql", sent[2].Fields["Database Type"])
+
+	attempts, total, err := dbService.GetNotificationAttempts(database.NotificationFilter{ConfigName: "ops-slack"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.True(t, attempts[0].Success)
+	assert.Equal(t, 503, attempts[2].StatusCode)
+}
+
+func TestOutbox_DeadLetters(t *testing.T) {
+	outbox, dbService := newTestOutbox(t, OutboxOptions{MaxAttempts: 2})
+	config, err := dbService.GetNotificationConfigByName("ops-slack")
+	assert.NoError(t, err)
+
+	// Temporary failures give up after MaxAttempts
+	outbox.send = func(*database.NotificationConfig, *Message) error { return errors.New("connection reset") }
+	exhausted, err := outbox.Enqueue(config, CreateBackupErrorMessage(SampleBackupData(true)))
+	assert.NoError(t, err)
+	outbox.DeliverNow()
+	makeDue(t, dbService)
+	outbox.DeliverNow()
+
+	stored, err := dbService.GetNotificationDelivery(exhausted.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, database.DeliveryStatusDead, stored.Status)
+	assert.Equal(t, 2, stored.Attempts)
+	assert.Equal(t, "connection reset", stored.LastError)
+
+	// Permanent failures give up at once
+	outbox.send = func(*database.NotificationConfig, *Message) error {
+		return &DeliveryError{StatusCode: http.StatusNotFound, Message: "slack webhook returned status 404"}
+	}
+	rejected, err := outbox.Enqueue(config, CreateBackupErrorMessage(SampleBackupData(true)))
+	assert.NoError(t, err)
+	outbox.DeliverNow()
+
+	stored, err = dbService.GetNotificationDelivery(rejected.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, database.DeliveryStatusDead, stored.Status)
+	assert.Equal(t, 1, stored.Attempts)
+
+	// A dead letter can be queued again
+	outbox.send = func(*database.NotificationConfig, *Message) error { return nil }
+	assert.NoError(t, outbox.Retry(rejected.ID))
+	outbox.DeliverNow()
+
+	stored, err = dbService.GetNotificationDelivery(rejected.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, database.DeliveryStatusDelivered, stored.Status)
+	assert.ErrorContains(t, outbox.Retry(rejected.ID), "already delivered")
+
+	// Messages of deleted configs are not sent
+	orphan, err := outbox.Enqueue(&database.NotificationConfig{Name: "deleted", Channel: "slack"}, &Message{Title: "Test"})
+	assert.NoError(t, err)
+	outbox.DeliverNow()
+	stored, err = dbService.GetNotificationDelivery(orphan.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, database.DeliveryStatusDead, stored.Status)
+	assert.Equal(t, "notification config no longer exists", stored.LastError)
+}
+
+func TestOutbox_HonorsRetryAfter(t *testing.T) {
+	outbox, dbService := newTestOutbox(t, OutboxOptions{InitialBackoff: time.Second})
+	config, err := dbService.GetNotificationConfigByName("ops-slack")
+	assert.NoError(t, err)
+
+	calls := 0
+	outbox.send = func(*database.NotificationConfig, *Message) error {
+		calls++
+		return &DeliveryError{StatusCode: http.StatusTooManyRequests, RetryAfter: 10 * time.Minute, Message: "slack webhook returned status 429"}
+	}
+
+	first, err := outbox.Enqueue(config, &Message{Title: "first"})
+	assert.NoError(t, err)
+	second, err := outbox.Enqueue(config, &Message{Title: "second"})
+	assert.NoError(t, err)
+
+	// The second message waits for the rate limit of the first without being attempted
+	assert.Equal(t, 1, outbox.DeliverNow())
+	assert.Equal(t, 1, calls)
+
+	stored, err := dbService.GetNotificationDelivery(first.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, database.DeliveryStatusPending, stored.Status)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), stored.NextAttemptAt, 5*time.Second)
+
+	stored, err = dbService.GetNotificationDelivery(second.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, database.DeliveryStatusPending, stored.Status)
+	assert.Zero(t, stored.Attempts)
+}
+
+func TestOutbox_KeepsOrderPerConfig(t *testing.T) {
+	outbox, dbService := newTestOutbox(t, OutboxOptions{})
+	config, err := dbService.GetNotificationConfigByName("ops-slack")
+	assert.NoError(t, err)
+
+	down := true
+	var sent []string
+	outbox.send = func(_ *database.NotificationConfig, message *Message) error {
+		if down {
+			return &DeliveryError{StatusCode: http.StatusServiceUnavailable, Message: "pagerduty returned status 503"}
+		}
+		sent = append(sent, message.Event)
+		return nil
+	}
+
+	// The trigger fails and waits for its retry while the next backup succeeds
+	_, err = outbox.Enqueue(config, &Message{Title: "Backup Failed", Event: EventBackupFailed})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, outbox.DeliverNow())
+
+	_, err = outbox.Enqueue(config, &Message{Title: "Backup Succeeded", Event: EventBackupRecovered})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, outbox.DeliverNow())
+
+	// Both go out in the order they were queued, in a single run
+	down = false
+	makeDue(t, dbService)
+	assert.Equal(t, 2, outbox.DeliverNow())
+	assert.Equal(t, []string{EventBackupFailed, EventBackupRecovered}, sent)
+}
+
+func TestOutbox_Backoff(t *testing.T) {
+	outbox := &Outbox{options: OutboxOptions{InitialBackoff: 30 * time.Second, MaxBackoff: 2 * time.Minute}.withDefaults()}
+
+	assert.Equal(t, 30*time.Second, outbox.backoff(1, 0))
+	assert.Equal(t, time.Minute, outbox.backoff(2, 0))
+	assert.Equal(t, 2*time.Minute, outbox.backoff(3, 0))
+	assert.Equal(t, 2*time.Minute, outbox.backoff(10, 0))
+	assert.Equal(t, 5*time.Minute, outbox.backoff(1, 5*time.Minute))
+}
+
+func TestManager_QueuesInOutbox(t *testing.T) {
+	outbox, dbService := newTestOutbox(t, OutboxOptions{})
+	manager := NewManager(dbService)
+	manager.SetOutbox(outbox)
+
+	results := manager.SendBackupErrorNotification(SampleBackupData(true))
+	assert.Len(t, results, 1)
+	assert.True(t, results[0].Queued)
+	assert.False(t, results[0].Success)
+
+	deliveries, _, err := dbService.GetNotificationDeliveries(database.NotificationFilter{ConfigName: "ops-slack"})
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, strconv.FormatUint(uint64(deliveries[0].ID), 10), results[0].MessageID)
+	assert.Equal(t, EventBackupFailed, deliveries[0].Event)
+}
+
+func TestManager_RecordsSynchronousSends(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	_, dbService := newTestOutbox(t, OutboxOptions{})
+	assert.NoError(t, dbService.SaveNotificationConfig(&database.NotificationConfig{
+		Name: "ops-slack", Channel: "slack", Config: map[string]interface{}{"webhook_url": server.URL}, NotifyOnError: true, Enabled: true,
+	}))
+
+	results := NewManager(dbService).SendBackupErrorNotification(SampleBackupData(true))
+	assert.Len(t, results, 1)
+	assert.Equal(t, "slack webhook returned status 429", results[0].Error)
+
+	attempts, _, err := dbService.GetNotificationAttempts(database.NotificationFilter{Status: "failed"})
+	assert.NoError(t, err)
+	assert.Len(t, attempts, 1)
+	assert.Equal(t, http.StatusTooManyRequests, attempts[0].StatusCode)
+	assert.Nil(t, attempts[0].DeliveryID)
+}
+
+func TestDeliveryErrors(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+	assert.Equal(t, 120*time.Second, parseRetryAfter("120", now))
+	assert.Equal(t, 1500*time.Millisecond, parseRetryAfter("1.5", now))
+	assert.Equal(t, time.Minute, parseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+
+	assert.False(t, isPermanentError(&DeliveryError{StatusCode: http.StatusTooManyRequests}))
+	assert.False(t, isPermanentError(&DeliveryError{StatusCode: http.StatusBadGateway}))
+	assert.True(t, isPermanentError(&DeliveryError{StatusCode: http.StatusForbidden}))
+	assert.False(t, isPermanentError(errors.New("dial tcp: connection refused")))
+	assert.True(t, isPermanentError(&textproto.Error{Code: 550, Msg: "mailbox unavailable"}))
+	assert.False(t, isPermanentError(&textproto.Error{Code: 451, Msg: "try again later"}))
+}
+
+func TestOutbox_WebhookEventIDStableAcrossRetries(t *testing.T) {
+	var ids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get(WebhookHeaderEventID))
+		if len(ids) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	outbox, dbService := newTestOutbox(t, OutboxOptions{})
+	assert.NoError(t, dbService.SaveNotificationConfig(&database.NotificationConfig{
+		Name: "ops-webhook", Channel: "webhook", Config: map[string]interface{}{"url": server.URL}, NotifyOnError: true, Enabled: true,
+	}))
+	config, err := dbService.GetNotificationConfigByName("ops-webhook")
+	assert.NoError(t, err)
+
+	message := createBackupMessage("webhook", SampleBackupData(true), false)
+	_, err = outbox.Enqueue(config, message)
+	assert.NoError(t, err)
+	outbox.DeliverNow()
+	makeDue(t, dbService)
+	outbox.DeliverNow()
+
+	// Receivers see one event under one ID, however often it is sent
+	assert.Len(t, ids, 2)
+	assert.Equal(t, message.ID, ids[0])
+	assert.Equal(t, ids[0], ids[1])
+}
@@ -0,0 +1,98 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// NotifierFactory creates a notifier from the settings of a notification config
+type NotifierFactory func(config map[string]interface{}) (Notifier, error)
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[NotificationChannel]NotifierFactory)
+)
+
+func init() {
+	Register(ChannelChatwork, configFactory("Chatwork", func(config ChatworkConfig) Notifier { return NewChatworkNotifier(config) }))
+	Register(ChannelDiscord, configFactory("Discord", func(config DiscordConfig) Notifier { return NewDiscordNotifier(config) }))
+	Register(ChannelSlack, configFactory("Slack", func(config SlackConfig) Notifier { return NewSlackNotifier(config) }))
+	Register(ChannelEmail, configFactory("email", func(config EmailConfig) Notifier { return NewEmailNotifier(config) }))
+	Register(ChannelTeams, configFactory("Teams", func(config TeamsConfig) Notifier { return NewTeamsNotifier(config) }))
+	Register(ChannelTelegram, configFactory("Telegram", func(config TelegramConfig) Notifier { return NewTelegramNotifier(config) }))
+	Register(ChannelWebhook, configFactory("webhook", func(config WebhookConfig) Notifier { return NewWebhookNotifier(config) }))
+	Register(ChannelPagerDuty, configFactory("PagerDuty", func(config PagerDutyConfig) Notifier { return NewPagerDutyNotifier(config) }))
+	Register(ChannelOpsgenie, configFactory("Opsgenie", func(config OpsgenieConfig) Notifier { return NewOpsgenieNotifier(config) }))
+}
+
+// Register makes a notification channel available to notification configs, e.g. an in-house chat tool.
+// It panics when the channel is empty, the factory is nil or the channel is already registered;
+// call it from an init function or before creating the backup manager.
+func Register(channel NotificationChannel, factory NotifierFactory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if channel == "" {
+		panic("notification: Register channel is empty")
+	}
+	if factory == nil {
+		panic("notification: Register factory is nil for channel " + string(channel))
+	}
+	if _, exists := registry[channel]; exists {
+		panic("notification: Register called twice for channel " + string(channel))
+	}
+	registry[channel] = factory
+}
+
+// RegisteredChannels returns the registered channels in alphabetical order
+func RegisteredChannels() []NotificationChannel {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	channels := make([]NotificationChannel, 0, len(registry))
+	for channel := range registry {
+		channels = append(channels, channel)
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i] < channels[j] })
+	return channels
+}
+
+// NewNotifier creates a notifier for a registered channel
+func NewNotifier(channel NotificationChannel, config map[string]interface{}) (Notifier, error) {
+	registryMutex.RLock()
+	factory, ok := registry[channel]
+	registryMutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported notification channel: %s", channel)
+	}
+	return factory(config)
+}
+
+// ValidateConfig checks the settings of a notification config with the notifier of its channel
+func ValidateConfig(channel NotificationChannel, config map[string]interface{}) error {
+	notifier, err := NewNotifier(channel, config)
+	if err != nil {
+		return err
+	}
+	return notifier.ValidateConfig(config)
+}
+
+// configFactory returns a factory decoding the settings into a typed config through JSON
+func configFactory[T any](label string, newNotifier func(T) Notifier) NotifierFactory {
+	return func(config map[string]interface{}) (Notifier, error) {
+		jsonData, err := json.Marshal(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s config: %w", label, err)
+		}
+
+		var typed T
+		if err := json.Unmarshal(jsonData, &typed); err != nil {
+			return nil, fmt.Errorf("failed to parse %s config: %w", label, err)
+		}
+
+		return newNotifier(typed), nil
+	}
+}
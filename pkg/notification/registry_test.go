@@ -0,0 +1,67 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingNotifier is a custom notifier registered by the tests
+type recordingNotifier struct {
+	room string
+}
+
+func (r *recordingNotifier) Send(message *Message) error { return nil }
+
+func (r *recordingNotifier) ValidateConfig(config map[string]interface{}) error {
+	if room, ok := config["room"].(string); !ok || room == "" {
+		return assert.AnError
+	}
+	return nil
+}
+
+func (r *recordingNotifier) GetChannelType() NotificationChannel { return "test-recording" }
+
+func TestRegister_CustomChannel(t *testing.T) {
+	Register("test-recording", configFactory("recording", func(config struct {
+		Room string `json:"room"`
+	}) Notifier {
+		return &recordingNotifier{room: config.Room}
+	}))
+
+	assert.Contains(t, RegisteredChannels(), NotificationChannel("test-recording"))
+
+	notifier, err := NewNotifier("test-recording", map[string]interface{}{"room": "ops"})
+	assert.NoError(t, err)
+	assert.Equal(t, "ops", notifier.(*recordingNotifier).room)
+
+	assert.NoError(t, ValidateConfig("test-recording", map[string]interface{}{"room": "ops"}))
+	assert.Error(t, ValidateConfig("test-recording", map[string]interface{}{}))
+
+	assert.Panics(t, func() {
+		Register("test-recording", func(map[string]interface{}) (Notifier, error) { return &recordingNotifier{}, nil })
+	})
+}
+
+func TestRegister_InvalidArguments(t *testing.T) {
+	factory := func(map[string]interface{}) (Notifier, error) { return &recordingNotifier{}, nil }
+	assert.Panics(t, func() { Register("", factory) })
+	assert.Panics(t, func() { Register("test-nil", nil) })
+}
+
+func TestRegisteredChannels_BuiltIn(t *testing.T) {
+	channels := RegisteredChannels()
+	for _, channel := range []NotificationChannel{
+		ChannelChatwork, ChannelDiscord, ChannelSlack, ChannelEmail, ChannelTeams,
+		ChannelTelegram, ChannelWebhook, ChannelPagerDuty, ChannelOpsgenie,
+	} {
+		assert.Contains(t, channels, channel)
+	}
+}
+
+func TestValidateConfig_Registry(t *testing.T) {
+	assert.NoError(t, ValidateConfig(ChannelWebhook, map[string]interface{}{"url": "https://example.com/hook"}))
+	assert.ErrorContains(t, ValidateConfig(ChannelWebhook, map[string]interface{}{}), "url is required")
+	assert.ErrorContains(t, ValidateConfig(ChannelSlack, map[string]interface{}{"webhook_url": 42}), "failed to parse Slack config")
+	assert.ErrorContains(t, ValidateConfig("carrier-pigeon", map[string]interface{}{}), "unsupported notification channel: carrier-pigeon")
+}
@@ -79,7 +79,7 @@ func (s *SlackNotifier) Send(message *Message) error {
 
 	// Check response
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("slack webhook returned status %d", resp.StatusCode)
+		return newStatusError(resp, "slack webhook returned status %d", resp.StatusCode)
 	}
 
 	return nil
@@ -0,0 +1,66 @@
+package notification
+
+import (
+	"fmt"
+)
+
+// CreateStorageMessage creates a message that a backup may not fit in the Drive storage left
+func CreateStorageMessage(data *StorageNotificationData) *Message {
+	fields := map[string]interface{}{
+		"Connection":     data.Connection,
+		"Projected Size": formatFileSize(data.ProjectedSize),
+		"Free Space":     formatFileSize(data.FreeBytes),
+		"Used":           fmt.Sprintf("%s of %s", formatFileSize(data.Usage), formatFileSize(data.Limit)),
+	}
+
+	// Both messages are about one backup, so its next success resolves the incident they open
+	if data.Skipped {
+		return &Message{
+			Type:       MessageTypeError,
+			Event:      EventBackupSkipped,
+			Title:      "Backup skipped: Drive storage is full",
+			Text:       fmt.Sprintf("Backup '%s' was skipped because Google Drive connection '%s' does not have room for it. Free up space or raise the storage limit.", data.ConfigName, data.Connection),
+			Fields:     fields,
+			ConfigName: data.ConfigName,
+			Timestamp:  data.CheckedAt,
+		}
+	}
+
+	return &Message{
+		Type:       MessageTypeWarning,
+		Event:      EventDriveStorageLow,
+		Title:      "Drive storage is running low",
+		Text:       fmt.Sprintf("Backup '%s' may not fit in the space left on Google Drive connection '%s'; the upload is likely to fail.", data.ConfigName, data.Connection),
+		Fields:     fields,
+		ConfigName: data.ConfigName,
+		Timestamp:  data.CheckedAt,
+	}
+}
+
+// CreateCapacityMessage creates a warning that a Drive connection is forecast to fill up
+func CreateCapacityMessage(data *CapacityNotificationData) *Message {
+	return &Message{
+		Type:  MessageTypeWarning,
+		Event: EventDriveCapacityLow,
+		Title: "Drive storage forecast",
+		Text:  fmt.Sprintf("Google Drive connection '%s' is forecast to be full in %d day(s) at the current backup growth.", data.Connection, data.DaysUntilFull),
+		Fields: map[string]interface{}{
+			"Connection":      data.Connection,
+			"Used":            fmt.Sprintf("%s of %s", formatFileSize(data.Usage), formatFileSize(data.Limit)),
+			"Daily Growth":    formatFileSize(data.DailyGrowth),
+			"Days Until Full": data.DaysUntilFull,
+		},
+		Timestamp: data.GeneratedAt,
+	}
+}
+
+// SendStorageNotification notifies every enabled config that notifies on errors about low Drive storage
+func (m *Manager) SendStorageNotification(data *StorageNotificationData) []NotificationResult {
+	return m.sendToErrorConfigs(CreateStorageMessage(data), "Drive storage")
+}
+
+// SendCapacityNotification notifies every enabled config that notifies on errors about a capacity forecast.
+// Incident channels are left out, since nothing would resolve the incident.
+func (m *Manager) SendCapacityNotification(data *CapacityNotificationData) []NotificationResult {
+	return m.sendToErrorConfigs(CreateCapacityMessage(data), "Drive capacity")
+}
@@ -0,0 +1,46 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateStorageMessage(t *testing.T) {
+	data := &StorageNotificationData{
+		Connection:    "client-a",
+		ConfigName:    "orders",
+		ProjectedSize: 2 * 1024 * 1024,
+		FreeBytes:     1024 * 1024,
+		Limit:         1024 * 1024 * 1024,
+		Usage:         1023 * 1024 * 1024,
+		CheckedAt:     time.Now(),
+	}
+
+	message := CreateStorageMessage(data)
+	assert.Equal(t, MessageTypeWarning, message.Type)
+	assert.Contains(t, message.Text, "client-a")
+	assert.Equal(t, "2.0 MB", message.Fields["Projected Size"])
+	assert.Equal(t, "1.0 MB", message.Fields["Free Space"])
+
+	data.Skipped = true
+	message = CreateStorageMessage(data)
+	assert.Equal(t, MessageTypeError, message.Type)
+	assert.Contains(t, message.Title, "skipped")
+}
+
+func TestCreateCapacityMessage(t *testing.T) {
+	message := CreateCapacityMessage(&CapacityNotificationData{
+		Connection:    "client-a",
+		Limit:         1024 * 1024 * 1024,
+		Usage:         512 * 1024 * 1024,
+		DailyGrowth:   64 * 1024 * 1024,
+		DaysUntilFull: 8,
+		GeneratedAt:   time.Now(),
+	})
+	assert.Equal(t, MessageTypeWarning, message.Type)
+	assert.Contains(t, message.Text, "8 day(s)")
+	assert.Equal(t, 8, message.Fields["Days Until Full"])
+	assert.Equal(t, "64.0 MB", message.Fields["Daily Growth"])
+}
@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vfa-khuongdv/lazy/internal/database"
+)
+
+// DefaultRateWindow is the rate limit window used when none is set
+const DefaultRateWindow = time.Hour
+
+// SuppressionRules limits repeated failure alerts of a backup config to a notification config.
+// The first failure is always notified, later ones only every RepeatEvery failures or once
+// RepeatAfter has passed since the last alert; zero values disable a rule.
+type SuppressionRules = database.NotificationSuppression
+
+// SuppressionOptions enables alert suppression for all notification configs
+type SuppressionOptions struct {
+	Defaults   *SuppressionRules // rules of notification configs without their own; nil notifies every failure
+	RateLimit  int               // most notifications each channel sends per RateWindow, 0 for no limit
+	RateWindow time.Duration     // defaults to DefaultRateWindow
+}
+
+// ValidateSuppressionRules checks that no rule is negative
+func ValidateSuppressionRules(rules *SuppressionRules) error {
+	if rules == nil {
+		return nil
+	}
+	if rules.RepeatEvery < 0 {
+		return fmt.Errorf("suppression repeat_every must not be negative")
+	}
+	if rules.RepeatAfter < 0 {
+		return fmt.Errorf("suppression repeat_after must not be negative")
+	}
+	return nil
+}
+
+// failureStreak tracks the consecutive failures of a backup config
+type failureStreak struct {
+	failures int
+	since    time.Time
+	notified map[string]time.Time // last alert per notification config
+}
+
+// alerted returns when a notification config was last alerted about the streak; it is safe on a nil streak
+func (f *failureStreak) alerted(configName string) (time.Time, bool) {
+	if f == nil {
+		return time.Time{}, false
+	}
+	at, ok := f.notified[configName]
+	return at, ok
+}
+
+// suppressor decides which backup alerts are sent and enforces the channel rate limits.
+// Its state is kept in memory; Manager.RestoreFailureStreaks rebuilds the streaks after a restart.
+type suppressor struct {
+	options SuppressionOptions
+
+	mutex   sync.Mutex
+	streaks map[string]*failureStreak // by backup config name
+	sent    map[string][]time.Time    // recent sends by channel
+}
+
+func newSuppressor(options SuppressionOptions) *suppressor {
+	if options.RateWindow <= 0 {
+		options.RateWindow = DefaultRateWindow
+	}
+	return &suppressor{
+		options: options,
+		streaks: make(map[string]*failureStreak),
+		sent:    make(map[string][]time.Time),
+	}
+}
+
+// recordFailure counts a failed backup and returns the number of failures in a row
+func (s *suppressor) recordFailure(configName string, now time.Time) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	streak, ok := s.streaks[configName]
+	if !ok {
+		streak = &failureStreak{since: now, notified: make(map[string]time.Time)}
+		s.streaks[configName] = streak
+	}
+	streak.failures++
+	return streak.failures
+}
+
+// restoreStreak sets the failure streak of a backup config, e.g. from its history after a restart,
+// taking the given notification configs as alerted when the streak started
+func (s *suppressor) restoreStreak(configName string, failures int, since time.Time, alerted []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	streak := &failureStreak{failures: failures, since: since, notified: make(map[string]time.Time, len(alerted))}
+	for _, name := range alerted {
+		streak.notified[name] = since
+	}
+	s.streaks[configName] = streak
+}
+
+// recordSuccess ends the failure streak of a backup config and returns it, nil when there was none
+func (s *suppressor) recordSuccess(configName string) *failureStreak {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	streak := s.streaks[configName]
+	delete(s.streaks, configName)
+	return streak
+}
+
+// shouldAlert reports whether the current failure of a backup config is notified to a notification config
+func (s *suppressor) shouldAlert(configName string, config *database.NotificationConfig, now time.Time) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	streak, ok := s.streaks[configName]
+	if !ok {
+		return true
+	}
+
+	rules := config.Suppression
+	if rules == nil {
+		rules = s.options.Defaults
+	}
+
+	lastAlert, alerted := streak.notified[config.Name]
+	return !alerted || rules == nil ||
+		(rules.RepeatEvery > 0 && (streak.failures-1)%rules.RepeatEvery == 0) ||
+		(rules.RepeatAfter > 0 && now.Sub(lastAlert) >= rules.RepeatAfter)
+}
+
+// markAlerted remembers that a notification config was alerted about the failure streak of a backup config
+func (s *suppressor) markAlerted(configName, notificationConfig string, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if streak, ok := s.streaks[configName]; ok {
+		streak.notified[notificationConfig] = now
+	}
+}
+
+// allowSend reports whether a channel is below its rate limit and counts the send when it is.
+// Forced sends, such as recoveries, are always allowed but still count.
+func (s *suppressor) allowSend(channel string, now time.Time, force bool) bool {
+	if s.options.RateLimit <= 0 {
+		return true
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Drop the sends that left the window
+	recent := s.sent[channel]
+	cutoff := now.Add(-s.options.RateWindow)
+	for len(recent) > 0 && !recent[0].After(cutoff) {
+		recent = recent[1:]
+	}
+
+	if len(recent) >= s.options.RateLimit && !force {
+		s.sent[channel] = recent
+		return false
+	}
+	s.sent[channel] = append(recent, now)
+	return true
+}
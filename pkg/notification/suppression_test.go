@@ -0,0 +1,228 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vfa-khuongdv/lazy/internal/database"
+)
+
+func TestSuppressor_RepeatRules(t *testing.T) {
+	start := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	alerts := newSuppressor(SuppressionOptions{Defaults: &SuppressionRules{RepeatEvery: 3}})
+
+	everyThird := &database.NotificationConfig{Name: "ops-slack"}
+	hourly := &database.NotificationConfig{Name: "ops-mail", Suppression: &SuppressionRules{RepeatAfter: 2 * time.Hour}}
+	onlyFirst := &database.NotificationConfig{Name: "ops-chat", Suppression: &SuppressionRules{}}
+
+	// alert sends when the rules allow it, as the manager does when no rate limit drops the send
+	alert := func(configName string, config *database.NotificationConfig, now time.Time) bool {
+		if !alerts.shouldAlert(configName, config, now) {
+			return false
+		}
+		alerts.markAlerted(configName, config.Name, now)
+		return true
+	}
+
+	var third, afterHours, first []bool
+	for i := 0; i < 7; i++ {
+		now := start.Add(time.Duration(i) * time.Hour)
+		assert.Equal(t, i+1, alerts.recordFailure("orders-db", now))
+		third = append(third, alert("orders-db", everyThird, now))
+		afterHours = append(afterHours, alert("orders-db", hourly, now))
+		first = append(first, alert("orders-db", onlyFirst, now))
+	}
+
+	assert.Equal(t, []bool{true, false, false, true, false, false, true}, third)
+	assert.Equal(t, []bool{true, false, true, false, true, false, true}, afterHours)
+	assert.Equal(t, []bool{true, false, false, false, false, false, false}, first)
+
+	// Other backup configs have their own streak
+	alerts.recordFailure("users-db", start)
+	assert.True(t, alert("users-db", onlyFirst, start))
+
+	// A success ends the streak and tells who was alerted
+	streak := alerts.recordSuccess("orders-db")
+	assert.Equal(t, 7, streak.failures)
+	_, alerted := streak.alerted("ops-chat")
+	assert.True(t, alerted)
+	assert.Nil(t, alerts.recordSuccess("orders-db"))
+
+	// Without rules every failure is notified
+	unlimited := newSuppressor(SuppressionOptions{})
+	for i := 0; i < 3; i++ {
+		unlimited.recordFailure("orders-db", start)
+		assert.True(t, unlimited.shouldAlert("orders-db", everyThird, start))
+		unlimited.markAlerted("orders-db", everyThird.Name, start)
+	}
+}
+
+func TestSuppressor_RateLimit(t *testing.T) {
+	start := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	alerts := newSuppressor(SuppressionOptions{RateLimit: 2, RateWindow: time.Hour})
+
+	assert.True(t, alerts.allowSend("slack", start, false))
+	assert.True(t, alerts.allowSend("slack", start.Add(time.Minute), false))
+	assert.False(t, alerts.allowSend("slack", start.Add(2*time.Minute), false))
+
+	// Channels are limited separately and forced sends always pass
+	assert.True(t, alerts.allowSend("email", start.Add(2*time.Minute), false))
+	assert.True(t, alerts.allowSend("slack", start.Add(3*time.Minute), true))
+
+	// The window slides: the first send drops out after an hour, but the forced one still counts
+	assert.False(t, alerts.allowSend("slack", start.Add(time.Hour), false))
+	assert.True(t, alerts.allowSend("slack", start.Add(time.Hour+2*time.Minute), false))
+
+	assert.True(t, newSuppressor(SuppressionOptions{}).allowSend("slack", start, false))
+}
+
+func TestValidateSuppressionRules(t *testing.T) {
+	assert.NoError(t, ValidateSuppressionRules(nil))
+	assert.NoError(t, ValidateSuppressionRules(&SuppressionRules{RepeatEvery: 4, RepeatAfter: time.Hour}))
+	assert.ErrorContains(t, ValidateSuppressionRules(&SuppressionRules{RepeatEvery: -1}), "repeat_every")
+	assert.ErrorContains(t, ValidateSuppressionRules(&SuppressionRules{RepeatAfter: -time.Hour}), "repeat_after")
+}
+
+func TestManager_SuppressesRepeatedFailures(t *testing.T) {
+	outbox, dbService := newTestOutbox(t, OutboxOptions{})
+	manager := NewManager(dbService)
+	manager.SetOutbox(outbox)
+	manager.SetSuppression(SuppressionOptions{Defaults: &SuppressionRules{RepeatEvery: 4}})
+
+	// An hourly backup failing all night only alerts on the first and fifth failure
+	var suppressed []bool
+	for i := 0; i < 8; i++ {
+		results := manager.SendBackupErrorNotification(SampleBackupData(true))
+		assert.Len(t, results, 1)
+		suppressed = append(suppressed, results[0].Suppressed)
+	}
+	assert.Equal(t, []bool{false, true, true, true, false, true, true, true}, suppressed)
+
+	deliveries, total, err := dbService.GetNotificationDeliveries(database.NotificationFilter{Event: EventBackupFailed})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Contains(t, deliveries[0].Payload, `"Consecutive Failures":5`)
+
+	// The recovery reaches the alerted config even though it does not notify on success
+	results := manager.SendBackupSuccessNotification(SampleBackupData(false))
+	assert.Len(t, results, 1)
+	assert.True(t, results[0].Queued)
+
+	deliveries, _, err = dbService.GetNotificationDeliveries(database.NotificationFilter{Event: EventBackupRecovered})
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Contains(t, deliveries[0].Payload, "8 failed backups")
+
+	// Later successes are not sent
+	assert.Empty(t, manager.SendBackupSuccessNotification(SampleBackupData(false)))
+}
+
+func TestManager_RateLimitsChannels(t *testing.T) {
+	outbox, dbService := newTestOutbox(t, OutboxOptions{})
+	manager := NewManager(dbService)
+	manager.SetOutbox(outbox)
+	manager.SetSuppression(SuppressionOptions{RateLimit: 1})
+
+	data := SampleBackupData(true)
+	data.ConfigName = "orders-db"
+	assert.False(t, manager.SendBackupErrorNotification(data)[0].Suppressed)
+
+	data = SampleBackupData(true)
+	data.ConfigName = "users-db"
+	assert.True(t, manager.SendBackupErrorNotification(data)[0].Suppressed)
+
+	// Recoveries are sent over the limit
+	data = SampleBackupData(false)
+	data.ConfigName = "orders-db"
+	results := manager.SendBackupSuccessNotification(data)
+	assert.Len(t, results, 1)
+	assert.False(t, results[0].Suppressed)
+	assert.True(t, results[0].Queued)
+}
+
+func TestManager_RateLimitedAlertIsRetried(t *testing.T) {
+	outbox, dbService := newTestOutbox(t, OutboxOptions{})
+	manager := NewManager(dbService)
+	manager.SetOutbox(outbox)
+	manager.SetSuppression(SuppressionOptions{Defaults: &SuppressionRules{RepeatEvery: 10}, RateLimit: 1})
+
+	// Another backup uses up the channel's limit
+	other := SampleBackupData(true)
+	other.ConfigName = "users-db"
+	assert.False(t, manager.SendBackupErrorNotification(other)[0].Suppressed)
+
+	// The dropped first alert of orders-db does not count, so its next failure is alerted once the window frees up
+	data := SampleBackupData(true)
+	data.ConfigName = "orders-db"
+	assert.True(t, manager.SendBackupErrorNotification(data)[0].Suppressed)
+
+	manager.getSuppressor().sent = make(map[string][]time.Time)
+	data = SampleBackupData(true)
+	data.ConfigName = "orders-db"
+	assert.False(t, manager.SendBackupErrorNotification(data)[0].Suppressed)
+
+	// Later failures follow the repeat rule again
+	data = SampleBackupData(true)
+	data.ConfigName = "orders-db"
+	manager.getSuppressor().sent = make(map[string][]time.Time)
+	assert.True(t, manager.SendBackupErrorNotification(data)[0].Suppressed)
+
+	deliveries, _, err := dbService.GetNotificationDeliveries(database.NotificationFilter{Event: EventBackupFailed})
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 2)
+}
+
+func TestManager_NoRecoveryWithoutDeliveredAlert(t *testing.T) {
+	outbox, dbService := newTestOutbox(t, OutboxOptions{})
+	manager := NewManager(dbService)
+	manager.SetOutbox(outbox)
+	manager.SetSuppression(SuppressionOptions{Defaults: &SuppressionRules{}, RateLimit: 1})
+
+	other := SampleBackupData(true)
+	other.ConfigName = "users-db"
+	manager.SendBackupErrorNotification(other)
+
+	data := SampleBackupData(true)
+	data.ConfigName = "orders-db"
+	assert.True(t, manager.SendBackupErrorNotification(data)[0].Suppressed)
+
+	// Nobody heard about the failure, so there is nothing to recover from
+	data = SampleBackupData(false)
+	data.ConfigName = "orders-db"
+	assert.Empty(t, manager.SendBackupSuccessNotification(data))
+}
+
+func TestManager_RestoresFailureStreaks(t *testing.T) {
+	outbox, dbService := newTestOutbox(t, OutboxOptions{})
+	assert.NoError(t, dbService.SaveBackupConfig(&database.BackupConfig{
+		Name: "orders-db", BackupMode: "full", DatabaseURL: "mysql://localhost/orders", DatabaseType: "mysql", CronSchedule: "0 0 * * * *", Enabled: true,
+	}))
+	start := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	for i, status := range []string{database.BackupStatusFailed, database.BackupStatusSuccess, database.BackupStatusFailed,
+		database.BackupStatusSkipped, database.BackupStatusFailed, database.BackupStatusInProgress} {
+		assert.NoError(t, dbService.SaveBackupHistory(&database.BackupHistory{
+			ConfigName: "orders-db", DatabaseURL: "mysql://localhost/orders", BackupType: "mysql", FileName: "orders.sql",
+			Status: status, StartedAt: start.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	// After a restart the streak of two failures since the last success carries on
+	manager := NewManager(dbService)
+	manager.SetOutbox(outbox)
+	manager.SetSuppression(SuppressionOptions{Defaults: &SuppressionRules{RepeatEvery: 3}})
+	assert.NoError(t, manager.RestoreFailureStreaks())
+
+	results := manager.SendBackupErrorNotification(SampleBackupData(true))
+	assert.True(t, results[0].Suppressed, "third failure")
+	results = manager.SendBackupErrorNotification(SampleBackupData(true))
+	assert.False(t, results[0].Suppressed, "fourth failure")
+
+	// The recovery is still sent
+	results = manager.SendBackupSuccessNotification(SampleBackupData(false))
+	assert.Len(t, results, 1)
+	deliveries, _, err := dbService.GetNotificationDeliveries(database.NotificationFilter{Event: EventBackupRecovered})
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Contains(t, deliveries[0].Payload, "4 failed backups")
+}
@@ -0,0 +1,280 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TeamsNotifier implements the Notifier interface for Microsoft Teams
+type TeamsNotifier struct {
+	config TeamsConfig
+}
+
+// NewTeamsNotifier creates a new Microsoft Teams notifier
+func NewTeamsNotifier(config TeamsConfig) *TeamsNotifier {
+	return &TeamsNotifier{
+		config: config,
+	}
+}
+
+// TeamsWebhookPayload represents the message posted to a Teams incoming webhook or Workflows URL
+type TeamsWebhookPayload struct {
+	Type        string            `json:"type"`
+	Attachments []TeamsAttachment `json:"attachments"`
+}
+
+// TeamsAttachment wraps an Adaptive Card
+type TeamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	ContentURL  *string      `json:"contentUrl"`
+	Content     AdaptiveCard `json:"content"`
+}
+
+// AdaptiveCard represents an Adaptive Card
+type AdaptiveCard struct {
+	Schema  string                 `json:"$schema"`
+	Type    string                 `json:"type"`
+	Version string                 `json:"version"`
+	Body    []AdaptiveCardElement  `json:"body"`
+	Actions []AdaptiveCardAction   `json:"actions,omitempty"`
+	MSTeams map[string]interface{} `json:"msteams,omitempty"`
+}
+
+// AdaptiveCardElement represents a TextBlock, FactSet or Container of an Adaptive Card
+type AdaptiveCardElement struct {
+	Type     string                `json:"type"`
+	Text     string                `json:"text,omitempty"`
+	Weight   string                `json:"weight,omitempty"`
+	Size     string                `json:"size,omitempty"`
+	Color    string                `json:"color,omitempty"`
+	IsSubtle bool                  `json:"isSubtle,omitempty"`
+	Wrap     bool                  `json:"wrap,omitempty"`
+	Style    string                `json:"style,omitempty"`
+	Bleed    bool                  `json:"bleed,omitempty"`
+	Items    []AdaptiveCardElement `json:"items,omitempty"`
+	Facts    []AdaptiveCardFact    `json:"facts,omitempty"`
+}
+
+// AdaptiveCardFact represents a fact in a FactSet
+type AdaptiveCardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// AdaptiveCardAction represents an Action.OpenUrl button
+type AdaptiveCardAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Send sends a notification message to Microsoft Teams
+func (t *TeamsNotifier) Send(message *Message) error {
+	// Create Teams payload
+	payload := t.createPayload(message)
+
+	// Marshal to JSON
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Teams payload: %w", err)
+	}
+
+	// Create HTTP request
+	req, err := http.NewRequest("POST", t.config.WebhookURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DB-Backup-GDrive/1.0")
+
+	// Send request
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Incoming webhooks answer 200, Workflows answer 202
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newStatusError(resp, "teams webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// ValidateConfig validates the Microsoft Teams configuration
+func (t *TeamsNotifier) ValidateConfig(config map[string]interface{}) error {
+	webhookURL, ok := config["webhook_url"].(string)
+	if !ok || webhookURL == "" {
+		return fmt.Errorf("webhook_url is required for Teams")
+	}
+
+	if parsed, err := url.Parse(webhookURL); err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return fmt.Errorf("webhook_url must be an https URL for Teams")
+	}
+
+	return nil
+}
+
+// GetChannelType returns the notification channel type
+func (t *TeamsNotifier) GetChannelType() NotificationChannel {
+	return ChannelTeams
+}
+
+// createPayload creates an Adaptive Card payload from a message.
+// Fields holding a bare URL become buttons, the others are listed in a FactSet.
+func (t *TeamsNotifier) createPayload(message *Message) *TeamsWebhookPayload {
+	style, color := t.getStyleForType(message.Type)
+
+	header := AdaptiveCardElement{
+		Type:  "Container",
+		Style: style,
+		Bleed: true,
+		Items: []AdaptiveCardElement{
+			{Type: "TextBlock", Text: message.Title, Weight: "Bolder", Size: "Medium", Color: color, Wrap: true},
+		},
+	}
+	body := []AdaptiveCardElement{header}
+
+	if message.Text != "" {
+		body = append(body, AdaptiveCardElement{Type: "TextBlock", Text: message.Text, Wrap: true})
+	}
+
+	names := make([]string, 0, len(message.Fields))
+	for name := range message.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var facts []AdaptiveCardFact
+	var actions []AdaptiveCardAction
+	for _, name := range names {
+		value := fmt.Sprintf("%v", message.Fields[name])
+		if isLinkValue(value) {
+			actions = append(actions, AdaptiveCardAction{Type: "Action.OpenUrl", Title: name, URL: value})
+			continue
+		}
+		facts = append(facts, AdaptiveCardFact{Title: name, Value: value})
+	}
+
+	// Add configuration name if present
+	if message.ConfigName != "" {
+		facts = append(facts, AdaptiveCardFact{Title: "Configuration", Value: message.ConfigName})
+	}
+	if len(facts) > 0 {
+		body = append(body, AdaptiveCardElement{Type: "FactSet", Facts: facts})
+	}
+
+	body = append(body, AdaptiveCardElement{
+		Type:     "TextBlock",
+		Text:     fmt.Sprintf("Database Backup Service · %s", message.Timestamp.Format("2006-01-02 15:04:05 MST")),
+		Size:     "Small",
+		IsSubtle: true,
+		Wrap:     true,
+	})
+
+	return &TeamsWebhookPayload{
+		Type: "message",
+		Attachments: []TeamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: AdaptiveCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body:    body,
+				Actions: actions,
+				MSTeams: map[string]interface{}{"width": "Full"},
+			},
+		}},
+	}
+}
+
+// getStyleForType returns the header container style and title color for the message type
+func (t *TeamsNotifier) getStyleForType(msgType MessageType) (string, string) {
+	switch msgType {
+	case MessageTypeSuccess:
+		return "good", "Good"
+	case MessageTypeError:
+		return "attention", "Attention"
+	case MessageTypeWarning:
+		return "warning", "Warning"
+	case MessageTypeInfo:
+		return "accent", "Accent"
+	default:
+		return "default", "Default"
+	}
+}
+
+// isLinkValue reports whether a field value is a bare http(s) URL
+func isLinkValue(value string) bool {
+	if !strings.HasPrefix(value, "https://") && !strings.HasPrefix(value, "http://") {
+		return false
+	}
+	parsed, err := url.Parse(value)
+	return err == nil && parsed.Host != "" && !strings.ContainsAny(value, " \n")
+}
+
+// CreateTeamsBackupSuccessMessage creates a Teams-optimized success message
+func CreateTeamsBackupSuccessMessage(data *BackupNotificationData) *Message {
+	duration := data.CompletedAt.Sub(data.StartedAt)
+
+	fields := map[string]interface{}{
+		"Database Type": data.DatabaseType,
+		"File Name":     data.FileName,
+		"File Size":     formatFileSize(data.BackupSize),
+		"Duration":      duration.Round(time.Second).String(),
+	}
+
+	// Rendered as an "Open in Google Drive" button
+	if data.WebViewLink != "" {
+		fields["Open in Google Drive"] = data.WebViewLink
+	}
+
+	return &Message{
+		Type:       MessageTypeSuccess,
+		Title:      fmt.Sprintf("✅ Backup Completed: %s", data.ConfigName),
+		Text:       fmt.Sprintf("Database backup completed successfully for **%s**", data.ConfigName),
+		Fields:     fields,
+		Timestamp:  data.CompletedAt,
+		ConfigName: data.ConfigName,
+	}
+}
+
+// CreateTeamsBackupErrorMessage creates a Teams-optimized error message
+func CreateTeamsBackupErrorMessage(data *BackupNotificationData) *Message {
+	var duration time.Duration
+	if !data.CompletedAt.IsZero() {
+		duration = data.CompletedAt.Sub(data.StartedAt)
+	} else {
+		duration = time.Since(data.StartedAt)
+	}
+
+	fields := map[string]interface{}{
+		"Database Type": data.DatabaseType,
+		"Duration":      duration.Round(time.Second).String(),
+		"Error":         data.ErrorMessage,
+	}
+
+	completedAt := data.CompletedAt
+	if completedAt.IsZero() {
+		completedAt = time.Now()
+	}
+
+	return &Message{
+		Type:       MessageTypeError,
+		Title:      fmt.Sprintf("❌ Backup Failed: %s", data.ConfigName),
+		Text:       fmt.Sprintf("Database backup failed for **%s**", data.ConfigName),
+		Fields:     fields,
+		Timestamp:  completedAt,
+		ConfigName: data.ConfigName,
+	}
+}
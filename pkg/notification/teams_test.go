@@ -0,0 +1,167 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeamsNotifier_GetChannelType(t *testing.T) {
+	notifier := NewTeamsNotifier(TeamsConfig{WebhookURL: "https://example.webhook.office.com/webhookb2/test"})
+	assert.Equal(t, ChannelTeams, notifier.GetChannelType())
+}
+
+func TestTeamsNotifier_ValidateConfig(t *testing.T) {
+	notifier := &TeamsNotifier{}
+
+	tests := []struct {
+		name        string
+		config      map[string]interface{}
+		expectError bool
+	}{
+		{
+			name:   "incoming webhook",
+			config: map[string]interface{}{"webhook_url": "https://example.webhook.office.com/webhookb2/test"},
+		},
+		{
+			name:   "workflows URL",
+			config: map[string]interface{}{"webhook_url": "https://prod-00.westus.logic.azure.com:443/workflows/abc/triggers/manual/paths/invoke?sig=xyz"},
+		},
+		{
+			name:        "missing webhook_url",
+			config:      map[string]interface{}{},
+			expectError: true,
+		},
+		{
+			name:        "plain http",
+			config:      map[string]interface{}{"webhook_url": "http://example.webhook.office.com/webhookb2/test"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := notifier.ValidateConfig(tt.config)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTeamsNotifier_Send(t *testing.T) {
+	tests := []struct {
+		name           string
+		serverResponse int
+		expectError    bool
+	}{
+		{name: "incoming webhook", serverResponse: http.StatusOK},
+		{name: "workflows", serverResponse: http.StatusAccepted},
+		{name: "bad request", serverResponse: http.StatusBadRequest, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "POST", r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+				var payload TeamsWebhookPayload
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+				assert.Equal(t, "message", payload.Type)
+				assert.Len(t, payload.Attachments, 1)
+				assert.Equal(t, "application/vnd.microsoft.card.adaptive", payload.Attachments[0].ContentType)
+				assert.Equal(t, "AdaptiveCard", payload.Attachments[0].Content.Type)
+
+				w.WriteHeader(tt.serverResponse)
+			}))
+			defer server.Close()
+
+			notifier := NewTeamsNotifier(TeamsConfig{WebhookURL: server.URL})
+			err := notifier.Send(&Message{Type: MessageTypeInfo, Title: "Test", Text: "Test message", Timestamp: time.Now()})
+			if tt.expectError {
+				assert.ErrorContains(t, err, "teams webhook returned status 400")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTeamsNotifier_createPayload(t *testing.T) {
+	notifier := NewTeamsNotifier(TeamsConfig{})
+
+	payload := notifier.createPayload(&Message{
+		Type:  MessageTypeError,
+		Title: "Backup Failed: orders",
+		Text:  "Database backup failed for **orders**",
+		Fields: map[string]interface{}{
+			"Error":     "connection refused",
+			"Attempts":  3,
+			"Authorize": "https://accounts.google.com/o/oauth2/auth?state=abc",
+		},
+		Timestamp:  time.Now(),
+		ConfigName: "orders",
+	})
+
+	card := payload.Attachments[0].Content
+	header := card.Body[0]
+	assert.Equal(t, "Container", header.Type)
+	assert.Equal(t, "attention", header.Style)
+	assert.Equal(t, "Attention", header.Items[0].Color)
+	assert.Equal(t, "Backup Failed: orders", header.Items[0].Text)
+	assert.Equal(t, "Database backup failed for **orders**", card.Body[1].Text)
+
+	// Facts are sorted, URLs become buttons
+	factSet := card.Body[2]
+	assert.Equal(t, "FactSet", factSet.Type)
+	assert.Equal(t, []AdaptiveCardFact{
+		{Title: "Attempts", Value: "3"},
+		{Title: "Error", Value: "connection refused"},
+		{Title: "Configuration", Value: "orders"},
+	}, factSet.Facts)
+	assert.Equal(t, []AdaptiveCardAction{
+		{Type: "Action.OpenUrl", Title: "Authorize", URL: "https://accounts.google.com/o/oauth2/auth?state=abc"},
+	}, card.Actions)
+
+	for msgType, style := range map[MessageType]string{
+		MessageTypeSuccess: "good",
+		MessageTypeWarning: "warning",
+		MessageTypeInfo:    "accent",
+	} {
+		payload := notifier.createPayload(&Message{Type: msgType, Title: "Test", Timestamp: time.Now()})
+		assert.Equal(t, style, payload.Attachments[0].Content.Body[0].Style)
+	}
+}
+
+func TestCreateTeamsBackupMessages(t *testing.T) {
+	startedAt := time.Now().Add(-90 * time.Second)
+	data := &BackupNotificationData{
+		ConfigName:   "orders",
+		DatabaseType: "mysql",
+		BackupSize:   2048,
+		FileName:     "orders.sql",
+		WebViewLink:  "https://drive.google.com/file/d/abc/view",
+		StartedAt:    startedAt,
+		CompletedAt:  startedAt.Add(90 * time.Second),
+	}
+
+	message := CreateTeamsBackupSuccessMessage(data)
+	assert.Equal(t, MessageTypeSuccess, message.Type)
+	assert.Contains(t, message.Title, "orders")
+	assert.Equal(t, "2.0 KB", message.Fields["File Size"])
+	assert.Equal(t, "1m30s", message.Fields["Duration"])
+	assert.Equal(t, data.WebViewLink, message.Fields["Open in Google Drive"])
+
+	data.ErrorMessage = "connection refused"
+	message = CreateTeamsBackupErrorMessage(data)
+	assert.Equal(t, MessageTypeError, message.Type)
+	assert.Equal(t, "connection refused", message.Fields["Error"])
+	assert.NotContains(t, message.Fields, "Open in Google Drive")
+}
@@ -0,0 +1,292 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Telegram parse modes
+const (
+	TelegramParseModeHTML       = "HTML"
+	TelegramParseModeMarkdownV2 = "MarkdownV2"
+)
+
+// telegramAPIURL is the Bot API endpoint, with the bot token appended
+const telegramAPIURL = "https://api.telegram.org/bot"
+
+// telegramMaxMessageLength is the longest text sendMessage accepts, in UTF-16 code units
+const telegramMaxMessageLength = 4096
+
+// telegramMarkdownV2Escaper escapes every character MarkdownV2 reserves outside of entities
+var telegramMarkdownV2Escaper = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`, "`", "\\`",
+	">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// TelegramNotifier implements the Notifier interface for Telegram bots
+type TelegramNotifier struct {
+	config TelegramConfig
+	apiURL string
+}
+
+// NewTelegramNotifier creates a new Telegram notifier
+func NewTelegramNotifier(config TelegramConfig) *TelegramNotifier {
+	return &TelegramNotifier{
+		config: config,
+		apiURL: telegramAPIURL,
+	}
+}
+
+// TelegramSendMessageRequest represents the body of a sendMessage call
+type TelegramSendMessageRequest struct {
+	ChatID                string `json:"chat_id"`
+	MessageThreadID       int    `json:"message_thread_id,omitempty"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+// telegramResponse represents the envelope of a Bot API response
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description,omitempty"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after,omitempty"` // seconds to wait after a 429
+	} `json:"parameters,omitempty"`
+}
+
+// Send sends a notification message to the Telegram chat
+func (t *TelegramNotifier) Send(message *Message) error {
+	request := &TelegramSendMessageRequest{
+		ChatID:                t.config.ChatID,
+		MessageThreadID:       t.config.MessageThreadID,
+		Text:                  t.formatMessage(message),
+		ParseMode:             t.parseMode(),
+		DisableWebPagePreview: true,
+	}
+
+	// Marshal to JSON
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Telegram request: %w", err)
+	}
+
+	// Create HTTP request
+	req, err := http.NewRequest("POST", t.apiURL+t.config.BotToken+"/sendMessage", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DB-Backup-GDrive/1.0")
+
+	// Send request
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The request URL holds the bot token, keep it out of the error
+		return fmt.Errorf("failed to send request to Telegram")
+	}
+	defer resp.Body.Close()
+
+	// Check response
+	var response telegramResponse
+	json.NewDecoder(resp.Body).Decode(&response)
+	if resp.StatusCode != http.StatusOK || !response.OK {
+		err := newStatusError(resp, "telegram API returned status %d", resp.StatusCode)
+		if response.Description != "" {
+			err.Message += ": " + response.Description
+		}
+		if response.Parameters.RetryAfter > 0 {
+			err.RetryAfter = time.Duration(response.Parameters.RetryAfter) * time.Second
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ValidateConfig validates the Telegram configuration
+func (t *TelegramNotifier) ValidateConfig(config map[string]interface{}) error {
+	botToken, ok := config["bot_token"].(string)
+	if !ok || botToken == "" {
+		return fmt.Errorf("bot_token is required for Telegram")
+	}
+
+	chatID, ok := config["chat_id"].(string)
+	if !ok || chatID == "" {
+		return fmt.Errorf("chat_id is required for Telegram")
+	}
+
+	// JSON configs decode numbers as float64, configs declared in code may hold an int
+	if threadID, ok := config["message_thread_id"]; ok {
+		switch id := threadID.(type) {
+		case int, int64:
+		case float64:
+			if id != math.Trunc(id) {
+				return fmt.Errorf("message_thread_id must be an integer for Telegram")
+			}
+		default:
+			return fmt.Errorf("message_thread_id must be an integer for Telegram")
+		}
+	}
+
+	switch parseMode, _ := config["parse_mode"].(string); parseMode {
+	case "", TelegramParseModeHTML, TelegramParseModeMarkdownV2:
+	default:
+		return fmt.Errorf("parse_mode must be %s or %s for Telegram", TelegramParseModeHTML, TelegramParseModeMarkdownV2)
+	}
+
+	return nil
+}
+
+// GetChannelType returns the notification channel type
+func (t *TelegramNotifier) GetChannelType() NotificationChannel {
+	return ChannelTelegram
+}
+
+// parseMode returns the configured parse mode, HTML by default
+func (t *TelegramNotifier) parseMode() string {
+	if t.config.ParseMode == "" {
+		return TelegramParseModeHTML
+	}
+	return t.config.ParseMode
+}
+
+// formatMessage formats a message for Telegram, escaping every value for the parse mode.
+// Messages over Telegram's length limit, typically with a dump's error output, have their longest
+// values shortened before escaping, so no escape sequence or tag is cut in half.
+func (t *TelegramNotifier) formatMessage(message *Message) string {
+	text := t.renderMessage(message)
+	for telegramLength(text) > telegramMaxMessageLength {
+		shortened, ok := t.shortenLongestValue(message)
+		if !ok {
+			break
+		}
+		message = shortened
+		text = t.renderMessage(message)
+	}
+	return text
+}
+
+// renderMessage formats a message for Telegram without a length limit
+func (t *TelegramNotifier) renderMessage(message *Message) string {
+	escape, bold, italic := html.EscapeString, "<b>%s</b>", "<i>%s</i>"
+	if t.parseMode() == TelegramParseModeMarkdownV2 {
+		escape, bold, italic = telegramMarkdownV2Escaper.Replace, "*%s*", "_%s_"
+	}
+
+	var builder strings.Builder
+
+	// === Title Section ===
+	builder.WriteString(fmt.Sprintf(bold, escape(t.getEmojiForType(message.Type)+" "+message.Title)))
+	builder.WriteString("\n")
+
+	// === Body Section ===
+	if message.Text != "" {
+		builder.WriteString("\n" + escape(message.Text) + "\n")
+	}
+
+	// === Fields Section ===
+	names := make([]string, 0, len(message.Fields))
+	for name := range message.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) > 0 || message.ConfigName != "" {
+		builder.WriteString("\n")
+	}
+	for _, name := range names {
+		builder.WriteString(fmt.Sprintf(bold, escape(name+":")))
+		builder.WriteString(" " + escape(fmt.Sprintf("%v", message.Fields[name])) + "\n")
+	}
+	if message.ConfigName != "" {
+		builder.WriteString(fmt.Sprintf(bold, escape("Configuration:")))
+		builder.WriteString(" " + escape(message.ConfigName) + "\n")
+	}
+
+	// === Footer Section ===
+	builder.WriteString("\n")
+	builder.WriteString(fmt.Sprintf(italic, escape(message.Timestamp.Format("2006-01-02 15:04:05 MST"))))
+
+	return builder.String()
+}
+
+// telegramLength returns the length of a text as Telegram counts it
+func telegramLength(text string) int {
+	length := 0
+	for _, r := range text {
+		length += utf16.RuneLen(r)
+	}
+	return length
+}
+
+// shortenLongestValue returns a copy of the message whose longest text, title or field value is cut
+// as little as the length limit allows, or false when nothing is long enough to shorten
+func (t *TelegramNotifier) shortenLongestValue(message *Message) (*Message, bool) {
+	shortened := *message
+	shortened.Fields = make(map[string]interface{}, len(message.Fields))
+	for name, value := range message.Fields {
+		shortened.Fields[name] = value
+	}
+
+	text, title := message.Text, message.Title
+	longest := utf8.RuneCountInString(text)
+	shorten := func(keep int) { shortened.Text = truncateText(keep, text) }
+	if length := utf8.RuneCountInString(title); length > longest {
+		longest = length
+		shorten = func(keep int) { shortened.Title = truncateText(keep, title) }
+	}
+	for name, value := range message.Fields {
+		value := fmt.Sprintf("%v", value)
+		if length := utf8.RuneCountInString(value); length > longest {
+			longest = length
+			shorten = func(keep int) { shortened.Fields[name] = truncateText(keep, value) }
+		}
+	}
+
+	// Keep at least a few characters so the value stays recognizable
+	const minKeep = 20
+	if longest <= minKeep {
+		return nil, false
+	}
+
+	// Escaping makes values longer by an amount that depends on their characters, so search for the longest cut that fits
+	best, low, high := minKeep, minKeep+1, longest-1
+	for low <= high {
+		keep := (low + high) / 2
+		shorten(keep)
+		if telegramLength(t.renderMessage(&shortened)) <= telegramMaxMessageLength {
+			best, low = keep, keep+1
+		} else {
+			high = keep - 1
+		}
+	}
+	shorten(best)
+	return &shortened, true
+}
+
+// getEmojiForType returns an appropriate emoji for the message type
+func (t *TelegramNotifier) getEmojiForType(msgType MessageType) string {
+	switch msgType {
+	case MessageTypeSuccess:
+		return "✅"
+	case MessageTypeError:
+		return "❌"
+	case MessageTypeWarning:
+		return "⚠️"
+	default:
+		return "ℹ️"
+	}
+}
@@ -0,0 +1,162 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTelegramNotifier_GetChannelType(t *testing.T) {
+	notifier := NewTelegramNotifier(TelegramConfig{BotToken: "123:abc", ChatID: "-100123"})
+	assert.Equal(t, ChannelTelegram, notifier.GetChannelType())
+}
+
+func TestTelegramNotifier_ValidateConfig(t *testing.T) {
+	notifier := &TelegramNotifier{}
+
+	tests := []struct {
+		name        string
+		config      map[string]interface{}
+		expectError string
+	}{
+		{
+			name:   "valid config",
+			config: map[string]interface{}{"bot_token": "123:abc", "chat_id": "-100123", "message_thread_id": float64(42), "parse_mode": "MarkdownV2"},
+		},
+		{
+			name:   "thread ID declared in code",
+			config: map[string]interface{}{"bot_token": "123:abc", "chat_id": "@backups", "message_thread_id": 42},
+		},
+		{
+			name:        "missing bot_token",
+			config:      map[string]interface{}{"chat_id": "-100123"},
+			expectError: "bot_token is required",
+		},
+		{
+			name:        "missing chat_id",
+			config:      map[string]interface{}{"bot_token": "123:abc"},
+			expectError: "chat_id is required",
+		},
+		{
+			name:        "fractional thread ID",
+			config:      map[string]interface{}{"bot_token": "123:abc", "chat_id": "-100123", "message_thread_id": 4.2},
+			expectError: "message_thread_id must be an integer",
+		},
+		{
+			name:        "unknown parse mode",
+			config:      map[string]interface{}{"bot_token": "123:abc", "chat_id": "-100123", "parse_mode": "Markdown"},
+			expectError: "parse_mode must be",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := notifier.ValidateConfig(tt.config)
+			if tt.expectError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestTelegramNotifier_Send(t *testing.T) {
+	var received TelegramSendMessageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/bot123:abc/sendMessage", r.URL.Path)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+
+		if received.ChatID == "missing" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer server.Close()
+
+	notifier := NewTelegramNotifier(TelegramConfig{BotToken: "123:abc", ChatID: "-100123", MessageThreadID: 42})
+	notifier.apiURL = server.URL + "/bot"
+
+	err := notifier.Send(&Message{Type: MessageTypeSuccess, Title: "Backup Completed", Timestamp: time.Now()})
+	assert.NoError(t, err)
+	assert.Equal(t, "-100123", received.ChatID)
+	assert.Equal(t, 42, received.MessageThreadID)
+	assert.Equal(t, TelegramParseModeHTML, received.ParseMode)
+
+	notifier.config.ChatID = "missing"
+	err = notifier.Send(&Message{Type: MessageTypeError, Title: "Backup Failed", Timestamp: time.Now()})
+	assert.ErrorContains(t, err, "chat not found")
+	assert.NotContains(t, err.Error(), "123:abc")
+}
+
+func TestTelegramNotifier_formatMessage(t *testing.T) {
+	timestamp := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	message := &Message{
+		Type:       MessageTypeError,
+		Title:      "Backup Failed: orders_db",
+		Text:       "Dump of <orders> failed (exit 2).",
+		Fields:     map[string]interface{}{"Error": "a & b > c", "File Size": "1.5 MB"},
+		Timestamp:  timestamp,
+		ConfigName: "orders_db",
+	}
+
+	html := NewTelegramNotifier(TelegramConfig{}).formatMessage(message)
+	assert.Equal(t, "<b>❌ Backup Failed: orders_db</b>\n"+
+		"\nDump of &lt;orders&gt; failed (exit 2).\n"+
+		"\n<b>Error:</b> a &amp; b &gt; c\n"+
+		"<b>File Size:</b> 1.5 MB\n"+
+		"<b>Configuration:</b> orders_db\n"+
+		"\n<i>2026-01-02 03:04:05 UTC</i>", html)
+
+	markdown := NewTelegramNotifier(TelegramConfig{ParseMode: TelegramParseModeMarkdownV2}).formatMessage(message)
+	assert.Equal(t, "*❌ Backup Failed: orders\\_db*\n"+
+		"\nDump of <orders\\> failed \\(exit 2\\)\\.\n"+
+		"\n*Error:* a & b \\> c\n"+
+		"*File Size:* 1\\.5 MB\n"+
+		"*Configuration:* orders\\_db\n"+
+		"\n_2026\\-01\\-02 03:04:05 UTC_", markdown)
+}
+
+func TestTelegramNotifier_formatMessageTruncates(t *testing.T) {
+	message := &Message{
+		Type:       MessageTypeError,
+		Title:      "Backup Failed: orders_db",
+		Text:       "Dump of orders_db failed.",
+		Fields:     map[string]interface{}{"Error": strings.Repeat("mysqldump: <table> & co. failed! ", 400), "File Size": "1.5 MB"},
+		Timestamp:  time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		ConfigName: "orders_db",
+	}
+
+	// The error is cut just enough to fit
+	html := NewTelegramNotifier(TelegramConfig{}).formatMessage(message)
+	assert.LessOrEqual(t, telegramLength(html), telegramMaxMessageLength)
+	assert.Greater(t, telegramLength(html), telegramMaxMessageLength-20)
+	assert.True(t, strings.HasPrefix(html, "<b>❌ Backup Failed: orders_db</b>\n"))
+	assert.True(t, strings.HasSuffix(html, "\n<i>2026-01-02 03:04:05 UTC</i>"))
+	assert.Contains(t, html, "<b>File Size:</b> 1.5 MB\n")
+	assert.Contains(t, html, "...\n<b>File Size:</b>")
+	// No entity is cut in half
+	stripped := strings.NewReplacer("&lt;", "", "&gt;", "", "&amp;", "", "&#34;", "", "&#39;", "").Replace(html)
+	assert.NotContains(t, stripped, "&")
+
+	markdown := NewTelegramNotifier(TelegramConfig{ParseMode: TelegramParseModeMarkdownV2}).formatMessage(message)
+	assert.LessOrEqual(t, telegramLength(markdown), telegramMaxMessageLength)
+	assert.Greater(t, telegramLength(markdown), telegramMaxMessageLength-20)
+	assert.True(t, strings.HasSuffix(markdown, "\n_2026\\-01\\-02 03:04:05 UTC_"))
+	assert.Contains(t, markdown, "\\.\\.\\.\n*File Size:*")
+	// Every reserved character of the shortened value is still escaped
+	value := markdown[strings.Index(markdown, "*Error:* ")+len("*Error:* ") : strings.Index(markdown, "\n*File Size:*")]
+	assert.NotRegexp(t, "[_*\\[\\]()~`>#+=|{}.!\\\\-]", regexp.MustCompile(`\\.`).ReplaceAllString(value, ""))
+
+	// The original message is left alone for other channels
+	assert.Len(t, message.Fields["Error"], 400*len("mysqldump: <table> & co. failed! "))
+}
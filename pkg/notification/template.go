@@ -0,0 +1,266 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/vfa-khuongdv/lazy/internal/database"
+)
+
+// MessageTemplates holds the text/template sources of a notification config's backup messages.
+// Title and Text replace the built-in ones when set; Fields, when set, replace all built-in fields
+// and fields rendering to an empty string are left out. Templates are executed with TemplateData.
+type MessageTemplates = database.NotificationTemplates
+
+// TemplateData is the data message templates are executed with; the BackupNotificationData
+// fields are available directly, e.g. {{.ConfigName}} or {{humanSize .BackupSize}}
+type TemplateData struct {
+	*BackupNotificationData
+	Event     string        // EventBackupSucceeded, EventBackupRecovered or EventBackupFailed
+	Succeeded bool          // false when the backup failed
+	Elapsed   time.Duration // time the backup took, or has taken so far when it failed early
+}
+
+// templateFuncs are the helper functions available to message templates. Rendered values are plain text:
+// channels escape any markup in them, so links are written as bare URLs.
+var templateFuncs = template.FuncMap{
+	"humanSize":  formatFileSize,
+	"duration":   formatTemplateDuration,
+	"formatTime": func(layout string, t time.Time) string { return t.Format(layout) },
+	"driveLink":  driveFileLink,
+	"default":    defaultValue,
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"truncate":   truncateText,
+}
+
+// compiledTemplates holds parsed message templates
+type compiledTemplates struct {
+	title  *template.Template
+	text   *template.Template
+	fields map[string]*template.Template
+}
+
+// parseMessageTemplates parses every set template, naming the failing one in the error
+func parseMessageTemplates(templates *MessageTemplates) (*compiledTemplates, error) {
+	compiled := &compiledTemplates{fields: make(map[string]*template.Template, len(templates.Fields))}
+
+	var err error
+	if compiled.title, err = parseTemplate("title", templates.Title); err != nil {
+		return nil, err
+	}
+	if compiled.text, err = parseTemplate("text", templates.Text); err != nil {
+		return nil, err
+	}
+	for name, source := range templates.Fields {
+		if source == "" {
+			continue
+		}
+		if compiled.fields[name], err = parseTemplate("field "+name, source); err != nil {
+			return nil, err
+		}
+	}
+
+	return compiled, nil
+}
+
+func parseTemplate(name, source string) (*template.Template, error) {
+	if source == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// ValidateMessageTemplates parses the templates and executes them against sample success and
+// failure data, so unknown fields and functions are reported when the config is loaded
+func ValidateMessageTemplates(templates *MessageTemplates) error {
+	if templates.IsEmpty() {
+		return nil
+	}
+
+	compiled, err := parseMessageTemplates(templates)
+	if err != nil {
+		return err
+	}
+
+	for _, failed := range []bool{false, true} {
+		data := SampleBackupData(failed)
+		if err := compiled.apply(defaultBackupMessage(data), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PreviewBackupMessage renders the message a backup produces with the given templates.
+// A nil data previews SampleBackupData; data with an ErrorMessage previews a failed backup.
+func PreviewBackupMessage(templates *MessageTemplates, data *BackupNotificationData) (*Message, error) {
+	if data == nil {
+		data = SampleBackupData(false)
+	}
+	return renderBackupMessage(templates, defaultBackupMessage(data), data)
+}
+
+// SampleBackupData returns made-up backup data to validate and preview templates with
+func SampleBackupData(failed bool) *BackupNotificationData {
+	startedAt := time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+	data := &BackupNotificationData{
+		ConfigName:   "orders-db",
+		DatabaseType: "mysql",
+		BackupSize:   48 * 1024 * 1024,
+		Duration:     83 * time.Second,
+		FileName:     "orders-db_20260102_030000.sql.gz",
+		FileID:       "1a2b3c4d5e6f",
+		WebViewLink:  "https://drive.google.com/file/d/1a2b3c4d5e6f/view",
+		StartedAt:    startedAt,
+		CompletedAt:  startedAt.Add(83 * time.Second),
+	}
+	if failed {
+		data.BackupSize = 0
+		data.FileName = ""
+		data.FileID = ""
+		data.WebViewLink = ""
+		data.ErrorMessage = "mysqldump: Got error: 2003: Can't connect to MySQL server"
+	}
+	return data
+}
+
+// renderBackupMessage applies the templates to a built-in backup message
+func renderBackupMessage(templates *MessageTemplates, message *Message, data *BackupNotificationData) (*Message, error) {
+	if templates.IsEmpty() {
+		return message, nil
+	}
+
+	compiled, err := parseMessageTemplates(templates)
+	if err != nil {
+		return nil, err
+	}
+	if err := compiled.apply(message, data); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// apply renders the templates into message
+func (c *compiledTemplates) apply(message *Message, data *BackupNotificationData) error {
+	templateData := newTemplateData(data, message.Type == MessageTypeSuccess)
+
+	if c.title != nil {
+		title, err := executeTemplate(c.title, templateData)
+		if err != nil {
+			return err
+		}
+		message.Title = title
+	}
+
+	if c.text != nil {
+		text, err := executeTemplate(c.text, templateData)
+		if err != nil {
+			return err
+		}
+		message.Text = text
+	}
+
+	if len(c.fields) > 0 {
+		names := make([]string, 0, len(c.fields))
+		for name := range c.fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fields := make(map[string]interface{}, len(names))
+		for _, name := range names {
+			value, err := executeTemplate(c.fields[name], templateData)
+			if err != nil {
+				return err
+			}
+			if value = strings.TrimSpace(value); value != "" {
+				fields[name] = value
+			}
+		}
+		message.Fields = fields
+	}
+
+	return nil
+}
+
+func executeTemplate(tmpl *template.Template, data *TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+func newTemplateData(data *BackupNotificationData, succeeded bool) *TemplateData {
+	templateData := &TemplateData{
+		BackupNotificationData: data,
+		Event:                  EventBackupSucceeded,
+		Succeeded:              succeeded,
+	}
+	if !succeeded {
+		templateData.Event = EventBackupFailed
+	} else if data.ConsecutiveFailures > 0 {
+		templateData.Event = EventBackupRecovered
+	}
+
+	if data.CompletedAt.IsZero() {
+		templateData.Elapsed = time.Since(data.StartedAt)
+	} else {
+		templateData.Elapsed = data.CompletedAt.Sub(data.StartedAt)
+	}
+	return templateData
+}
+
+// defaultBackupMessage returns the built-in message of a backup, an error message when it failed
+func defaultBackupMessage(data *BackupNotificationData) *Message {
+	if data.ErrorMessage != "" {
+		return CreateBackupErrorMessage(data)
+	}
+	return CreateBackupSuccessMessage(data)
+}
+
+// formatTemplateDuration rounds a duration to seconds, or milliseconds below a second
+func formatTemplateDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+// driveFileLink returns the Google Drive link of a file ID, empty without one
+func driveFileLink(fileID string) string {
+	if fileID == "" {
+		return ""
+	}
+	return "https://drive.google.com/file/d/" + fileID + "/view"
+}
+
+// defaultValue returns value, or fallback when value is empty; {{.FileName | default "none"}}
+func defaultValue(fallback, value interface{}) interface{} {
+	if value == nil || fmt.Sprintf("%v", value) == "" {
+		return fallback
+	}
+	return value
+}
+
+// truncateText shortens text to at most n characters, ending with "..." when cut
+func truncateText(n int, text string) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	if n <= 3 {
+		return string(runes[:n])
+	}
+	return string(runes[:n-3]) + "..."
+}
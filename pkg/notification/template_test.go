@@ -0,0 +1,102 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vfa-khuongdv/lazy/internal/database"
+)
+
+func TestPreviewBackupMessage(t *testing.T) {
+	templates := &MessageTemplates{
+		Title: "{{if .Succeeded}}✅{{else}}❌{{end}} {{.ConfigName | upper}}",
+		Text:  "{{.Event}} after {{duration .Elapsed}}",
+		Fields: map[string]string{
+			"Size":  "{{if .Succeeded}}{{humanSize .BackupSize}}{{end}}",
+			"File":  "{{driveLink .FileID}}",
+			"Error": "{{.ErrorMessage | truncate 20}}",
+			"Date":  "{{.StartedAt | formatTime \"2006-01-02\"}}",
+		},
+	}
+
+	message, err := PreviewBackupMessage(templates, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, MessageTypeSuccess, message.Type)
+	assert.Equal(t, "✅ ORDERS-DB", message.Title)
+	assert.Equal(t, "backup.succeeded after 1m23s", message.Text)
+	assert.Equal(t, map[string]interface{}{
+		"Size": "48.0 MB",
+		"File": "https://drive.google.com/file/d/1a2b3c4d5e6f/view",
+		"Date": "2026-01-02",
+	}, message.Fields)
+
+	// Fields rendering to nothing are left out
+	message, err = PreviewBackupMessage(templates, SampleBackupData(true))
+	assert.NoError(t, err)
+	assert.Equal(t, MessageTypeError, message.Type)
+	assert.Equal(t, "❌ ORDERS-DB", message.Title)
+	assert.Equal(t, map[string]interface{}{"Error": "mysqldump: Got er...", "Date": "2026-01-02"}, message.Fields)
+}
+
+func TestPreviewBackupMessage_KeepsBuiltIn(t *testing.T) {
+	data := SampleBackupData(false)
+
+	message, err := PreviewBackupMessage(&MessageTemplates{Title: "{{.ConfigName}} done"}, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "orders-db done", message.Title)
+	assert.Equal(t, CreateBackupSuccessMessage(data).Text, message.Text)
+	assert.Equal(t, CreateBackupSuccessMessage(data).Fields, message.Fields)
+
+	message, err = PreviewBackupMessage(nil, data)
+	assert.NoError(t, err)
+	assert.Equal(t, CreateBackupSuccessMessage(data), message)
+}
+
+func TestValidateMessageTemplates(t *testing.T) {
+	assert.NoError(t, ValidateMessageTemplates(nil))
+	assert.NoError(t, ValidateMessageTemplates(&MessageTemplates{Text: "{{.FileName | default \"no file\"}}"}))
+
+	assert.ErrorContains(t, ValidateMessageTemplates(&MessageTemplates{Title: "{{.ConfigName"}), "invalid title template")
+	assert.ErrorContains(t, ValidateMessageTemplates(&MessageTemplates{Text: "{{shout .ConfigName}}"}), `function "shout" not defined`)
+	assert.ErrorContains(t, ValidateMessageTemplates(&MessageTemplates{
+		Fields: map[string]string{"Host": "{{.Hostname}}"},
+	}), "failed to render field Host template")
+}
+
+func TestCreateBackupMessage_Templates(t *testing.T) {
+	manager := &Manager{}
+	data := SampleBackupData(true)
+
+	// Broken templates fall back to the built-in message
+	message := manager.backupMessage(&database.NotificationConfig{Channel: "slack", Templates: &MessageTemplates{Title: "{{.Missing}}"}}, data, false)
+	assert.Equal(t, createBackupMessage("slack", data, false), message)
+
+	message = manager.backupMessage(&database.NotificationConfig{Channel: "discord", Templates: &MessageTemplates{Title: "{{.ConfigName}} failed"}}, data, false)
+	assert.Equal(t, "orders-db failed", message.Title)
+	assert.Equal(t, CreateDiscordBackupErrorMessage(data).Fields, message.Fields)
+}
+
+func TestTemplateLinks_ThroughChannels(t *testing.T) {
+	manager := &Manager{}
+	templates := &MessageTemplates{Fields: map[string]string{
+		"File":   "{{driveLink .FileID}}",
+		"Markup": "<b>{{.ConfigName}}</b>",
+	}}
+	message := manager.backupMessage(&database.NotificationConfig{Channel: "telegram", Templates: templates}, SampleBackupData(false), true)
+
+	// Bare URLs arrive intact for the chat app to link, markup is shown as typed
+	text := NewTelegramNotifier(TelegramConfig{}).formatMessage(message)
+	assert.Contains(t, text, "https://drive.google.com/file/d/1a2b3c4d5e6f/view")
+	assert.Contains(t, text, "&lt;b&gt;orders-db&lt;/b&gt;")
+	assert.ErrorContains(t, ValidateMessageTemplates(&MessageTemplates{Text: `{{htmlLink "x" "y"}}`}), `function "htmlLink" not defined`)
+}
+
+func TestTemplateHelpers(t *testing.T) {
+	assert.Equal(t, "250ms", formatTemplateDuration(250*time.Millisecond+400*time.Microsecond))
+	assert.Equal(t, "1m30s", formatTemplateDuration(90*time.Second+200*time.Millisecond))
+	assert.Equal(t, "", driveFileLink(""))
+	assert.Equal(t, "none", defaultValue("none", ""))
+	assert.Equal(t, int64(0), defaultValue("none", int64(0)))
+	assert.Equal(t, "abc", truncateText(3, "abcdef"))
+}
@@ -8,9 +8,15 @@ import (
 type NotificationChannel string
 
 const (
-	ChannelChatwork NotificationChannel = "chatwork"
-	ChannelDiscord  NotificationChannel = "discord"
-	ChannelSlack    NotificationChannel = "slack"
+	ChannelChatwork  NotificationChannel = "chatwork"
+	ChannelDiscord   NotificationChannel = "discord"
+	ChannelSlack     NotificationChannel = "slack"
+	ChannelEmail     NotificationChannel = "email"
+	ChannelTeams     NotificationChannel = "teams"
+	ChannelTelegram  NotificationChannel = "telegram"
+	ChannelWebhook   NotificationChannel = "webhook"
+	ChannelPagerDuty NotificationChannel = "pagerduty"
+	ChannelOpsgenie  NotificationChannel = "opsgenie"
 )
 
 // MessageType represents the type of notification message
@@ -32,8 +38,27 @@ type Message struct {
 	Timestamp   time.Time              `json:"timestamp"`
 	ConfigName  string                 `json:"config_name,omitempty"`
 	DatabaseURL string                 `json:"database_url,omitempty"`
+	// Event names what happened for machine consumers such as webhooks, e.g. EventBackupFailed
+	Event string `json:"event,omitempty"`
+	// Backup holds the raw backup data of backup messages
+	Backup *BackupNotificationData `json:"backup,omitempty"`
+	// ID identifies the message across send attempts; the outbox sets it when queuing, so receivers can drop repeats
+	ID string `json:"id,omitempty"`
 }
 
+// Event names carried by messages
+const (
+	EventBackupSucceeded    = "backup.succeeded"
+	EventBackupFailed       = "backup.failed"
+	EventBackupRecovered    = "backup.recovered" // the first success after failures
+	EventBackupSkipped      = "backup.skipped"
+	EventDriveAuthFailing   = "drive.auth_failing"
+	EventDriveAuthRecovered = "drive.auth_recovered" // a failing connection passed its check again
+	EventDriveStorageLow    = "drive.storage_low"
+	EventDriveCapacityLow   = "drive.capacity_forecast"
+	EventNotificationTest   = "notification.test"
+)
+
 // BackupNotificationData contains backup-specific data for notifications
 type BackupNotificationData struct {
 	ConfigName   string        `json:"config_name"`
@@ -46,6 +71,41 @@ type BackupNotificationData struct {
 	ErrorMessage string        `json:"error_message,omitempty"`
 	StartedAt    time.Time     `json:"started_at"`
 	CompletedAt  time.Time     `json:"completed_at"`
+	// ConsecutiveFailures counts the failed runs in a row: including this one for a failure,
+	// the ones it recovered from for a success
+	ConsecutiveFailures int `json:"consecutive_failures,omitempty"`
+}
+
+// DriveAuthNotificationData describes a Drive connection whose authorization keeps failing
+type DriveAuthNotificationData struct {
+	Connection          string    `json:"connection"`
+	ErrorMessage        string    `json:"error_message"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	AuthURL             string    `json:"auth_url,omitempty"` // empty for service account connections
+	NextBackupAt        time.Time `json:"next_backup_at,omitempty"`
+	CheckedAt           time.Time `json:"checked_at"`
+}
+
+// StorageNotificationData describes a backup that may not fit in the Drive storage left on its connection
+type StorageNotificationData struct {
+	Connection    string    `json:"connection"`
+	ConfigName    string    `json:"config_name"`
+	ProjectedSize int64     `json:"projected_size"`
+	FreeBytes     int64     `json:"free_bytes"`
+	Limit         int64     `json:"limit"`
+	Usage         int64     `json:"usage"`
+	Skipped       bool      `json:"skipped"` // the backup was skipped rather than attempted
+	CheckedAt     time.Time `json:"checked_at"`
+}
+
+// CapacityNotificationData describes a Drive connection forecast to run out of storage soon
+type CapacityNotificationData struct {
+	Connection    string    `json:"connection"`
+	Limit         int64     `json:"limit"`
+	Usage         int64     `json:"usage"`
+	DailyGrowth   int64     `json:"daily_growth"`
+	DaysUntilFull int       `json:"days_until_full"`
+	GeneratedAt   time.Time `json:"generated_at"`
 }
 
 // Notifier interface defines the methods that all notification implementations must provide
@@ -82,13 +142,62 @@ type SlackConfig struct {
 	IconURL    string `json:"icon_url,omitempty"`
 }
 
+// TeamsConfig holds Microsoft Teams-specific configuration
+type TeamsConfig struct {
+	WebhookURL string `json:"webhook_url"` // incoming webhook or Workflows "post to a channel" URL
+}
+
+// TelegramConfig holds Telegram bot configuration
+type TelegramConfig struct {
+	BotToken        string `json:"bot_token"`
+	ChatID          string `json:"chat_id"`                     // numeric chat ID or @channelusername
+	MessageThreadID int    `json:"message_thread_id,omitempty"` // forum topic to post in (optional)
+	ParseMode       string `json:"parse_mode,omitempty"`        // "HTML" (default) or "MarkdownV2"
+}
+
+// WebhookConfig holds generic webhook configuration
+type WebhookConfig struct {
+	URL     string            `json:"url"`
+	Secret  string            `json:"secret,omitempty"`  // signs every event with HMAC-SHA256 when set
+	Headers map[string]string `json:"headers,omitempty"` // extra request headers, e.g. an API key
+}
+
+// PagerDutyConfig holds PagerDuty Events API v2 configuration
+type PagerDutyConfig struct {
+	RoutingKey string `json:"routing_key"`      // integration key of the service
+	Source     string `json:"source,omitempty"` // event source, "lazy" by default
+}
+
+// OpsgenieConfig holds Opsgenie Alert API configuration
+type OpsgenieConfig struct {
+	APIKey   string   `json:"api_key"`
+	Region   string   `json:"region,omitempty"`   // "us" (default) or "eu"
+	Priority string   `json:"priority,omitempty"` // priority of failed backup alerts, P2 by default
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// EmailConfig holds SMTP configuration for email notifications
+type EmailConfig struct {
+	Host          string   `json:"host"`
+	Port          int      `json:"port,omitempty"`     // defaults to 587, 465 or 25 depending on Security
+	Security      string   `json:"security,omitempty"` // "starttls" (default), "tls" or "none"
+	Username      string   `json:"username,omitempty"` // PLAIN authentication is skipped when empty
+	Password      string   `json:"password,omitempty"`
+	From          string   `json:"from"`
+	To            []string `json:"to"`
+	SubjectPrefix string   `json:"subject_prefix,omitempty"`
+}
+
 // NotificationResult represents the result of sending a notification
 type NotificationResult struct {
 	Channel   NotificationChannel `json:"channel"`
 	Success   bool                `json:"success"`
 	Error     string              `json:"error,omitempty"`
 	SentAt    time.Time           `json:"sent_at"`
-	MessageID string              `json:"message_id,omitempty"`
+	MessageID string              `json:"message_id,omitempty"` // outbox delivery ID of queued messages
+	Queued    bool                `json:"queued,omitempty"`     // stored in the outbox, sent in the background
+	// Suppressed is set when the message was not sent because of a suppression rule or rate limit
+	Suppressed bool `json:"suppressed,omitempty"`
 }
 
 type NotificationConfig struct {
@@ -98,4 +207,6 @@ type NotificationConfig struct {
 	NotifyOnSuccess bool                   `json:"notify_on_success"`
 	NotifyOnError   bool                   `json:"notify_on_error"`
 	Enabled         bool                   `json:"enabled"`
+	Templates       *MessageTemplates      `json:"templates,omitempty"`   // overrides the built-in backup messages
+	Suppression     *SuppressionRules      `json:"suppression,omitempty"` // limits repeated failure alerts
 }
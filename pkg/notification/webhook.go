@@ -0,0 +1,264 @@
+package notification
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookEventVersion is the version of the JSON event schema; fields are only added within a version
+const WebhookEventVersion = "1"
+
+// Webhook request headers
+const (
+	WebhookHeaderEvent     = "X-Lazy-Event"
+	WebhookHeaderEventID   = "X-Lazy-Event-Id"
+	WebhookHeaderTimestamp = "X-Lazy-Timestamp"
+	WebhookHeaderSignature = "X-Lazy-Signature"
+)
+
+// webhookSignaturePrefix versions the signature scheme so it can change without breaking receivers
+const webhookSignaturePrefix = "v1="
+
+// WebhookNotifier implements the Notifier interface for generic HTTP webhooks
+type WebhookNotifier struct {
+	config WebhookConfig
+}
+
+// NewWebhookNotifier creates a new generic webhook notifier
+func NewWebhookNotifier(config WebhookConfig) *WebhookNotifier {
+	return &WebhookNotifier{
+		config: config,
+	}
+}
+
+// WebhookEvent is the JSON body posted to webhooks
+type WebhookEvent struct {
+	Version    string            `json:"version"`
+	ID         string            `json:"id"`
+	Event      string            `json:"event"`
+	Severity   MessageType       `json:"severity"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Title      string            `json:"title"`
+	Text       string            `json:"text,omitempty"`
+	ConfigName string            `json:"config_name,omitempty"`
+	Fields     map[string]string `json:"fields,omitempty"`
+	Backup     *WebhookBackup    `json:"backup,omitempty"`
+}
+
+// WebhookBackup describes the backup of backup.* events
+type WebhookBackup struct {
+	ConfigName      string    `json:"config_name"`
+	DatabaseType    string    `json:"database_type"`
+	SizeBytes       int64     `json:"size_bytes"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	FileName        string    `json:"file_name,omitempty"`
+	FileID          string    `json:"file_id,omitempty"`
+	WebViewLink     string    `json:"web_view_link,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	StartedAt       time.Time `json:"started_at"`
+	CompletedAt     time.Time `json:"completed_at"`
+	// ConsecutiveFailures counts the failed runs in a row, or those a backup.recovered event ended
+	ConsecutiveFailures int `json:"consecutive_failures,omitempty"`
+}
+
+// Send posts the message as a versioned JSON event, signed when a secret is configured
+func (w *WebhookNotifier) Send(message *Message) error {
+	event, err := w.createEvent(message)
+	if err != nil {
+		return err
+	}
+
+	// Marshal to JSON
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	// Create HTTP request
+	req, err := http.NewRequest("POST", w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set headers; the configured ones cannot replace the event and signature headers
+	for name, value := range w.config.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DB-Backup-GDrive/1.0")
+	req.Header.Set(WebhookHeaderEvent, event.Event)
+	req.Header.Set(WebhookHeaderEventID, event.ID)
+	if w.config.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookHeaderTimestamp, timestamp)
+		req.Header.Set(WebhookHeaderSignature, SignWebhookPayload(w.config.Secret, timestamp, body))
+	}
+
+	// Send request
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Check response
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newStatusError(resp, "webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// ValidateConfig validates the webhook configuration
+func (w *WebhookNotifier) ValidateConfig(config map[string]interface{}) error {
+	rawURL, ok := config["url"].(string)
+	if !ok || rawURL == "" {
+		return fmt.Errorf("url is required for webhook")
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return fmt.Errorf("url must be an http or https URL for webhook")
+	}
+
+	if secret, ok := config["secret"]; ok {
+		if _, isString := secret.(string); !isString {
+			return fmt.Errorf("secret must be a string for webhook")
+		}
+	}
+
+	if headers, ok := config["headers"]; ok {
+		jsonData, err := json.Marshal(headers)
+		if err != nil {
+			return fmt.Errorf("invalid headers for webhook: %w", err)
+		}
+		var parsedHeaders map[string]string
+		if err := json.Unmarshal(jsonData, &parsedHeaders); err != nil {
+			return fmt.Errorf("headers must map header names to strings for webhook")
+		}
+	}
+
+	return nil
+}
+
+// GetChannelType returns the notification channel type
+func (w *WebhookNotifier) GetChannelType() NotificationChannel {
+	return ChannelWebhook
+}
+
+// createEvent builds the event posted for a message
+func (w *WebhookNotifier) createEvent(message *Message) (*WebhookEvent, error) {
+	// Queued messages keep their ID across retries; synchronous sends get a new one
+	id := message.ID
+	if id == "" {
+		var err error
+		if id, err = newMessageID(); err != nil {
+			return nil, fmt.Errorf("failed to generate event ID: %w", err)
+		}
+	}
+
+	event := &WebhookEvent{
+		Version:    WebhookEventVersion,
+		ID:         id,
+		Event:      message.Event,
+		Severity:   message.Type,
+		OccurredAt: message.Timestamp.UTC(),
+		Title:      message.Title,
+		Text:       message.Text,
+		ConfigName: message.ConfigName,
+	}
+	if event.Event == "" {
+		event.Event = "notification." + string(message.Type)
+	}
+
+	if len(message.Fields) > 0 {
+		event.Fields = make(map[string]string, len(message.Fields))
+		for name, value := range message.Fields {
+			event.Fields[name] = fmt.Sprintf("%v", value)
+		}
+	}
+
+	if data := message.Backup; data != nil {
+		// Failed backups may not have a completion time, the message uses the time it was created
+		completedAt := data.CompletedAt
+		if completedAt.IsZero() {
+			completedAt = message.Timestamp
+		}
+		event.Backup = &WebhookBackup{
+			ConfigName:      data.ConfigName,
+			DatabaseType:    data.DatabaseType,
+			SizeBytes:       data.BackupSize,
+			DurationSeconds: completedAt.Sub(data.StartedAt).Seconds(),
+			FileName:        data.FileName,
+			FileID:          data.FileID,
+			WebViewLink:     data.WebViewLink,
+			Error:           data.ErrorMessage,
+			StartedAt:       data.StartedAt.UTC(),
+			CompletedAt:     completedAt.UTC(),
+
+			ConsecutiveFailures: data.ConsecutiveFailures,
+		}
+	}
+
+	return event, nil
+}
+
+// SignWebhookPayload returns the signature header value of a webhook body:
+// "v1=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return webhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks the signature headers of a received webhook against its raw body.
+// Requests signed more than tolerance away from now are rejected to prevent replays.
+func VerifyWebhookSignature(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	timestamp := header.Get(WebhookHeaderTimestamp)
+	signature := header.Get(WebhookHeaderSignature)
+	if timestamp == "" || signature == "" {
+		return fmt.Errorf("missing webhook signature headers")
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid webhook timestamp")
+	}
+	if age := time.Since(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("webhook timestamp is outside the %s tolerance", tolerance)
+	}
+
+	if !strings.HasPrefix(signature, webhookSignaturePrefix) ||
+		!hmac.Equal([]byte(signature), []byte(SignWebhookPayload(secret, timestamp, body))) {
+		return fmt.Errorf("webhook signature does not match")
+	}
+
+	return nil
+}
+
+// CreateWebhookBackupSuccessMessage creates a success message carrying the backup data for webhook events
+func CreateWebhookBackupSuccessMessage(data *BackupNotificationData) *Message {
+	message := CreateBackupSuccessMessage(data)
+	message.Event = EventBackupSucceeded
+	message.Backup = data
+	return message
+}
+
+// CreateWebhookBackupErrorMessage creates an error message carrying the backup data for webhook events
+func CreateWebhookBackupErrorMessage(data *BackupNotificationData) *Message {
+	message := CreateBackupErrorMessage(data)
+	message.Event = EventBackupFailed
+	message.Backup = data
+	return message
+}
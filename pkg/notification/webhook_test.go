@@ -0,0 +1,141 @@
+package notification
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookNotifier_GetChannelType(t *testing.T) {
+	assert.Equal(t, ChannelWebhook, NewWebhookNotifier(WebhookConfig{URL: "https://example.com/hook"}).GetChannelType())
+}
+
+func TestWebhookNotifier_ValidateConfig(t *testing.T) {
+	notifier := &WebhookNotifier{}
+
+	tests := []struct {
+		name        string
+		config      map[string]interface{}
+		expectError string
+	}{
+		{
+			name: "valid config",
+			config: map[string]interface{}{
+				"url": "https://incidents.example.com/hooks/lazy", "secret": "s3cret",
+				"headers": map[string]interface{}{"Authorization": "Bearer token"},
+			},
+		},
+		{
+			name:        "missing url",
+			config:      map[string]interface{}{"secret": "s3cret"},
+			expectError: "url is required",
+		},
+		{
+			name:        "not an http url",
+			config:      map[string]interface{}{"url": "ftp://example.com/hook"},
+			expectError: "url must be an http or https URL",
+		},
+		{
+			name:        "non-string header",
+			config:      map[string]interface{}{"url": "https://example.com/hook", "headers": map[string]interface{}{"X-Retries": 3}},
+			expectError: "headers must map header names to strings",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := notifier.ValidateConfig(tt.config)
+			if tt.expectError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_Send(t *testing.T) {
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(WebhookConfig{
+		URL:     server.URL,
+		Secret:  "s3cret",
+		Headers: map[string]string{"Authorization": "Bearer token", WebhookHeaderEvent: "spoofed"},
+	})
+
+	startedAt := time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+	data := &BackupNotificationData{
+		ConfigName:   "orders",
+		DatabaseType: "mysql",
+		ErrorMessage: "connection refused",
+		StartedAt:    startedAt,
+		CompletedAt:  startedAt.Add(90 * time.Second),
+	}
+	assert.NoError(t, notifier.Send(CreateWebhookBackupErrorMessage(data)))
+
+	assert.Equal(t, "Bearer token", header.Get("Authorization"))
+	assert.Equal(t, EventBackupFailed, header.Get(WebhookHeaderEvent))
+	assert.NoError(t, VerifyWebhookSignature("s3cret", header, body, 5*time.Minute))
+
+	var event WebhookEvent
+	assert.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, WebhookEventVersion, event.Version)
+	assert.Equal(t, EventBackupFailed, event.Event)
+	assert.Equal(t, header.Get(WebhookHeaderEventID), event.ID)
+	assert.Equal(t, MessageTypeError, event.Severity)
+	assert.Equal(t, "orders", event.ConfigName)
+	assert.Equal(t, "connection refused", event.Fields["Error"])
+	assert.Equal(t, &WebhookBackup{
+		ConfigName:      "orders",
+		DatabaseType:    "mysql",
+		DurationSeconds: 90,
+		Error:           "connection refused",
+		StartedAt:       startedAt,
+		CompletedAt:     startedAt.Add(90 * time.Second),
+	}, event.Backup)
+
+	// Messages without an event name are posted as notification.<type>
+	assert.NoError(t, notifier.Send(&Message{Type: MessageTypeWarning, Title: "Heads up", Timestamp: time.Now()}))
+	assert.Equal(t, "notification.warning", header.Get(WebhookHeaderEvent))
+}
+
+func TestWebhookNotifier_SendUnsigned(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get(WebhookHeaderSignature))
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := NewWebhookNotifier(WebhookConfig{URL: server.URL}).Send(&Message{Type: MessageTypeInfo, Title: "Test", Timestamp: time.Now()})
+	assert.ErrorContains(t, err, "webhook returned status 500")
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"version":"1"}`)
+	signed := func(at time.Time) http.Header {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		header := http.Header{}
+		header.Set(WebhookHeaderTimestamp, timestamp)
+		header.Set(WebhookHeaderSignature, SignWebhookPayload("s3cret", timestamp, body))
+		return header
+	}
+
+	assert.NoError(t, VerifyWebhookSignature("s3cret", signed(time.Now()), body, time.Minute))
+	assert.ErrorContains(t, VerifyWebhookSignature("other", signed(time.Now()), body, time.Minute), "does not match")
+	assert.ErrorContains(t, VerifyWebhookSignature("s3cret", signed(time.Now()), []byte(`{"version":"2"}`), time.Minute), "does not match")
+	assert.ErrorContains(t, VerifyWebhookSignature("s3cret", signed(time.Now().Add(-time.Hour)), body, time.Minute), "outside the 1m0s tolerance")
+	assert.ErrorContains(t, VerifyWebhookSignature("s3cret", http.Header{}, body, time.Minute), "missing webhook signature headers")
+}
@@ -0,0 +1,679 @@
+package lazy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/vfa-khuongdv/lazy/internal/database"
+	"github.com/vfa-khuongdv/lazy/internal/scheduler"
+	"github.com/vfa-khuongdv/lazy/pkg/backup"
+	"github.com/vfa-khuongdv/lazy/pkg/notification"
+)
+
+// SyncAction describes what a reconcile does with a single stored config
+type SyncAction string
+
+const (
+	SyncActionCreate    SyncAction = "create"
+	SyncActionUpdate    SyncAction = "update"
+	SyncActionDisable   SyncAction = "disable"
+	SyncActionDelete    SyncAction = "delete"
+	SyncActionUnchanged SyncAction = "unchanged"
+)
+
+// PrunePolicy controls what happens to stored configs no longer declared in Config
+type PrunePolicy string
+
+const (
+	PruneDelete  PrunePolicy = "delete"  // remove the stored config (default)
+	PruneDisable PrunePolicy = "disable" // keep the stored config but disable it
+)
+
+// Kinds of configuration handled by the reconcile
+const (
+	SyncKindBackup       = "backup"
+	SyncKindNotification = "notification"
+)
+
+// SyncOptions controls how declared configs are reconciled with the metadata database
+type SyncOptions struct {
+	// PrunePolicy decides what happens to stored configs missing from Config (default: delete)
+	PrunePolicy PrunePolicy
+	// KeepRuntimeConfigs preserves configs added at runtime (e.g. through AddBackupMySQLConfig)
+	KeepRuntimeConfigs bool
+	// ContinueOnError lets Initialize start with the configs that synced when others fail to apply,
+	// logging the failures; by default any sync failure fails Initialize. Invalid declarations
+	// always fail Initialize.
+	ContinueOnError bool
+}
+
+// SyncPlanItem is a single planned change for one config
+type SyncPlanItem struct {
+	Kind    string     `json:"kind"`
+	Name    string     `json:"name"`
+	Action  SyncAction `json:"action"`
+	Changes []string   `json:"changes,omitempty"` // changed fields for updates
+}
+
+// String returns a human-readable description of the item
+func (i SyncPlanItem) String() string {
+	if len(i.Changes) > 0 {
+		return fmt.Sprintf("%s %s config '%s' (%s)", i.Action, i.Kind, i.Name, strings.Join(i.Changes, ", "))
+	}
+	return fmt.Sprintf("%s %s config '%s'", i.Action, i.Kind, i.Name)
+}
+
+// SyncPlan lists the changes a reconcile makes (or would make) to stored configs
+type SyncPlan struct {
+	Items []SyncPlanItem `json:"items"`
+}
+
+// HasChanges reports whether the plan contains anything other than unchanged items
+func (p *SyncPlan) HasChanges() bool {
+	for _, item := range p.Items {
+		if item.Action != SyncActionUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of items with the given action
+func (p *SyncPlan) Count(action SyncAction) int {
+	count := 0
+	for _, item := range p.Items {
+		if item.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Summary returns a one-line summary of the plan
+func (p *SyncPlan) Summary() string {
+	return fmt.Sprintf("%d to create, %d to update, %d to disable, %d to delete, %d unchanged",
+		p.Count(SyncActionCreate), p.Count(SyncActionUpdate), p.Count(SyncActionDisable),
+		p.Count(SyncActionDelete), p.Count(SyncActionUnchanged))
+}
+
+// SyncItemError records why a single config could not be reconciled
+type SyncItemError struct {
+	Kind   string
+	Name   string
+	Action SyncAction
+	Err    error
+}
+
+func (e *SyncItemError) Error() string {
+	if e.Action == "" {
+		return fmt.Sprintf("%s config '%s': %v", e.Kind, e.Name, e.Err)
+	}
+	return fmt.Sprintf("%s %s config '%s': %v", e.Action, e.Kind, e.Name, e.Err)
+}
+
+func (e *SyncItemError) Unwrap() error {
+	return e.Err
+}
+
+// SyncError aggregates the per-config failures of a reconcile
+type SyncError struct {
+	Errors []*SyncItemError
+}
+
+func (e *SyncError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, itemErr := range e.Errors {
+		messages = append(messages, itemErr.Error())
+	}
+	return fmt.Sprintf("%d config(s) failed to sync: %s", len(e.Errors), strings.Join(messages, "; "))
+}
+
+func (e *SyncError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, itemErr := range e.Errors {
+		errs = append(errs, itemErr)
+	}
+	return errs
+}
+
+// syncErrorOrNil returns nil when no item failed
+func syncErrorOrNil(errs []*SyncItemError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &SyncError{Errors: errs}
+}
+
+// backupSyncStep pairs a plan item with the config it will be applied to
+type backupSyncStep struct {
+	item     SyncPlanItem
+	config   *database.BackupConfig
+	dbConfig *backup.MySQLConfig
+}
+
+// notificationSyncStep pairs a plan item with the config it will be applied to
+type notificationSyncStep struct {
+	item   SyncPlanItem
+	config *database.NotificationConfig
+}
+
+// PlanSync computes the changes Initialize would make to stored configs without applying them
+func (lm *LazyManager) PlanSync() (*SyncPlan, error) {
+	plan := &SyncPlan{}
+
+	backupSteps, backupErrs, err := lm.planBackupSync()
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range backupSteps {
+		plan.Items = append(plan.Items, step.item)
+	}
+
+	notificationSteps, notificationErrs, err := lm.planNotificationSync()
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range notificationSteps {
+		plan.Items = append(plan.Items, step.item)
+	}
+
+	return plan, syncErrorOrNil(append(backupErrs, notificationErrs...))
+}
+
+// planBackupSync diffs the declared scheduler configs against the stored backup configs
+func (lm *LazyManager) planBackupSync() ([]backupSyncStep, []*SyncItemError, error) {
+	existing, err := lm.dbService.GetAllBackupConfigs()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load stored backup configs: %w", err)
+	}
+
+	var desired []*database.BackupConfig
+	var errs, duplicates []*SyncItemError
+	dbConfigs := make(map[string]*backup.MySQLConfig)
+	seen := make(map[string]bool)
+
+	storedEnabled := make(map[string]bool, len(existing))
+	for _, config := range existing {
+		storedEnabled[config.Name] = config.Enabled
+	}
+
+	for _, sc := range lm.config.SchedulerConfig {
+		if seen[sc.Name] {
+			duplicates = append(duplicates, &SyncItemError{Kind: SyncKindBackup, Name: sc.Name, Err: fmt.Errorf("duplicate config name")})
+			continue
+		}
+		seen[sc.Name] = true
+
+		config, err := buildBackupConfig(sc.Name, sc.BackupMode, sc.DatabaseConfig, sc.CronExpression)
+		if err != nil {
+			errs = append(errs, &SyncItemError{Kind: SyncKindBackup, Name: sc.Name, Err: err})
+			continue
+		}
+		if sc.Connection != "" {
+			if !lm.hasDriveConnection(sc.Connection) {
+				errs = append(errs, &SyncItemError{Kind: SyncKindBackup, Name: sc.Name, Err: fmt.Errorf("drive connection '%s' is not configured", sc.Connection)})
+				continue
+			}
+			config.Connection = sc.Connection
+		}
+		// Without an explicit state, a config disabled at runtime is not turned back on
+		if sc.Enabled != nil {
+			config.Enabled = *sc.Enabled
+		} else if enabled, ok := storedEnabled[sc.Name]; ok {
+			config.Enabled = enabled
+		}
+		config.Source = database.ConfigSourceStatic
+		desired = append(desired, config)
+		dbConfigs[sc.Name] = sc.DatabaseConfig
+	}
+
+	// Stored configs whose declaration failed validation are left untouched
+	steps := diffBackupConfigs(desired, withoutFailed(existing, errs, func(c database.BackupConfig) string { return c.Name }), lm.config.SyncOptions)
+	for i := range steps {
+		steps[i].dbConfig = dbConfigs[steps[i].item.Name]
+	}
+
+	return steps, append(errs, duplicates...), nil
+}
+
+// diffBackupConfigs computes the steps needed to turn existing into desired
+func diffBackupConfigs(desired []*database.BackupConfig, existing []database.BackupConfig, opts SyncOptions) []backupSyncStep {
+	var steps []backupSyncStep
+	stored := make(map[string]*database.BackupConfig, len(existing))
+	for i := range existing {
+		stored[existing[i].Name] = &existing[i]
+	}
+
+	declared := make(map[string]bool, len(desired))
+	for _, config := range desired {
+		declared[config.Name] = true
+		item := SyncPlanItem{Kind: SyncKindBackup, Name: config.Name}
+
+		current, ok := stored[config.Name]
+		if !ok {
+			item.Action = SyncActionCreate
+			steps = append(steps, backupSyncStep{item: item, config: config})
+			continue
+		}
+
+		item.Changes = backupConfigChanges(current, config)
+		if len(item.Changes) == 0 {
+			item.Action = SyncActionUnchanged
+		} else {
+			item.Action = SyncActionUpdate
+		}
+		config.ID = current.ID
+		config.CreatedAt = current.CreatedAt
+		steps = append(steps, backupSyncStep{item: item, config: config})
+	}
+
+	for i := range existing {
+		current := &existing[i]
+		if declared[current.Name] {
+			continue
+		}
+		item := SyncPlanItem{Kind: SyncKindBackup, Name: current.Name}
+		switch {
+		case opts.KeepRuntimeConfigs && current.Source != database.ConfigSourceStatic:
+			item.Action = SyncActionUnchanged
+		case opts.PrunePolicy == PruneDisable:
+			if current.Enabled {
+				item.Action = SyncActionDisable
+			} else {
+				item.Action = SyncActionUnchanged
+			}
+		default:
+			item.Action = SyncActionDelete
+		}
+		steps = append(steps, backupSyncStep{item: item, config: current})
+	}
+
+	return steps
+}
+
+// backupConfigChanges lists the fields that differ between two backup configs
+func backupConfigChanges(current, desired *database.BackupConfig) []string {
+	var changes []string
+	if current.BackupMode != desired.BackupMode {
+		changes = append(changes, "backup_mode")
+	}
+	if current.DatabaseURL != desired.DatabaseURL {
+		changes = append(changes, "database_url")
+	}
+	if current.DatabaseType != desired.DatabaseType {
+		changes = append(changes, "database_type")
+	}
+	if current.CronSchedule != desired.CronSchedule {
+		changes = append(changes, "cron_schedule")
+	}
+	if current.Enabled != desired.Enabled {
+		changes = append(changes, "enabled")
+	}
+	if current.Source != desired.Source {
+		changes = append(changes, "source")
+	}
+	if current.Connection != desired.Connection {
+		changes = append(changes, "connection")
+	}
+	return changes
+}
+
+// applyBackupSyncStep persists a single planned backup config change and updates the scheduler
+func (lm *LazyManager) applyBackupSyncStep(step backupSyncStep) error {
+	config := step.config
+
+	switch step.item.Action {
+	case SyncActionCreate:
+		if err := testBackupConnection(step.dbConfig); err != nil {
+			return err
+		}
+		if err := lm.dbService.SaveBackupConfig(config); err != nil {
+			return fmt.Errorf("failed to save backup config: %w", err)
+		}
+		if config.Enabled {
+			if err := lm.schedulerService.AddBackupJob(config); err != nil {
+				return fmt.Errorf("failed to schedule backup job: %w", err)
+			}
+		}
+
+	case SyncActionUpdate:
+		if containsChange(step.item.Changes, "database_url") {
+			if err := testBackupConnection(step.dbConfig); err != nil {
+				return err
+			}
+		}
+		if err := lm.dbService.UpdateBackupConfig(config); err != nil {
+			return fmt.Errorf("failed to update backup config: %w", err)
+		}
+		if config.Enabled {
+			if err := lm.schedulerService.AddBackupJob(config); err != nil {
+				return fmt.Errorf("failed to reschedule backup job: %w", err)
+			}
+		} else {
+			lm.schedulerService.RemoveBackupJob(config.Name)
+		}
+
+	case SyncActionDisable:
+		config.Enabled = false
+		if err := lm.dbService.UpdateBackupConfig(config); err != nil {
+			return fmt.Errorf("failed to disable backup config: %w", err)
+		}
+		lm.schedulerService.RemoveBackupJob(config.Name)
+
+	case SyncActionDelete:
+		lm.schedulerService.RemoveBackupJob(config.Name)
+		if err := lm.dbService.DeleteBackupConfig(config.Name); err != nil {
+			return fmt.Errorf("failed to delete backup config: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// planNotificationSync diffs the declared notification configs against the stored ones
+func (lm *LazyManager) planNotificationSync() ([]notificationSyncStep, []*SyncItemError, error) {
+	existing, err := lm.dbService.GetNotificationConfigs()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load stored notification configs: %w", err)
+	}
+
+	var desired []*database.NotificationConfig
+	var errs, duplicates []*SyncItemError
+	seen := make(map[string]bool)
+
+	for _, nc := range lm.config.NotificationConfig {
+		if nc.Name == "" {
+			errs = append(errs, &SyncItemError{Kind: SyncKindNotification, Name: nc.Name, Err: fmt.Errorf("name is required")})
+			continue
+		}
+		if seen[nc.Name] {
+			duplicates = append(duplicates, &SyncItemError{Kind: SyncKindNotification, Name: nc.Name, Err: fmt.Errorf("duplicate config name")})
+			continue
+		}
+		seen[nc.Name] = true
+
+		settings, err := normalizeSettings(nc.Config)
+		if err != nil {
+			errs = append(errs, &SyncItemError{Kind: SyncKindNotification, Name: nc.Name, Err: err})
+			continue
+		}
+		if err := notification.ValidateConfig(notification.NotificationChannel(nc.Channel), settings); err != nil {
+			errs = append(errs, &SyncItemError{Kind: SyncKindNotification, Name: nc.Name, Err: err})
+			continue
+		}
+		if err := notification.ValidateMessageTemplates(nc.Templates); err != nil {
+			errs = append(errs, &SyncItemError{Kind: SyncKindNotification, Name: nc.Name, Err: err})
+			continue
+		}
+		if err := notification.ValidateSuppressionRules(nc.Suppression); err != nil {
+			errs = append(errs, &SyncItemError{Kind: SyncKindNotification, Name: nc.Name, Err: err})
+			continue
+		}
+
+		desired = append(desired, &database.NotificationConfig{
+			Name:            nc.Name,
+			Channel:         nc.Channel,
+			Config:          settings,
+			NotifyOnSuccess: nc.NotifyOnSuccess,
+			NotifyOnError:   nc.NotifyOnError,
+			Enabled:         nc.Enabled,
+			Templates:       normalizeTemplates(nc.Templates),
+			Suppression:     nc.Suppression,
+			Source:          database.ConfigSourceStatic,
+		})
+	}
+
+	// Stored configs whose declaration failed validation are left untouched
+	existing = withoutFailed(existing, errs, func(c database.NotificationConfig) string { return c.Name })
+	return diffNotificationConfigs(desired, existing, lm.config.SyncOptions), append(errs, duplicates...), nil
+}
+
+// diffNotificationConfigs computes the steps needed to turn existing into desired
+func diffNotificationConfigs(desired []*database.NotificationConfig, existing []database.NotificationConfig, opts SyncOptions) []notificationSyncStep {
+	var steps []notificationSyncStep
+	stored := make(map[string]*database.NotificationConfig, len(existing))
+	for i := range existing {
+		stored[existing[i].Name] = &existing[i]
+	}
+
+	declared := make(map[string]bool, len(desired))
+	for _, config := range desired {
+		declared[config.Name] = true
+		item := SyncPlanItem{Kind: SyncKindNotification, Name: config.Name}
+
+		current, ok := stored[config.Name]
+		if !ok {
+			item.Action = SyncActionCreate
+			steps = append(steps, notificationSyncStep{item: item, config: config})
+			continue
+		}
+
+		item.Changes = notificationConfigChanges(current, config)
+		if len(item.Changes) == 0 {
+			item.Action = SyncActionUnchanged
+		} else {
+			item.Action = SyncActionUpdate
+		}
+		config.ID = current.ID
+		config.CreatedAt = current.CreatedAt
+		steps = append(steps, notificationSyncStep{item: item, config: config})
+	}
+
+	for i := range existing {
+		current := &existing[i]
+		if declared[current.Name] {
+			continue
+		}
+		item := SyncPlanItem{Kind: SyncKindNotification, Name: current.Name}
+		switch {
+		case opts.KeepRuntimeConfigs && current.Source != database.ConfigSourceStatic:
+			item.Action = SyncActionUnchanged
+		case opts.PrunePolicy == PruneDisable:
+			if current.Enabled {
+				item.Action = SyncActionDisable
+			} else {
+				item.Action = SyncActionUnchanged
+			}
+		default:
+			item.Action = SyncActionDelete
+		}
+		steps = append(steps, notificationSyncStep{item: item, config: current})
+	}
+
+	return steps
+}
+
+// notificationConfigChanges lists the fields that differ between two notification configs
+func notificationConfigChanges(current, desired *database.NotificationConfig) []string {
+	var changes []string
+	if current.Channel != desired.Channel {
+		changes = append(changes, "channel")
+	}
+	if !reflect.DeepEqual(current.Config, desired.Config) {
+		changes = append(changes, "config")
+	}
+	if !reflect.DeepEqual(current.Templates, desired.Templates) {
+		changes = append(changes, "templates")
+	}
+	if !reflect.DeepEqual(current.Suppression, desired.Suppression) {
+		changes = append(changes, "suppression")
+	}
+	if current.NotifyOnSuccess != desired.NotifyOnSuccess {
+		changes = append(changes, "notify_on_success")
+	}
+	if current.NotifyOnError != desired.NotifyOnError {
+		changes = append(changes, "notify_on_error")
+	}
+	if current.Enabled != desired.Enabled {
+		changes = append(changes, "enabled")
+	}
+	if current.Source != desired.Source {
+		changes = append(changes, "source")
+	}
+	return changes
+}
+
+// applyNotificationSyncStep persists a single planned notification config change
+func (lm *LazyManager) applyNotificationSyncStep(step notificationSyncStep) error {
+	config := step.config
+
+	switch step.item.Action {
+	case SyncActionCreate, SyncActionUpdate:
+		if err := lm.dbService.SaveNotificationConfig(config); err != nil {
+			return fmt.Errorf("failed to save notification config: %w", err)
+		}
+
+	case SyncActionDisable:
+		config.Enabled = false
+		if err := lm.dbService.UpdateNotificationConfig(config); err != nil {
+			return fmt.Errorf("failed to disable notification config: %w", err)
+		}
+
+	case SyncActionDelete:
+		if err := lm.dbService.DeleteNotificationConfig(config.Name); err != nil {
+			return fmt.Errorf("failed to delete notification config: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// normalizeSettings round-trips a settings map through JSON so it compares equal to the stored copy
+func normalizeSettings(settings map[string]interface{}) (map[string]interface{}, error) {
+	if settings == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config settings: %w", err)
+	}
+
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, fmt.Errorf("invalid config settings: %w", err)
+	}
+	return normalized, nil
+}
+
+// normalizeTemplates drops empty templates so they compare equal to the stored copy
+func normalizeTemplates(templates *notification.MessageTemplates) *database.NotificationTemplates {
+	if templates.IsEmpty() {
+		return nil
+	}
+
+	normalized := *templates
+	if len(normalized.Fields) == 0 {
+		normalized.Fields = nil
+	}
+	return &normalized
+}
+
+// buildBackupConfig validates the given settings and converts them into a stored backup config
+func buildBackupConfig(name, mode string, dbConfig *backup.MySQLConfig, expression string) (*database.BackupConfig, error) {
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	// Validate cron expression
+	if err := scheduler.ValidateCronExpression(expression); err != nil {
+		return nil, fmt.Errorf("invalid cron expression: %w", err)
+	}
+
+	// Validate database configuration
+	if dbConfig == nil {
+		return nil, fmt.Errorf("database configuration is required")
+	}
+	if err := dbConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid database configuration: %w", err)
+	}
+
+	return &database.BackupConfig{
+		Name:         name,
+		BackupMode:   mode,
+		DatabaseURL:  "mysql://" + dbConfig.GetConnectionString(),
+		DatabaseType: "mysql",
+		CronSchedule: expression,
+		Enabled:      true,
+		Connection:   DefaultDriveConnection,
+	}, nil
+}
+
+// testBackupConnection makes sure the target database is reachable
+func testBackupConnection(dbConfig *backup.MySQLConfig) error {
+	backupService, err := backup.NewMySQLBackupWithConfig(dbConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create backup service: %w", err)
+	}
+
+	if err := backupService.TestConnection(); err != nil {
+		return fmt.Errorf("database connection test failed: %w", err)
+	}
+	return nil
+}
+
+// withoutFailed drops stored configs whose declared counterpart could not be validated
+func withoutFailed[T any](existing []T, errs []*SyncItemError, name func(T) string) []T {
+	if len(errs) == 0 {
+		return existing
+	}
+
+	failed := make(map[string]bool, len(errs))
+	for _, itemErr := range errs {
+		failed[itemErr.Name] = true
+	}
+
+	kept := make([]T, 0, len(existing))
+	for _, config := range existing {
+		if !failed[name(config)] {
+			kept = append(kept, config)
+		}
+	}
+	return kept
+}
+
+// containsChange reports whether a field name is part of a change list
+func containsChange(changes []string, field string) bool {
+	for _, change := range changes {
+		if change == field {
+			return true
+		}
+	}
+	return false
+}
+
+// logSyncPlan logs every item of a plan that changes something
+func logSyncPlan(kind string, items []SyncPlanItem) {
+	plan := &SyncPlan{Items: items}
+	log.Printf("Sync plan for %s configs: %s", kind, plan.Summary())
+	for _, item := range items {
+		if item.Action != SyncActionUnchanged {
+			log.Printf("Sync: %s", item)
+		}
+	}
+}
+
+// isSyncError reports whether err only carries per-config failures
+func isSyncError(err error) bool {
+	var syncErr *SyncError
+	return errors.As(err, &syncErr)
+}
+
+// tolerateSyncError reports whether Initialize may carry on after a sync failure.
+// A declaration that fails validation is a programming error, so it is never tolerated.
+func tolerateSyncError(err error, opts SyncOptions) bool {
+	var syncErr *SyncError
+	if !opts.ContinueOnError || !errors.As(err, &syncErr) {
+		return false
+	}
+	for _, itemErr := range syncErr.Errors {
+		if itemErr.Action == "" {
+			return false
+		}
+	}
+	return true
+}